@@ -0,0 +1,63 @@
+package main
+
+/* Self-balancing trees
+Inserting sorted (or unlucky random) keys into a plain binary search tree degrades it to a linked list,
+and then every recursive walk like tree.Walk goes O(n) deep.
+AVL and red-black trees rebalance themselves with rotations on every Insert and Delete to stay O(log n) high.
+
+All variants in package tree implement tree.OrderedSet, so they are interchangeable below.
+How much the height costs shows in the benchmarks of the tree package:
+	go test -run XXX -bench . ./tree
+*/
+
+import (
+	"fmt"
+	"math/rand"
+
+	"github.com/ysyesilyurt/lets-go/bootstrap/stages/go_rulez/tree"
+)
+
+type variant struct {
+	name string
+	new  func() tree.OrderedSet[int]
+}
+
+var variants = []variant{
+	{"bst", func() tree.OrderedSet[int] { return tree.New[int]() }},
+	{"avl", func() tree.OrderedSet[int] { return tree.NewAVL[int]() }},
+	{"red-black", func() tree.OrderedSet[int] { return tree.NewRedBlack[int]() }},
+}
+
+func main() {
+	/* tree.Checked verifies the invariants of a variant after each Insert and Delete and panics on the first violation */
+	for _, v := range variants {
+		s := tree.Checked(v.new())
+		for i := 0; i < 10000; i++ {
+			if k := rand.Intn(500); rand.Intn(3) == 0 {
+				s.Delete(k)
+			} else {
+				s.Insert(k)
+			}
+		}
+		fmt.Printf("%-9s survived 10000 checked mutations (%d keys, height %d)\n", v.name, s.Len(), s.Height())
+	}
+
+	const n = 2000
+	sorted := make([]int, n)
+	for i := range sorted {
+		sorted[i] = i
+	}
+	for _, keys := range []struct {
+		name string
+		keys []int
+	}{{"sorted", sorted}, {"random", rand.Perm(n)}} {
+		fmt.Printf("\nheight after %d %s inserts\n", n, keys.name)
+		for _, v := range variants {
+			s := v.new()
+			for _, k := range keys.keys {
+				s.Insert(k)
+			}
+			fmt.Printf("  %-9s %4d\n", v.name, s.Height())
+		}
+	}
+}
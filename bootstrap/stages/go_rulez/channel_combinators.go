@@ -0,0 +1,151 @@
+package main
+
+/* Channel combinators
+The package chans wraps the usual channel patterns into functions:
+	* OrDone(done, in) -> range over in until done is closed
+	* Merge(done, ins...) -> all values of many channels on one
+	* Tee(done, in) -> every value of in on two channels
+	* Bridge(done, ins) -> the values of a channel of channels, one channel after the other
+	* Or(dones...) -> closed as soon as any of dones is
+	* Take, Repeat and Buffer
+Each of them starts goroutines, and each of those must be gone once done is closed.
+leaked checks that for every combinator below: it takes a leakcheck snapshot before the combinator is used,
+and after done is closed asks which of the goroutines started since are still there (see goroutine_leaks.go).
+The tests in chans do the same with leakcheck.Check: go test ./chans
+*/
+
+import (
+	"fmt"
+	"slices"
+	"time"
+
+	"github.com/ysyesilyurt/lets-go/bootstrap/stages/go_rulez/chans"
+	"github.com/ysyesilyurt/lets-go/bootstrap/stages/go_rulez/leakcheck"
+)
+
+// leaked runs f with a fresh done channel, closes it and returns the goroutines left over
+// once they had a second to return.
+func leaked(f func(done chan struct{})) []leakcheck.Goroutine {
+	s := leakcheck.Take()
+	done := make(chan struct{})
+	f(done)
+	close(done)
+	return s.Leaked(leakcheck.Config{})
+}
+
+// count sends 1..n and closes its channel, or stops once done is closed.
+func count(done <-chan struct{}, n int) <-chan int {
+	out := make(chan int)
+	go func() {
+		defer close(out)
+		for i := 1; i <= n; i++ {
+			select {
+			case out <- i:
+			case <-done:
+				return
+			}
+		}
+	}()
+	return out
+}
+
+func after(d time.Duration) <-chan struct{} {
+	c := make(chan struct{})
+	time.AfterFunc(d, func() { close(c) })
+	return c
+}
+
+func main() {
+	examples := []struct {
+		name string
+		run  func(done chan struct{})
+	}{
+		{"Merge", func(done chan struct{}) {
+			var got []int
+			for v := range chans.Merge(done, count(done, 3), count(done, 3), count(done, 3)) {
+				got = append(got, v)
+			}
+			slices.Sort(got)
+			fmt.Println("  merged:", got)
+		}},
+		{"Merge, walking away early", func(done chan struct{}) {
+			merged := chans.Merge(done, chans.Repeat(done, "a"), chans.Repeat(done, "b"))
+			fmt.Println("  first of endless a's and b's:", <-merged)
+		}},
+		{"OrDone", func(done chan struct{}) {
+			stop := make(chan struct{})
+			n := 0
+			for v := range chans.OrDone(stop, count(done, 1000)) {
+				if n += v; v == 10 {
+					close(stop)
+				}
+			}
+			fmt.Println("  sum until we stopped ranging:", n)
+		}},
+		{"Tee", func(done chan struct{}) {
+			a, b := chans.Tee(done, count(done, 5))
+			var fromA, fromB []int
+			for a != nil || b != nil { // the nil channel trick of select_and_close, once more
+				select {
+				case v, ok := <-a:
+					if !ok {
+						a = nil
+						continue
+					}
+					fromA = append(fromA, v)
+				case v, ok := <-b:
+					if !ok {
+						b = nil
+						continue
+					}
+					fromB = append(fromB, v)
+				}
+			}
+			fmt.Println("  a got", fromA, "b got", fromB)
+		}},
+		{"Tee, reading one side only", func(done chan struct{}) {
+			a, _ := chans.Tee(done, count(done, 5))
+			fmt.Println("  a got", <-a, "and then Tee waits for b forever, unless done is closed")
+		}},
+		{"Bridge", func(done chan struct{}) {
+			ins := make(chan (<-chan int))
+			go func() {
+				defer close(ins)
+				for i := 1; i <= 3; i++ {
+					ins <- count(done, i)
+				}
+			}()
+			var got []int
+			for v := range chans.Bridge(done, ins) {
+				got = append(got, v)
+			}
+			fmt.Println("  bridged:", got)
+		}},
+		{"Or", func(done chan struct{}) {
+			start := time.Now()
+			<-chans.Or(after(time.Hour), after(time.Minute), after(50*time.Millisecond), after(time.Second), done)
+			fmt.Printf("  the first of five signals came after %v\n", time.Since(start).Round(10*time.Millisecond))
+		}},
+		{"Take of Repeat", func(done chan struct{}) {
+			var got []string
+			for v := range chans.Take(done, chans.Repeat(done, "tick", "tock"), 5) {
+				got = append(got, v)
+			}
+			fmt.Println("  took:", got)
+		}},
+		{"Buffer", func(done chan struct{}) {
+			buffered := chans.Buffer(done, count(done, 100), 10)
+			time.Sleep(10 * time.Millisecond) // the producer runs ahead meanwhile
+			fmt.Println("  values waiting in the buffer before the first read:", len(buffered))
+		}},
+	}
+
+	for _, e := range examples {
+		fmt.Println(e.name)
+		if gs := leaked(e.run); len(gs) > 0 {
+			fmt.Printf("  LEAK: goroutines left after done was closed (%d)\n%s\n", len(gs), leakcheck.Format(gs))
+		} else {
+			fmt.Println("  no goroutines left after done was closed")
+		}
+	}
+}
@@ -0,0 +1,200 @@
+// Package chans holds generic channel combinators, the patterns select_and_close in go_rulez.go spells out
+// inline made reusable:
+//
+//	done := make(chan struct{})
+//	defer close(done)
+//	for v := range chans.Take(done, chans.Merge(done, a, b, c), 10) {
+//		...
+//	}
+//
+// Every combinator takes a done channel (ctx.Done() will do) and starts goroutines that forward values
+// until their inputs are closed or done is. Closing done is enough to stop them all: a goroutine blocked on
+// a send nobody will receive gives up as soon as done is closed, so nothing leaks once the consumer walks away.
+// The output channels are closed when the goroutines writing to them return.
+package chans
+
+import "sync"
+
+// OrDone forwards the values of in until in is closed or done is, so that ranging over in can be stopped
+// without a select in every loop:
+//
+//	for v := range chans.OrDone(ctx.Done(), in) { ... }
+func OrDone[T any](done <-chan struct{}, in <-chan T) <-chan T {
+	out := make(chan T)
+	go func() {
+		defer close(out)
+		for {
+			select {
+			case v, ok := <-in:
+				if !ok || !send(done, out, v) {
+					return
+				}
+			case <-done:
+				return
+			}
+		}
+	}()
+	return out
+}
+
+// Merge forwards the values of all ins on one channel, which is closed once all of them are closed
+// or done is. Values of the same input keep their order, values of different inputs interleave.
+func Merge[T any](done <-chan struct{}, ins ...<-chan T) <-chan T {
+	out := make(chan T)
+	var wg sync.WaitGroup
+	wg.Add(len(ins))
+	for _, in := range ins {
+		go func() {
+			defer wg.Done()
+			for v := range OrDone(done, in) {
+				if !send(done, out, v) {
+					return
+				}
+			}
+		}()
+	}
+	go func() {
+		wg.Wait()
+		close(out)
+	}()
+	return out
+}
+
+// Tee sends every value of in on both returned channels before it takes the next one, so the slower
+// reader sets the pace for both. Both have to be read until they are closed, or done closed.
+func Tee[T any](done <-chan struct{}, in <-chan T) (<-chan T, <-chan T) {
+	out1, out2 := make(chan T), make(chan T)
+	go func() {
+		defer close(out1)
+		defer close(out2)
+		for v := range OrDone(done, in) {
+			// Whichever channel took v is set to nil, a nil channel is never ready, so the other one gets it next.
+			o1, o2 := out1, out2
+			for o1 != nil || o2 != nil {
+				select {
+				case o1 <- v:
+					o1 = nil
+				case o2 <- v:
+					o2 = nil
+				case <-done:
+					return
+				}
+			}
+		}
+	}()
+	return out1, out2
+}
+
+// Bridge flattens a channel of channels: it forwards every value of the first channel received from ins,
+// then of the second one and so on, until ins is closed or done is.
+func Bridge[T any](done <-chan struct{}, ins <-chan (<-chan T)) <-chan T {
+	out := make(chan T)
+	go func() {
+		defer close(out)
+		for in := range OrDone(done, ins) {
+			for v := range OrDone(done, in) {
+				if !send(done, out, v) {
+					return
+				}
+			}
+		}
+	}()
+	return out
+}
+
+// Or returns a channel that is closed as soon as any of dones is closed, to wait for whichever of
+// several signals comes first. Its goroutines only return then, so at least one of dones must be closed
+// eventually. Or of no channels returns nil, which is never ready.
+func Or(dones ...<-chan struct{}) <-chan struct{} {
+	switch len(dones) {
+	case 0:
+		return nil
+	case 1:
+		return dones[0]
+	}
+	orDone := make(chan struct{})
+	go func() {
+		defer close(orDone)
+		switch len(dones) {
+		case 2:
+			select {
+			case <-dones[0]:
+			case <-dones[1]:
+			}
+		default:
+			// Wait on the first three ourselves and on the rest through another Or, which also gets orDone
+			// so that its goroutines return as soon as ours does.
+			select {
+			case <-dones[0]:
+			case <-dones[1]:
+			case <-dones[2]:
+			case <-Or(append([]<-chan struct{}{orDone}, dones[3:]...)...):
+			}
+		}
+	}()
+	return orDone
+}
+
+// Take forwards the first n values of in and then closes its channel, without reading any further from in.
+func Take[T any](done <-chan struct{}, in <-chan T, n int) <-chan T {
+	out := make(chan T)
+	go func() {
+		defer close(out)
+		for i := 0; i < n; i++ {
+			select {
+			case v, ok := <-in:
+				if !ok || !send(done, out, v) {
+					return
+				}
+			case <-done:
+				return
+			}
+		}
+	}()
+	return out
+}
+
+// Repeat sends values over and over, in order, until done is closed. Without values it sends nothing
+// and its channel is closed right away.
+func Repeat[T any](done <-chan struct{}, values ...T) <-chan T {
+	out := make(chan T)
+	go func() {
+		defer close(out)
+		if len(values) == 0 {
+			return
+		}
+		for {
+			for _, v := range values {
+				if !send(done, out, v) {
+					return
+				}
+			}
+		}
+	}()
+	return out
+}
+
+// Buffer forwards the values of in through a channel with room for n of them, so that a bursty producer
+// can run up to n values ahead of its consumer. On done, values still in the buffer are dropped.
+func Buffer[T any](done <-chan struct{}, in <-chan T, n int) <-chan T {
+	out := make(chan T, max(n, 0))
+	go func() {
+		defer close(out)
+		for v := range OrDone(done, in) {
+			if !send(done, out, v) {
+				return
+			}
+		}
+	}()
+	return out
+}
+
+// send sends v on out unless done is closed first, and reports whether it did.
+func send[T any](done <-chan struct{}, out chan<- T, v T) bool {
+	select {
+	case out <- v:
+		return true
+	case <-done:
+		return false
+	}
+}
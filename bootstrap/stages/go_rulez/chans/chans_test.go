@@ -0,0 +1,204 @@
+package chans
+
+import (
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/ysyesilyurt/lets-go/bootstrap/stages/go_rulez/leakcheck"
+)
+
+// count sends 1..n and closes its channel, or stops once done is closed.
+func count(done <-chan struct{}, n int) <-chan int {
+	out := make(chan int)
+	go func() {
+		defer close(out)
+		for i := 1; i <= n; i++ {
+			if !send(done, out, i) {
+				return
+			}
+		}
+	}()
+	return out
+}
+
+// collect reads in until it is closed.
+func collect[T any](in <-chan T) []T {
+	var got []T
+	for v := range in {
+		got = append(got, v)
+	}
+	return got
+}
+
+/* Every test closes done on its way out, and leakcheck.Check fails it if a goroutine of the combinator,
+or of the inputs feeding it, is still around afterwards. Most of them also walk away from the output early,
+which is what leaks when a combinator does not watch done. */
+
+func TestOrDone(t *testing.T) {
+	leakcheck.Check(t, leakcheck.Config{})
+	done := make(chan struct{})
+	defer close(done)
+
+	if got := collect(OrDone(done, count(done, 3))); !slices.Equal(got, []int{1, 2, 3}) {
+		t.Fatalf("got %v, want [1 2 3]", got)
+	}
+	stop := make(chan struct{})
+	last := 0
+	for v := range OrDone(stop, count(done, 1000)) {
+		if last = v; v == 10 {
+			close(stop)
+		}
+	}
+	// select picks at random between a value and stop, so one or two more may come through, not all of them
+	if last < 10 || last == 1000 {
+		t.Fatalf("ranging ended at %d, want it to end soon after stop was closed at 10", last)
+	}
+}
+
+func TestMerge(t *testing.T) {
+	leakcheck.Check(t, leakcheck.Config{})
+	done := make(chan struct{})
+	defer close(done)
+
+	got := collect(Merge(done, count(done, 3), count(done, 2), count(done, 1)))
+	slices.Sort(got)
+	if !slices.Equal(got, []int{1, 1, 1, 2, 2, 3}) {
+		t.Fatalf("got %v", got)
+	}
+	if got := collect(Merge[int](done)); got != nil {
+		t.Fatalf("Merge of nothing sent %v", got)
+	}
+	<-Merge(done, Repeat(done, "a"), Repeat(done, "b"))
+}
+
+func TestTee(t *testing.T) {
+	leakcheck.Check(t, leakcheck.Config{})
+	done := make(chan struct{})
+	defer close(done)
+
+	a, b := Tee(done, count(done, 5))
+	var fromA, fromB []int
+	for a != nil || b != nil {
+		select {
+		case v, ok := <-a:
+			if !ok {
+				a = nil
+				continue
+			}
+			fromA = append(fromA, v)
+		case v, ok := <-b:
+			if !ok {
+				b = nil
+				continue
+			}
+			fromB = append(fromB, v)
+		}
+	}
+	if want := []int{1, 2, 3, 4, 5}; !slices.Equal(fromA, want) || !slices.Equal(fromB, want) {
+		t.Fatalf("a got %v, b got %v, want %v on both", fromA, fromB, want)
+	}
+
+	/* reading one side only: Tee is stuck on the other one until done is closed */
+	a, _ = Tee(done, count(done, 5))
+	<-a
+}
+
+func TestBridge(t *testing.T) {
+	leakcheck.Check(t, leakcheck.Config{})
+	done := make(chan struct{})
+	defer close(done)
+
+	ins := make(chan (<-chan int))
+	go func() {
+		defer close(ins)
+		for i := 1; i <= 3; i++ {
+			if !send(done, ins, count(done, i)) {
+				return
+			}
+		}
+	}()
+	if got := collect(Bridge(done, ins)); !slices.Equal(got, []int{1, 1, 2, 1, 2, 3}) {
+		t.Fatalf("got %v, want the channels one after the other", got)
+	}
+
+	endless := make(chan (<-chan int), 1)
+	endless <- Repeat(done, 7)
+	<-Bridge(done, endless)
+}
+
+func TestOr(t *testing.T) {
+	leakcheck.Check(t, leakcheck.Config{})
+	if Or() != nil {
+		t.Fatal("Or of nothing is not nil")
+	}
+	for _, n := range []int{1, 2, 3, 4, 20} {
+		dones := make([]chan struct{}, n)
+		ins := make([]<-chan struct{}, n)
+		for i := range dones {
+			dones[i] = make(chan struct{})
+			ins[i] = dones[i]
+		}
+		or := Or(ins...)
+		select {
+		case <-or:
+			t.Fatalf("Or of %d open channels is closed", n)
+		case <-time.After(10 * time.Millisecond):
+		}
+		close(dones[n-1]) // the last one, the deepest in the recursion
+		select {
+		case <-or:
+		case <-time.After(time.Second):
+			t.Fatalf("Or of %d channels not closed after the last one was", n)
+		}
+		for _, d := range dones[:n-1] {
+			close(d)
+		}
+	}
+}
+
+func TestTake(t *testing.T) {
+	leakcheck.Check(t, leakcheck.Config{})
+	done := make(chan struct{})
+	defer close(done)
+
+	if got := collect(Take(done, count(done, 100), 3)); !slices.Equal(got, []int{1, 2, 3}) {
+		t.Fatalf("got %v, want [1 2 3]", got)
+	}
+	if got := collect(Take(done, count(done, 2), 3)); !slices.Equal(got, []int{1, 2}) {
+		t.Fatalf("got %v, want what there was", got)
+	}
+	<-Take(done, Repeat(done, 1), 10)
+}
+
+func TestRepeat(t *testing.T) {
+	leakcheck.Check(t, leakcheck.Config{})
+	done := make(chan struct{})
+	defer close(done)
+
+	if got := collect(Take(done, Repeat(done, "tick", "tock"), 5)); !slices.Equal(got, []string{"tick", "tock", "tick", "tock", "tick"}) {
+		t.Fatalf("got %v", got)
+	}
+	if got := collect(Repeat[int](done)); got != nil {
+		t.Fatalf("Repeat of nothing sent %v", got)
+	}
+}
+
+func TestBuffer(t *testing.T) {
+	leakcheck.Check(t, leakcheck.Config{})
+	done := make(chan struct{})
+	defer close(done)
+
+	buffered := Buffer(done, count(done, 100), 10)
+	deadline := time.Now().Add(time.Second)
+	for len(buffered) < 10 && time.Now().Before(deadline) {
+		time.Sleep(time.Millisecond) // the producer runs ahead meanwhile
+	}
+	if len(buffered) != 10 {
+		t.Fatalf("%d values waiting in the buffer, want 10", len(buffered))
+	}
+	if got := collect(buffered); len(got) != 100 || !slices.IsSorted(got) {
+		t.Fatalf("got %d values, want all 100 in order", len(got))
+	}
+	<-Buffer(done, Repeat(done, 1), 10)
+}
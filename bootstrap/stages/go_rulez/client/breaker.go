@@ -0,0 +1,167 @@
+package client
+
+import (
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/ysyesilyurt/lets-go/bootstrap/stages/go_rulez/clock"
+)
+
+/*
+	Circuit breaker.
+
+	A server that is down answers every retry with another failure, and clients hammering it keep it down.
+	A breaker counts consecutive failures and, past a threshold, stops letting calls through for a while:
+
+		         FailureThreshold failures in a row
+		Closed ------------------------------------> Open
+		  ^                                           |  ^
+		  | HalfOpenRequests                          |  |
+		  | successes             after OpenTimeout   |  | any failure
+		  |                                           v  |
+		  +------------------------------------- Half-open
+
+	Half-open lets HalfOpenRequests probe calls through: if they all succeed the target is back and the breaker
+	closes, a single failure opens it again for another OpenTimeout.
+*/
+
+// ErrOpen is returned for calls the breaker turned away without trying.
+var ErrOpen = errors.New("client: circuit breaker is open")
+
+// State is the state of a Breaker.
+type State int
+
+const (
+	Closed   State = iota // calls go through, failures are counted
+	Open                  // calls fail fast with ErrOpen
+	HalfOpen              // a few probe calls go through to see whether the target recovered
+)
+
+func (s State) String() string {
+	switch s {
+	case Closed:
+		return "closed"
+	case Open:
+		return "open"
+	case HalfOpen:
+		return "half-open"
+	}
+	return "unknown"
+}
+
+// BreakerConfig configures a Breaker, zero fields take the defaults below.
+type BreakerConfig struct {
+	FailureThreshold int           // consecutive failures that open the breaker; defaults to 5
+	OpenTimeout      time.Duration // how long the breaker stays open before probing; defaults to 30s
+	HalfOpenRequests int           // probe calls let through while half-open, all must succeed to close; defaults to 1
+	Clock            clock.Clock   // defaults to clock.Real()
+
+	// OnStateChange, if set, is called on every transition. It runs with the breaker locked,
+	// so it sees transitions in order but must be quick and must not call the breaker itself.
+	OnStateChange func(from, to State)
+}
+
+// Breaker is a circuit breaker, safe for concurrent use. Use NewBreaker to create one.
+type Breaker struct {
+	mu       sync.Mutex
+	cfg      BreakerConfig
+	state    State
+	failures int       // consecutive, while closed
+	openedAt time.Time // while open
+	probes   int       // let through while half-open
+	passed   int       // probes that succeeded
+	gen      uint64    // bumped on every transition, so results of calls from an earlier state are ignored
+}
+
+// NewBreaker returns a closed breaker.
+func NewBreaker(cfg BreakerConfig) *Breaker {
+	if cfg.FailureThreshold <= 0 {
+		cfg.FailureThreshold = 5
+	}
+	if cfg.OpenTimeout <= 0 {
+		cfg.OpenTimeout = 30 * time.Second
+	}
+	if cfg.HalfOpenRequests <= 0 {
+		cfg.HalfOpenRequests = 1
+	}
+	if cfg.Clock == nil {
+		cfg.Clock = clock.Real()
+	}
+	return &Breaker{cfg: cfg}
+}
+
+// State returns the current state of b. An open breaker whose OpenTimeout has passed reports HalfOpen.
+func (b *Breaker) State() State {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.expire()
+	return b.state
+}
+
+// Allow asks b whether a call may go through. If so, the caller must report how the call went by calling done
+// exactly once, with ok false for a failure. A call that ended without a verdict, such as one the caller canceled,
+// is reported with counted false: it neither resets nor adds to the failures, and hands its half-open probe back
+// to the next call. Otherwise Allow returns ErrOpen.
+func (b *Breaker) Allow() (done func(ok, counted bool), err error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.expire()
+	switch b.state {
+	case Open:
+		return nil, ErrOpen
+	case HalfOpen:
+		if b.probes == b.cfg.HalfOpenRequests {
+			return nil, ErrOpen
+		}
+		b.probes++
+	}
+	gen := b.gen
+	return func(ok, counted bool) { b.report(gen, ok, counted) }, nil
+}
+
+func (b *Breaker) report(gen uint64, ok, counted bool) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if gen != b.gen {
+		return // the call started before the last transition, it says nothing about the current state
+	}
+	switch {
+	case !counted:
+		if b.state == HalfOpen {
+			b.probes-- // let another call probe in its place
+		}
+	case b.state == Closed && ok:
+		b.failures = 0
+	case b.state == Closed:
+		if b.failures++; b.failures == b.cfg.FailureThreshold {
+			b.transition(Open)
+		}
+	case b.state == HalfOpen && ok:
+		if b.passed++; b.passed == b.cfg.HalfOpenRequests {
+			b.transition(Closed)
+		}
+	case b.state == HalfOpen:
+		b.transition(Open)
+	}
+}
+
+// expire moves an open breaker to half-open once its OpenTimeout has passed.
+func (b *Breaker) expire() {
+	if b.state == Open && b.cfg.Clock.Now().Sub(b.openedAt) >= b.cfg.OpenTimeout {
+		b.transition(HalfOpen)
+	}
+}
+
+func (b *Breaker) transition(to State) {
+	from := b.state
+	b.state = to
+	b.gen++
+	b.failures, b.probes, b.passed = 0, 0, 0
+	if to == Open {
+		b.openedAt = b.cfg.Clock.Now()
+	}
+	if b.cfg.OnStateChange != nil {
+		b.cfg.OnStateChange(from, to)
+	}
+}
@@ -0,0 +1,122 @@
+package client
+
+import (
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/ysyesilyurt/lets-go/bootstrap/stages/go_rulez/clock"
+)
+
+// call runs one call through b, reporting it as ok, and fails t if b turns it away.
+func call(t *testing.T, b *Breaker, ok bool) {
+	t.Helper()
+	done, err := b.Allow()
+	if err != nil {
+		t.Fatalf("Allow in state %v: %v", b.State(), err)
+	}
+	done(ok, true)
+}
+
+func TestBreakerTransitions(t *testing.T) {
+	clk := clock.NewFake(time.Unix(0, 0))
+	var mu sync.Mutex
+	var transitions []string
+	b := NewBreaker(BreakerConfig{FailureThreshold: 3, OpenTimeout: time.Minute, HalfOpenRequests: 2, Clock: clk,
+		OnStateChange: func(from, to State) {
+			mu.Lock()
+			defer mu.Unlock()
+			transitions = append(transitions, from.String()+"->"+to.String())
+		}})
+
+	/* failures only open the breaker when they come in a row */
+	call(t, b, false)
+	call(t, b, false)
+	call(t, b, true)
+	call(t, b, false)
+	call(t, b, false)
+	if b.State() != Closed {
+		t.Fatalf("state %v after two failures in a row, want closed", b.State())
+	}
+	call(t, b, false)
+	if _, err := b.Allow(); err != ErrOpen {
+		t.Fatalf("open breaker let a call through: %v", err)
+	}
+
+	/* after OpenTimeout it lets HalfOpenRequests probes through, a failing one opens it again */
+	clk.Advance(time.Minute)
+	if b.State() != HalfOpen {
+		t.Fatalf("state %v after OpenTimeout, want half-open", b.State())
+	}
+	call(t, b, false)
+	if b.State() != Open {
+		t.Fatalf("state %v after a failed probe, want open", b.State())
+	}
+
+	clk.Advance(time.Minute)
+	d1, _ := b.Allow()
+	d2, _ := b.Allow()
+	if _, err := b.Allow(); err != ErrOpen {
+		t.Fatal("half-open breaker let a third probe through")
+	}
+	d1(true, true)
+	if b.State() != HalfOpen {
+		t.Fatalf("state %v after one of two probes passed, want half-open", b.State())
+	}
+	d2(true, true)
+	if b.State() != Closed {
+		t.Fatalf("state %v after both probes passed, want closed", b.State())
+	}
+
+	want := "closed->open open->half-open half-open->open open->half-open half-open->closed"
+	if got := strings.Join(transitions, " "); got != want {
+		t.Fatalf("transitions %q, want %q", got, want)
+	}
+}
+
+func TestBreakerUncountedProbe(t *testing.T) {
+	clk := clock.NewFake(time.Unix(0, 0))
+	b := NewBreaker(BreakerConfig{FailureThreshold: 1, OpenTimeout: time.Minute, Clock: clk})
+	call(t, b, false)
+	clk.Advance(time.Minute)
+
+	/* a canceled probe gives no verdict: the breaker stays half-open and the next call probes instead */
+	done, err := b.Allow()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := b.Allow(); err != ErrOpen {
+		t.Fatal("a second probe got through while the first one runs")
+	}
+	done(true, false)
+	if b.State() != HalfOpen {
+		t.Fatalf("state %v after an uncounted probe, want half-open", b.State())
+	}
+	call(t, b, true)
+	if b.State() != Closed {
+		t.Fatalf("state %v after the next probe passed, want closed", b.State())
+	}
+
+	/* uncounted calls do not reset the failures in a row either */
+	b = NewBreaker(BreakerConfig{FailureThreshold: 2, Clock: clk})
+	call(t, b, false)
+	done, _ = b.Allow()
+	done(true, false)
+	call(t, b, false)
+	if b.State() != Open {
+		t.Fatalf("state %v, want an uncounted call between two failures not to keep the breaker closed", b.State())
+	}
+}
+
+func TestBreakerIgnoresStaleResults(t *testing.T) {
+	clk := clock.NewFake(time.Unix(0, 0))
+	b := NewBreaker(BreakerConfig{FailureThreshold: 1, OpenTimeout: time.Minute, Clock: clk})
+	slow, _ := b.Allow() // started while closed
+	call(t, b, false)
+	clk.Advance(time.Minute)
+	slow(true, true) // reports while half-open, but says nothing about the server since
+	if b.State() != HalfOpen {
+		t.Fatalf("state %v, want a result from before the breaker opened to be ignored", b.State())
+	}
+}
@@ -0,0 +1,275 @@
+// Package client makes outgoing HTTP calls survive a flaky server. Transport is an http.RoundTripper adding
+//   - a timeout per attempt, so one hanging attempt does not eat the whole budget of the call
+//   - retries with exponential backoff and jitter, for idempotent requests that failed in a way worth retrying
+//   - a circuit Breaker, to stop calling a server that keeps failing and give it room to recover
+//   - hedged requests: if an attempt is slow to answer, a second one races it and the first answer wins
+//
+// New wraps it in an http.Client:
+//
+//	c := client.New(client.Config{MaxAttempts: 3, Breaker: client.NewBreaker(client.BreakerConfig{})})
+//	resp, err := c.Get("http://localhost:8080/pairs/1/2")
+package client
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"math/rand"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// Config configures a Transport, zero fields take the defaults below.
+type Config struct {
+	Timeout     time.Duration     // per attempt, up to the end of the response headers; defaults to 5s
+	MaxAttempts int               // attempts per request, 1 means no retries; defaults to 3
+	BaseDelay   time.Duration     // backoff before the first retry, doubled for every retry after; defaults to 100ms
+	MaxDelay    time.Duration     // cap on the backoff, and on Retry-After; defaults to 2s
+	HedgeAfter  time.Duration     // send a second, racing attempt if the first one has not answered by then; 0 disables hedging
+	Breaker     *Breaker          // optional, shared by all requests of the Transport
+	Base        http.RoundTripper // sends the attempts; defaults to http.DefaultTransport
+}
+
+// Transport is an http.RoundTripper with timeouts, retries, a circuit breaker and hedging. Use NewTransport to create one.
+type Transport struct {
+	cfg Config
+}
+
+// NewTransport returns a Transport configured by cfg.
+func NewTransport(cfg Config) *Transport {
+	if cfg.Timeout <= 0 {
+		cfg.Timeout = 5 * time.Second
+	}
+	if cfg.MaxAttempts <= 0 {
+		cfg.MaxAttempts = 3
+	}
+	if cfg.BaseDelay <= 0 {
+		cfg.BaseDelay = 100 * time.Millisecond
+	}
+	if cfg.MaxDelay <= 0 {
+		cfg.MaxDelay = 2 * time.Second
+	}
+	if cfg.Base == nil {
+		cfg.Base = http.DefaultTransport
+	}
+	return &Transport{cfg: cfg}
+}
+
+// New returns an http.Client sending its requests through NewTransport(cfg).
+func New(cfg Config) *http.Client {
+	return &http.Client{Transport: NewTransport(cfg)}
+}
+
+// RoundTrip sends req, retrying failed attempts if req is idempotent. Requests with a body are only retried
+// if the body can be replayed, which http.NewRequest arranges for the usual in-memory bodies.
+// When all attempts fail it returns the outcome of the last one, a 503 response for instance, not an error of its own.
+func (t *Transport) RoundTrip(req *http.Request) (*http.Response, error) {
+	attempts := 1
+	if idempotent(req) && replayable(req) {
+		attempts = t.cfg.MaxAttempts
+	}
+	for i := 1; ; i++ {
+		resp, err := t.try(req, i > 1)
+		if i == attempts || !retryable(req, resp, err) {
+			return resp, err
+		}
+		delay := t.backoff(i, resp)
+		if resp != nil {
+			io.Copy(io.Discard, io.LimitReader(resp.Body, 4<<10)) // so the connection can be reused
+			resp.Body.Close()
+		}
+		select {
+		case <-time.After(delay):
+		case <-req.Context().Done():
+			return nil, req.Context().Err()
+		}
+	}
+}
+
+// try makes one attempt at req, hedged if enabled.
+func (t *Transport) try(req *http.Request, replay bool) (*http.Response, error) {
+	if t.cfg.HedgeAfter <= 0 || !idempotent(req) || !replayable(req) {
+		return t.attempt(req, replay)
+	}
+	return t.hedged(req, replay)
+}
+
+// hedged sends an attempt and, if it has not answered after HedgeAfter, a second one. The first answer that is not
+// worth retrying wins and the other attempt is canceled; if both are, the last one to arrive is returned.
+func (t *Transport) hedged(req *http.Request, replay bool) (*http.Response, error) {
+	type result struct {
+		resp *http.Response
+		err  error
+		n    int // of the attempt
+	}
+	results := make(chan result, 2) // buffered, so the loser does not block once nobody listens
+	var cancels []context.CancelFunc
+	send := func(replay bool) {
+		ctx, cancel := context.WithCancel(req.Context())
+		cancels = append(cancels, cancel)
+		go func(n int) {
+			resp, err := t.attempt(req.WithContext(ctx), replay)
+			results <- result{resp, err, n}
+		}(len(cancels) - 1)
+	}
+	send(replay)
+	hedge := time.NewTimer(t.cfg.HedgeAfter)
+	defer hedge.Stop()
+
+	received := 0
+	var last result
+	for {
+		select {
+		case <-hedge.C:
+			if len(cancels) == 1 && received == 0 {
+				send(true)
+			}
+			continue
+		case r := <-results:
+			received++
+			if r.n > 0 && errors.Is(r.err, ErrOpen) {
+				// A half-open breaker has no probe to spare for the hedge, which is then as good as never sent:
+				// the first attempt, the probe that may close the breaker again, is still the one to wait for.
+				if received < len(cancels) {
+					continue
+				}
+			} else {
+				if last.resp != nil {
+					last.resp.Body.Close()
+				}
+				last = r
+			}
+		}
+		if !retryable(req, last.resp, last.err) || received == len(cancels) {
+			break
+		}
+	}
+	for n, cancel := range cancels {
+		if n != last.n || last.err != nil {
+			cancel()
+		}
+	}
+	if received < len(cancels) {
+		go func() { // the canceled loser still has to be closed once it is done
+			if r := <-results; r.resp != nil {
+				r.resp.Body.Close()
+			}
+		}()
+	}
+	if last.err != nil {
+		return nil, last.err
+	}
+	last.resp.Body = &cancelBody{last.resp.Body, cancels[last.n]}
+	return last.resp, nil
+}
+
+// attempt sends req once, with its own timeout and through the breaker. The timeout covers the response headers,
+// the body can take longer, but the attempt context is only released once the body is closed.
+func (t *Transport) attempt(req *http.Request, replay bool) (*http.Response, error) {
+	ctx, cancel := context.WithCancel(req.Context())
+	out := req.Clone(ctx) // a RoundTripper must not modify the request it was given
+	if replay && req.GetBody != nil {
+		body, err := req.GetBody()
+		if err != nil {
+			cancel()
+			return nil, err
+		}
+		out.Body = body
+	}
+	var done func(ok, counted bool)
+	if t.cfg.Breaker != nil {
+		var err error
+		if done, err = t.cfg.Breaker.Allow(); err != nil {
+			cancel()
+			if out.Body != nil {
+				out.Body.Close() // a RoundTripper closes the body, also when it sends nothing
+			}
+			return nil, err
+		}
+	}
+
+	timer := time.AfterFunc(t.cfg.Timeout, cancel)
+	resp, err := t.cfg.Base.RoundTrip(out)
+	timedOut := !timer.Stop()
+	canceled := err != nil && req.Context().Err() != nil // by the caller, or by hedged for the loser of a race
+	if err != nil && timedOut && !canceled {
+		err = &timeoutError{t.cfg.Timeout, err}
+	}
+	if done != nil {
+		// a call somebody gave up on says nothing about the server, neither good nor bad
+		done(err == nil && resp.StatusCode < http.StatusInternalServerError, !canceled)
+	}
+	if err != nil {
+		cancel()
+		return nil, err
+	}
+	resp.Body = &cancelBody{resp.Body, cancel}
+	return resp, nil
+}
+
+// backoff returns how long to wait before the retry following attempt number i: a random delay up to
+// BaseDelay*2^(i-1) ("full jitter", so clients that failed together do not retry together), or what the
+// server asked for with Retry-After. Both capped at MaxDelay.
+func (t *Transport) backoff(i int, resp *http.Response) time.Duration {
+	if resp != nil {
+		if secs, err := strconv.Atoi(resp.Header.Get("Retry-After")); err == nil && secs >= 0 {
+			return min(time.Duration(secs)*time.Second, t.cfg.MaxDelay)
+		}
+	}
+	ceiling := min(t.cfg.BaseDelay<<min(i-1, 30), t.cfg.MaxDelay)
+	return time.Duration(rand.Int63n(int64(ceiling) + 1))
+}
+
+// idempotent reports whether sending req twice has the same effect as sending it once.
+// Requests of other methods can opt in with an Idempotency-Key header.
+func idempotent(req *http.Request) bool {
+	switch req.Method {
+	case "", http.MethodGet, http.MethodHead, http.MethodOptions, http.MethodTrace, http.MethodPut, http.MethodDelete:
+		return true
+	}
+	return req.Header.Get("Idempotency-Key") != ""
+}
+
+// replayable reports whether the body of req can be sent again.
+func replayable(req *http.Request) bool {
+	return req.Body == nil || req.Body == http.NoBody || req.GetBody != nil
+}
+
+// retryable reports whether an attempt failed in a way another attempt might not.
+func retryable(req *http.Request, resp *http.Response, err error) bool {
+	if err != nil {
+		return req.Context().Err() == nil && !errors.Is(err, ErrOpen)
+	}
+	switch resp.StatusCode {
+	case http.StatusTooManyRequests, http.StatusBadGateway, http.StatusServiceUnavailable, http.StatusGatewayTimeout:
+		return true
+	}
+	return false
+}
+
+// cancelBody releases the context of an attempt once its response body is closed.
+type cancelBody struct {
+	io.ReadCloser
+	cancel context.CancelFunc
+}
+
+func (b *cancelBody) Close() error {
+	err := b.ReadCloser.Close()
+	b.cancel()
+	return err
+}
+
+// timeoutError tells an attempt that ran out of time apart from the caller canceling the request.
+type timeoutError struct {
+	after time.Duration
+	err   error
+}
+
+func (e *timeoutError) Error() string {
+	return fmt.Sprintf("client: attempt timed out after %v", e.after)
+}
+func (e *timeoutError) Unwrap() error   { return e.err }
+func (e *timeoutError) Timeout() bool   { return true }
+func (e *timeoutError) Temporary() bool { return true }
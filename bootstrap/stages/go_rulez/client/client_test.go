@@ -0,0 +1,291 @@
+package client
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/ysyesilyurt/lets-go/bootstrap/stages/go_rulez/clock"
+)
+
+// flaky returns a test server answering the requests fail picks, by their number, with 503 and the others
+// with ok and the body they sent, and the number of requests it got.
+func flaky(t *testing.T, fail func(n int32) bool) (*httptest.Server, *atomic.Int32) {
+	var n atomic.Int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		if fail(n.Add(1)) {
+			http.Error(w, "try again later", http.StatusServiceUnavailable)
+			return
+		}
+		w.Write(append([]byte("ok "), body...))
+	}))
+	t.Cleanup(srv.Close)
+	return srv, &n
+}
+
+// read returns the body of resp, closing it, or the error.
+func read(resp *http.Response, err error) string {
+	if err != nil {
+		return err.Error()
+	}
+	defer resp.Body.Close()
+	b, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return err.Error()
+	}
+	return string(b)
+}
+
+func TestRetries(t *testing.T) {
+	srv, n := flaky(t, func(n int32) bool { return n%3 != 0 }) // two failures, then a success
+	c := New(Config{BaseDelay: time.Millisecond})
+
+	if got := read(c.Get(srv.URL)); got != "ok " || n.Load() != 3 {
+		t.Fatalf("GET got %q after %d attempts, want ok after 3", got, n.Load())
+	}
+
+	/* POST is not idempotent, unless it says so with an Idempotency-Key */
+	n.Store(0)
+	resp, err := c.Post(srv.URL, "text/plain", strings.NewReader("body"))
+	if err != nil || resp.StatusCode != http.StatusServiceUnavailable || n.Load() != 1 {
+		t.Fatalf("POST got %v %v after %d attempts, want a 503 after 1", resp.Status, err, n.Load())
+	}
+	resp.Body.Close()
+
+	n.Store(0)
+	req, _ := http.NewRequest("POST", srv.URL, strings.NewReader("body"))
+	req.Header.Set("Idempotency-Key", "k1")
+	if got := read(c.Do(req)); got != "ok body" || n.Load() != 3 {
+		t.Fatalf("POST with an Idempotency-Key got %q after %d attempts, want the body replayed 3 times", got, n.Load())
+	}
+
+	/* out of attempts, the last answer is returned as it is */
+	srv, n = flaky(t, func(int32) bool { return true })
+	resp, err = c.Get(srv.URL)
+	if err != nil || resp.StatusCode != http.StatusServiceUnavailable || n.Load() != 3 {
+		t.Fatalf("got %v %v after %d attempts, want a 503 after 3", resp.Status, err, n.Load())
+	}
+	resp.Body.Close()
+}
+
+func TestBackoff(t *testing.T) {
+	tr := NewTransport(Config{BaseDelay: 10 * time.Millisecond, MaxDelay: 50 * time.Millisecond})
+	for i := 1; i <= 6; i++ {
+		ceiling := min(10*time.Millisecond<<(i-1), 50*time.Millisecond)
+		var longest time.Duration
+		for range 1000 {
+			d := tr.backoff(i, nil)
+			if d < 0 || d > ceiling {
+				t.Fatalf("backoff after attempt %d = %v, want it within [0, %v]", i, d, ceiling)
+			}
+			longest = max(longest, d)
+		}
+		if longest < ceiling/2 {
+			t.Fatalf("backoff after attempt %d never went past %v of %v, the jitter does not spread", i, longest, ceiling)
+		}
+	}
+
+	resp := &http.Response{Header: http.Header{"Retry-After": {"0"}}}
+	if d := tr.backoff(3, resp); d != 0 {
+		t.Fatalf("backoff with Retry-After: 0 = %v", d)
+	}
+	resp.Header.Set("Retry-After", "120")
+	if d := tr.backoff(1, resp); d != 50*time.Millisecond {
+		t.Fatalf("backoff with Retry-After: 120 = %v, want MaxDelay", d)
+	}
+
+	/* and the retries wait no longer than that: two backoffs of at most 20ms and 40ms */
+	srv, _ := flaky(t, func(n int32) bool { return n < 3 })
+	c := New(Config{BaseDelay: 20 * time.Millisecond})
+	start := time.Now()
+	if got := read(c.Get(srv.URL)); got != "ok " {
+		t.Fatalf("got %q", got)
+	}
+	if took := time.Since(start); took > time.Second {
+		t.Fatalf("3 attempts took %v, the backoff is not capped", took)
+	}
+}
+
+func TestAttemptTimeout(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) { <-r.Context().Done() }))
+	defer srv.Close()
+	_, err := New(Config{Timeout: 20 * time.Millisecond, MaxAttempts: 2, BaseDelay: time.Millisecond}).Get(srv.URL)
+	var timeout interface{ Timeout() bool }
+	if !errors.As(err, &timeout) || !timeout.Timeout() {
+		t.Fatalf("got %v, want a timeout", err)
+	}
+}
+
+func TestHedging(t *testing.T) {
+	var n atomic.Int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if n.Add(1) == 1 {
+			<-r.Context().Done() // the first attempt is stuck until the hedge wins
+			return
+		}
+		w.Write([]byte("fast"))
+	}))
+	defer srv.Close()
+	c := New(Config{HedgeAfter: 30 * time.Millisecond})
+
+	start := time.Now()
+	if got := read(c.Get(srv.URL)); got != "fast" {
+		t.Fatalf("got %q, want the answer of the hedge", got)
+	}
+	if took := time.Since(start); took > time.Second {
+		t.Fatalf("the hedged request took %v", took)
+	}
+
+	/* an attempt answering in time is not hedged */
+	n.Store(1)
+	if got := read(c.Get(srv.URL)); got != "fast" {
+		t.Fatalf("got %q", got)
+	}
+	time.Sleep(50 * time.Millisecond)
+	if n.Load() != 2 {
+		t.Fatalf("%d requests for a fast answer, want 1", n.Load()-1)
+	}
+}
+
+func TestBreakerThroughTransport(t *testing.T) {
+	clk := clock.NewFake(time.Unix(0, 0))
+	b := NewBreaker(BreakerConfig{FailureThreshold: 3, OpenTimeout: time.Minute, Clock: clk})
+	srv, n := flaky(t, func(int32) bool { return true })
+	c := New(Config{MaxAttempts: 1, Breaker: b})
+
+	for i := 0; i < 3; i++ {
+		resp, err := c.Get(srv.URL)
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp.Body.Close()
+	}
+	if _, err := c.Get(srv.URL); !errors.Is(err, ErrOpen) || n.Load() != 3 {
+		t.Fatalf("got %v after %d requests, want ErrOpen without a 4th request", err, n.Load())
+	}
+	/* ErrOpen is not worth a retry, a retrying client fails fast as well */
+	if _, err := New(Config{Breaker: b, BaseDelay: time.Millisecond}).Get(srv.URL); !errors.Is(err, ErrOpen) || n.Load() != 3 {
+		t.Fatalf("got %v after %d requests, want ErrOpen without a 4th request", err, n.Load())
+	}
+}
+
+func TestCanceledProbeDoesNotCloseBreaker(t *testing.T) {
+	clk := clock.NewFake(time.Unix(0, 0))
+	b := NewBreaker(BreakerConfig{FailureThreshold: 1, OpenTimeout: time.Minute, Clock: clk})
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-r.Context().Done() // still failing, just slowly now
+	}))
+	defer srv.Close()
+	c := New(Config{MaxAttempts: 1, Breaker: b})
+
+	done, _ := b.Allow()
+	done(false, true)
+	clk.Advance(time.Minute)
+
+	/* the caller's deadline cuts the probe short, which says nothing about whether the server is back */
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	req, _ := http.NewRequestWithContext(ctx, "GET", srv.URL, nil)
+	if _, err := c.Do(req); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("got %v, want the deadline of the caller", err)
+	}
+	if b.State() != HalfOpen {
+		t.Fatalf("state %v after a canceled probe, want half-open", b.State())
+	}
+	if _, err := b.Allow(); err != nil {
+		t.Fatalf("the canceled probe did not hand its slot back: %v", err)
+	}
+}
+
+func TestHedgeLoserIsNotCounted(t *testing.T) {
+	b := NewBreaker(BreakerConfig{FailureThreshold: 2})
+	var n atomic.Int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if n.Add(1)%2 == 1 {
+			<-r.Context().Done() // the first attempt of every request is slow and loses
+			return
+		}
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+	defer srv.Close()
+	c := New(Config{MaxAttempts: 1, HedgeAfter: 10 * time.Millisecond, Breaker: b})
+
+	/* two failures in a row: the canceled losers in between must not count as successes and reset them */
+	for i := 0; i < 2; i++ {
+		resp, err := c.Get(srv.URL)
+		if err != nil || resp.StatusCode != http.StatusInternalServerError {
+			t.Fatalf("request %d got %v %v, want a 500", i+1, resp, err)
+		}
+		resp.Body.Close()
+		time.Sleep(20 * time.Millisecond) // for the loser to report back
+	}
+	if b.State() != Open {
+		t.Fatalf("state %v after two failed requests, want open", b.State())
+	}
+}
+
+func TestHedgeRejectedByHalfOpenBreaker(t *testing.T) {
+	clk := clock.NewFake(time.Unix(0, 0))
+	var mu sync.Mutex
+	var transitions []string
+	b := NewBreaker(BreakerConfig{FailureThreshold: 1, OpenTimeout: time.Minute, Clock: clk,
+		OnStateChange: func(from, to State) {
+			mu.Lock()
+			defer mu.Unlock()
+			transitions = append(transitions, to.String())
+		}})
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		time.Sleep(50 * time.Millisecond) // healthy again, but slower than HedgeAfter
+		w.Write([]byte("back"))
+	}))
+	defer srv.Close()
+	c := New(Config{MaxAttempts: 1, HedgeAfter: 10 * time.Millisecond, Breaker: b})
+
+	done, _ := b.Allow()
+	done(false, true)
+	clk.Advance(time.Minute)
+
+	/* the breaker has no probe left for the hedge, the request waits for the probe instead of failing with ErrOpen */
+	if got := read(c.Get(srv.URL)); got != "back" {
+		t.Fatalf("got %q, want the answer of the probe", got)
+	}
+	mu.Lock()
+	defer mu.Unlock()
+	if got := strings.Join(transitions, " "); got != "open half-open closed" {
+		t.Fatalf("transitions %q, want the probe to close the breaker", got)
+	}
+}
+
+// closeBody records whether it was closed.
+type closeBody struct {
+	io.Reader
+	closed atomic.Bool
+}
+
+func (b *closeBody) Close() error {
+	b.closed.Store(true)
+	return nil
+}
+
+func TestBodyClosedWhenBreakerIsOpen(t *testing.T) {
+	b := NewBreaker(BreakerConfig{FailureThreshold: 1})
+	done, _ := b.Allow()
+	done(false, true)
+
+	body := &closeBody{Reader: strings.NewReader("body")}
+	req, _ := http.NewRequest("POST", "http://127.0.0.1:1", body)
+	if _, err := NewTransport(Config{Breaker: b}).RoundTrip(req); !errors.Is(err, ErrOpen) {
+		t.Fatalf("got %v, want ErrOpen", err)
+	}
+	if !body.closed.Load() {
+		t.Fatal("the request body was not closed")
+	}
+}
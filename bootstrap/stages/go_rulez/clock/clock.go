@@ -0,0 +1,128 @@
+// Package clock lets time dependent code take its time from an interface instead of the time package,
+// so that tests can swap in a Fake clock and run instantly and deterministically.
+package clock
+
+import (
+	"slices"
+	"sync"
+	"time"
+)
+
+// Clock is the part of the time package our code depends on.
+type Clock interface {
+	Now() time.Time
+	// After waits for the duration to elapse and then sends the current time on the returned channel.
+	After(d time.Duration) <-chan time.Time
+	// NewTimer is After that can be stopped. Stop a timer nobody waits for anymore, or it stays pending
+	// until it fires (and keeps a Fake from telling waiting and abandoned timers apart).
+	NewTimer(d time.Duration) Timer
+}
+
+// Timer is the part of *time.Timer our code depends on.
+type Timer interface {
+	// C returns the channel the time is sent on when the timer fires.
+	C() <-chan time.Time
+	// Stop prevents the timer from firing and reports whether it was still pending.
+	Stop() bool
+}
+
+// Real returns the clock of the time package.
+func Real() Clock { return realClock{} }
+
+type realClock struct{}
+
+func (realClock) Now() time.Time                         { return time.Now() }
+func (realClock) After(d time.Duration) <-chan time.Time { return time.After(d) }
+func (realClock) NewTimer(d time.Duration) Timer         { return realTimer{time.NewTimer(d)} }
+
+type realTimer struct{ *time.Timer }
+
+func (t realTimer) C() <-chan time.Time { return t.Timer.C }
+
+// Fake is a Clock that only moves when told to with Advance. It is safe for concurrent use.
+type Fake struct {
+	mu      sync.Mutex
+	waiting *sync.Cond // signaled whenever someone starts waiting on After
+	now     time.Time
+	timers  []*fakeTimer // pending, neither fired nor stopped
+}
+
+type fakeTimer struct {
+	f  *Fake
+	at time.Time
+	ch chan time.Time
+}
+
+// NewFake returns a fake clock showing start.
+func NewFake(start time.Time) *Fake {
+	f := &Fake{now: start}
+	f.waiting = sync.NewCond(&f.mu)
+	return f
+}
+
+// Now returns the time the fake clock shows.
+func (f *Fake) Now() time.Time {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.now
+}
+
+// After returns a channel that fires once the clock has been advanced by d, right away if d <= 0.
+func (f *Fake) After(d time.Duration) <-chan time.Time { return f.NewTimer(d).C() }
+
+// NewTimer returns a timer that fires once the clock has been advanced by d, right away if d <= 0.
+func (f *Fake) NewTimer(d time.Duration) Timer {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	// the channel is buffered, so firing never blocks even if nobody listens anymore
+	t := &fakeTimer{f: f, at: f.now.Add(d), ch: make(chan time.Time, 1)}
+	if d <= 0 {
+		t.ch <- f.now
+		return t
+	}
+	f.timers = append(f.timers, t)
+	f.waiting.Broadcast()
+	return t
+}
+
+func (t *fakeTimer) C() <-chan time.Time { return t.ch }
+
+// Stop removes the timer from the pending ones, so BlockUntil no longer counts it.
+func (t *fakeTimer) Stop() bool {
+	t.f.mu.Lock()
+	defer t.f.mu.Unlock()
+	i := slices.Index(t.f.timers, t)
+	if i < 0 {
+		return false
+	}
+	t.f.timers = slices.Delete(t.f.timers, i, i+1)
+	return true
+}
+
+// Advance moves the clock forward by d and fires every After that is due, earliest first.
+func (f *Fake) Advance(d time.Duration) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.now = f.now.Add(d)
+	slices.SortStableFunc(f.timers, func(a, b *fakeTimer) int { return a.at.Compare(b.at) })
+	fired := 0
+	for _, t := range f.timers {
+		if t.at.After(f.now) {
+			break
+		}
+		t.ch <- f.now
+		fired++
+	}
+	f.timers = slices.Delete(f.timers, 0, fired)
+}
+
+// BlockUntil blocks until at least n Afters and timers are waiting for the clock to advance, stopped timers do not count.
+// A test can use it to make sure a goroutine reached its After before advancing, otherwise the Advance could happen
+// too early and be missed.
+func (f *Fake) BlockUntil(n int) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	for len(f.timers) < n {
+		f.waiting.Wait()
+	}
+}
@@ -0,0 +1,82 @@
+package clock
+
+import (
+	"testing"
+	"time"
+)
+
+func TestFakeFiresInOrder(t *testing.T) {
+	start := time.Unix(0, 0)
+	clk := NewFake(start)
+	late, early := clk.After(2*time.Second), clk.After(time.Second)
+	select {
+	case <-early:
+		t.Fatal("fired before the clock moved")
+	default:
+	}
+
+	clk.Advance(time.Second)
+	if got := <-early; !got.Equal(start.Add(time.Second)) {
+		t.Fatalf("early fired at %v", got)
+	}
+	select {
+	case <-late:
+		t.Fatal("late fired a second early")
+	default:
+	}
+	clk.Advance(time.Second)
+	<-late
+
+	if got := <-clk.After(0); !got.Equal(clk.Now()) {
+		t.Fatalf("After(0) fired at %v, want right away", got)
+	}
+}
+
+func TestFakeStop(t *testing.T) {
+	clk := NewFake(time.Unix(0, 0))
+	a, b := clk.NewTimer(time.Second), clk.NewTimer(time.Second)
+	clk.BlockUntil(2)
+	if !a.Stop() {
+		t.Fatal("Stop of a pending timer returned false")
+	}
+	if a.Stop() {
+		t.Fatal("second Stop returned true")
+	}
+	if n := len(clk.timers); n != 1 {
+		t.Fatalf("%d timers pending after Stop, want 1", n)
+	}
+
+	clk.Advance(time.Second)
+	<-b.C()
+	select {
+	case <-a.C():
+		t.Fatal("a stopped timer fired")
+	default:
+	}
+	if b.Stop() {
+		t.Fatal("Stop of a fired timer returned true")
+	}
+}
+
+func TestBlockUntil(t *testing.T) {
+	clk := NewFake(time.Unix(0, 0))
+	fired := make(chan struct{})
+	go func() {
+		<-clk.After(time.Minute)
+		close(fired)
+	}()
+	clk.BlockUntil(1)
+	clk.Advance(time.Minute)
+	<-fired
+}
+
+func TestRealTimer(t *testing.T) {
+	tm := Real().NewTimer(time.Millisecond)
+	<-tm.C()
+	if tm.Stop() {
+		t.Fatal("Stop of a fired timer returned true")
+	}
+	if !Real().NewTimer(time.Hour).Stop() {
+		t.Fatal("Stop of a pending timer returned false")
+	}
+}
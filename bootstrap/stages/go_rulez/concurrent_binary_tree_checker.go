@@ -1,66 +1,60 @@
 package main
 
-import "golang.org/x/tour/tree"
-import "fmt"
+import (
+	"fmt"
+	"math/rand"
+	"slices"
 
-// In Order Tree Traversal
-func WalkInOrder(t *tree.Tree, ch chan int) {
-	if t != nil {
-		WalkInOrder(t.Left, ch)
-		ch <- t.Value
-		WalkInOrder(t.Right, ch)
+	"github.com/ysyesilyurt/lets-go/bootstrap/stages/go_rulez/tree"
+)
+
+// newTourTree mimics tree.New(k) of golang.org/x/tour/tree,
+// a randomly shaped tree holding the values k, 2k, ..., 10k.
+func newTourTree(k int) *tree.Tree[int] {
+	t := tree.New[int]()
+	for _, v := range rand.Perm(10) {
+		t.Insert((v + 1) * k)
 	}
+	return t
 }
 
-// Walk walks the tree t sending all values
-// from the tree to the channel ch.
-func Walk(t *tree.Tree, ch chan int) {
-	WalkInOrder(t, ch)
-	close(ch)
-}
+func main() {
+	t1, t2 := newTourTree(10), newTourTree(1)
+	if !tree.Same(t1, t2) {
+		/* Print the trees sideways to see what went wrong, tree.DOT would give a Graphviz drawing instead */
+		fmt.Printf("t1 and t2 differ\nt1:\n%vt2:\n%v", t1, t2)
+	}
+	fmt.Println(tree.Same(newTourTree(1), newTourTree(1)))
 
-// Same determines whether the trees
-// t1 and t2 contain the same values.
-func Same(t1, t2 *tree.Tree) bool {
-	ch1, ch2 := make(chan int), make(chan int)
-	t1_contents, t2_contents := make([]int, 10), make([]int, 10)
-	go Walk(t1, ch1)
-	go Walk(t2, ch2)
-	for {
-		select {
-		case t1_val, ok := <-ch1:
-			if !ok {
-				ch1 = nil
-			} else {
-				t1_contents = append(t1_contents, t1_val)
-			}
-		case t2_val, ok := <-ch2:
-			if !ok {
-				ch2 = nil
-			} else {
-				t2_contents = append(t2_contents, t2_val)
-			}
-		}
-		if ch1 == nil && ch2 == nil {
-			break
-		}
+	/* Same only says yes or no, merge-walking both trees tells us how they differ */
+	fmt.Println(tree.Compare(t1.All(), t2.All(), 3))
+	fmt.Println("common:", slices.Collect(tree.Intersection(t1.All(), t2.All())))
+	fmt.Println("only in t2:", slices.Collect(tree.Difference(t2.All(), t1.All())))
+
+	/* Trees survive a round trip through the compact preorder format (and JSON) with their shape */
+	enc, _ := tree.EncodePreorder(t1.Root())
+	fmt.Println("t1 in preorder:", enc)
+	if dec, err := tree.DecodePreorder[int](enc); err != nil || dec.String() != t1.String() {
+		fmt.Println("round trip failed:", err)
 	}
-	for i, _ := range t1_contents {
-		if t1_contents[i] != t2_contents[i] {
-			return false
+
+	/*
+		Iterators: range-over-func traversals stop cleanly on break, no goroutine is left behind.
+		They are also much cheaper than Walk, go test -run XXX -bench Traversal ./tree compares them
+	*/
+	for v := range t1.InOrder() {
+		if v > 50 {
+			break
 		}
+		fmt.Print(v, " ")
 	}
-	return true
-}
+	fmt.Println()
 
-func main() {
-	t1, t2 := tree.New(10), tree.New(1)
-	fmt.Println(Same(t1, t2))
 	/*
 		// InOrder traversal test
 		ch := make(chan int)
-		t1 := tree.New(1)
-		go Walk(t1, ch)
+		t1 := newTourTree(1)
+		go tree.Walk(t1, ch)
 		for i := range ch {
 			fmt.Println(i)
 		}
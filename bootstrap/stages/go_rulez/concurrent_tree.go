@@ -0,0 +1,74 @@
+package main
+
+/* Concurrent ordered set
+tree.Concurrent lets many goroutines read while writers take turns, and hands out snapshots that
+stay consistent no matter what the writers do afterwards (see tree/concurrent.go).
+
+Run this one with the race detector to see that nobody steps on anybody's toes:
+	go run -race concurrent_tree.go
+The same stress run checks the outcome as a test as well:
+	go test -race -run Concurrent ./tree
+*/
+
+import (
+	"fmt"
+	"math/rand"
+	"slices"
+	"sync"
+	"sync/atomic"
+
+	"github.com/ysyesilyurt/lets-go/bootstrap/stages/go_rulez/tree"
+)
+
+func main() {
+	const (
+		writers   = 8
+		readers   = 8
+		mutations = 5000
+		keySpace  = 1000
+	)
+	set := tree.NewConcurrent[int]()
+	var wg sync.WaitGroup
+	var done atomic.Bool
+	var snapshots, broken atomic.Int64
+
+	for w := 0; w < writers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := 0; i < mutations; i++ {
+				if k := rand.Intn(keySpace); rand.Intn(2) == 0 {
+					set.Insert(k)
+				} else {
+					set.Delete(k)
+				}
+			}
+		}()
+	}
+
+	var readersWg sync.WaitGroup
+	for r := 0; r < readers; r++ {
+		readersWg.Add(1)
+		go func() {
+			defer readersWg.Done()
+			for !done.Load() {
+				/* a snapshot never changes under us: its keys are sorted, match its Len and its invariants hold */
+				snap := set.Snapshot()
+				keys := slices.Collect(snap.All())
+				if !slices.IsSorted(keys) || len(keys) != snap.Len() || snap.Verify() != nil {
+					broken.Add(1)
+				}
+				set.Contains(rand.Intn(keySpace))
+				snapshots.Add(1)
+			}
+		}()
+	}
+
+	wg.Wait()
+	done.Store(true)
+	readersWg.Wait()
+
+	fmt.Printf("%d writers made %d mutations while %d readers took %d snapshots, %d of them were inconsistent\n",
+		writers, writers*mutations, readers, snapshots.Load(), broken.Load())
+	fmt.Printf("final set: %d keys, height %d, invariants: %v\n", set.Len(), set.Height(), set.Verify())
+}
@@ -0,0 +1,20 @@
+// Package counter holds the concurrency safe counters of the go rulez stage,
+// starting with SafeCounter from the mutexes() demo in go_rulez.go.
+package counter
+
+// Counter is the API every counter here shares with SafeCounter.
+type Counter interface {
+	// Inc increments the counter for the given key.
+	Inc(key string)
+	// Value returns the current value of the counter for the given key.
+	Value(key string) int
+}
+
+var (
+	_ Counter = (*SafeCounter)(nil)
+	_ Counter = (*Sharded)(nil)
+	_ Counter = (*Window)(nil)
+	_ Counter = (*Expiring)(nil)
+	_ Counter = (*CountMin)(nil)
+	_ Counter = (*SpaceSaving)(nil)
+)
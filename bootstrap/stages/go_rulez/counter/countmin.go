@@ -0,0 +1,90 @@
+package counter
+
+import (
+	"fmt"
+	"hash/maphash"
+	"math"
+	"sync/atomic"
+)
+
+/*
+	Count-Min Sketch.
+
+	An exact map like SafeCounter.v grows with every new key. A Count-Min Sketch uses a fixed depth x width
+	grid of counters instead: every key hashes to one counter per row, Inc bumps all of them and Value takes
+	the smallest. Collisions can only add to a counter, so Value never undercounts, and with
+
+		width = ceil(e / epsilon)   and   depth = ceil(ln(1 / delta))
+
+	it overcounts by more than epsilon*N (N being the sum of all increments) with a probability of at most delta.
+	The counters are atomics, so any number of goroutines can Inc and Value without locks.
+*/
+
+// CountMin is a concurrent Count-Min Sketch, use NewCountMin to create one.
+// It only supports increments, negative deltas would void its error bound.
+type CountMin struct {
+	seed   maphash.Seed
+	width  uint64
+	depth  uint64
+	counts []atomic.Int64 // depth rows of width counters
+	total  atomic.Int64
+	eps    float64
+}
+
+// NewCountMin returns a sketch whose estimates exceed the true count by more than epsilon times
+// the total count with a probability of at most delta, e.g. NewCountMin(0.001, 0.01).
+// It takes about 8 * e/epsilon * ln(1/delta) bytes no matter how many keys it sees.
+// It panics unless both epsilon and delta lie strictly between 0 and 1.
+func NewCountMin(epsilon, delta float64) *CountMin {
+	if !(epsilon > 0 && epsilon < 1) || !(delta > 0 && delta < 1) {
+		panic(fmt.Sprintf("counter: Count-Min epsilon and delta must lie in (0, 1), got %v and %v", epsilon, delta))
+	}
+	width := uint64(math.Ceil(math.E / epsilon))
+	depth := uint64(math.Ceil(math.Log(1 / delta)))
+	return &CountMin{
+		seed:   maphash.MakeSeed(),
+		width:  width,
+		depth:  max(depth, 1),
+		counts: make([]atomic.Int64, width*max(depth, 1)),
+		eps:    epsilon,
+	}
+}
+
+// cell returns the counter of key in the given row. Rather than hashing the key once per row,
+// the rows combine two halves of a single hash (Kirsch and Mitzenmacher), which is as good in practice.
+func (c *CountMin) cell(h uint64, row uint64) *atomic.Int64 {
+	h1, h2 := h&math.MaxUint32, h>>32|1
+	return &c.counts[row*c.width+(h1+row*h2)%c.width]
+}
+
+// Inc increments the counter for the given key.
+func (c *CountMin) Inc(key string) { c.Add(key, 1) }
+
+// Add adds delta to the counter for the given key. It panics if delta is negative: the estimates are only
+// ever too high because counts never go down, a sketch can not take back what it added.
+func (c *CountMin) Add(key string, delta int) {
+	if delta < 0 {
+		panic(fmt.Sprintf("counter: Count-Min delta must not be negative, got %d", delta))
+	}
+	h := maphash.String(c.seed, key)
+	for row := uint64(0); row < c.depth; row++ {
+		c.cell(h, row).Add(int64(delta))
+	}
+	c.total.Add(int64(delta))
+}
+
+// Value returns the estimated count for the given key, never less than the true count.
+func (c *CountMin) Value(key string) int {
+	h := maphash.String(c.seed, key)
+	est := int64(math.MaxInt64)
+	for row := uint64(0); row < c.depth; row++ {
+		est = min(est, c.cell(h, row).Load())
+	}
+	return int(est)
+}
+
+// Total returns the sum of all increments so far.
+func (c *CountMin) Total() int { return int(c.total.Load()) }
+
+// ErrorBound returns epsilon*Total, the most any estimate exceeds its true count by (with probability 1-delta).
+func (c *CountMin) ErrorBound() int { return int(math.Ceil(c.eps * float64(c.Total()))) }
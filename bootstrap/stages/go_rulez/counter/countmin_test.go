@@ -0,0 +1,74 @@
+package counter
+
+import (
+	"math"
+	"math/rand"
+	"strconv"
+	"sync"
+	"testing"
+)
+
+// zipfStream returns a skewed stream of events, few keys are very frequent and most are rare, with their exact counts.
+func zipfStream(events int) ([]string, map[string]int) {
+	zipf := rand.NewZipf(rand.New(rand.NewSource(1)), 1.1, 1, 1<<20)
+	stream := make([]string, events)
+	exact := make(map[string]int)
+	for i := range stream {
+		stream[i] = "key-" + strconv.FormatUint(zipf.Uint64(), 10)
+		exact[stream[i]]++
+	}
+	return stream, exact
+}
+
+// feed increments c with every event of stream, from 4 goroutines at once.
+func feed(c Counter, stream []string) {
+	var wg sync.WaitGroup
+	for p := 0; p < 4; p++ {
+		wg.Add(1)
+		go func(part []string) {
+			defer wg.Done()
+			for _, key := range part {
+				c.Inc(key)
+			}
+		}(stream[p*len(stream)/4 : (p+1)*len(stream)/4])
+	}
+	wg.Wait()
+}
+
+func TestCountMinErrorBound(t *testing.T) {
+	const delta = 0.01
+	stream, exact := zipfStream(200000)
+	cms := NewCountMin(0.0005, delta)
+	feed(cms, stream)
+
+	if cms.Total() != len(stream) {
+		t.Fatalf("Total = %d, want %d", cms.Total(), len(stream))
+	}
+	over := 0
+	for key, n := range exact {
+		est := cms.Value(key)
+		if est < n {
+			t.Fatalf("Value(%s) = %d is below the true count %d", key, est, n)
+		}
+		if est > n+cms.ErrorBound() {
+			over++
+		}
+	}
+	/* each key may be off by more than the bound with probability delta, twice that leaves room for bad luck */
+	if share := float64(over) / float64(len(exact)); share > 2*delta {
+		t.Fatalf("%.2f%% of the keys are beyond the error bound %d, want at most %.2f%%", 100*share, cms.ErrorBound(), 100*delta)
+	}
+}
+
+func TestNewCountMinRejectsInvalidBounds(t *testing.T) {
+	for _, p := range [][2]float64{{0, 0.01}, {0.01, 0}, {1, 0.01}, {0.01, 1}, {-1, 0.5}, {0.5, 2}, {math.NaN(), 0.5}} {
+		func() {
+			defer func() {
+				if recover() == nil {
+					t.Errorf("NewCountMin(%v, %v) did not panic", p[0], p[1])
+				}
+			}()
+			NewCountMin(p[0], p[1])
+		}()
+	}
+}
@@ -0,0 +1,88 @@
+package counter
+
+import (
+	"sync"
+	"time"
+
+	"github.com/ysyesilyurt/lets-go/bootstrap/stages/go_rulez/clock"
+)
+
+// Expiring is a SafeCounter whose keys expire: a key that saw no increment for the TTL is dropped
+// by a janitor goroutine and starts over from zero. Use NewExpiring to create one and Close to stop the janitor.
+type Expiring struct {
+	mu      sync.Mutex
+	clock   clock.Clock
+	ttl     time.Duration
+	v       map[string]*expiringValue
+	janitor *janitor
+}
+
+type expiringValue struct {
+	n    int
+	last time.Time
+}
+
+// NewExpiring returns an expiring counter, clk may be nil for the real clock. It panics if ttl is not positive.
+func NewExpiring(ttl time.Duration, clk clock.Clock) *Expiring {
+	if ttl <= 0 {
+		panic("counter: ttl must be positive")
+	}
+	if clk == nil {
+		clk = clock.Real()
+	}
+	c := &Expiring{clock: clk, ttl: ttl, v: make(map[string]*expiringValue)}
+	c.janitor = startJanitor(clk, max(ttl/2, time.Millisecond), c.Sweep)
+	return c
+}
+
+// Inc increments the counter for the given key.
+func (c *Expiring) Inc(key string) { c.Add(key, 1) }
+
+// Add adds delta to the counter for the given key and returns the new value.
+func (c *Expiring) Add(key string, delta int) int {
+	now := c.clock.Now()
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	v, ok := c.v[key]
+	if !ok {
+		v = &expiringValue{}
+		c.v[key] = v
+	}
+	v.n += delta
+	v.last = now
+	return v.n
+}
+
+// Value returns the current value of the counter for the given key, 0 once it expired.
+func (c *Expiring) Value(key string) int {
+	now := c.clock.Now()
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if v, ok := c.v[key]; ok && now.Sub(v.last) < c.ttl { // do not wait for the janitor to hide expired keys
+		return v.n
+	}
+	return 0
+}
+
+// Len returns the number of keys that have not been swept yet.
+func (c *Expiring) Len() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return len(c.v)
+}
+
+// Sweep drops the keys that saw no increment for the TTL. The janitor calls it periodically,
+// calling it directly is handy with a fake clock.
+func (c *Expiring) Sweep() {
+	now := c.clock.Now()
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for key, v := range c.v {
+		if now.Sub(v.last) >= c.ttl {
+			delete(c.v, key)
+		}
+	}
+}
+
+// Close stops the janitor.
+func (c *Expiring) Close() { c.janitor.stop() }
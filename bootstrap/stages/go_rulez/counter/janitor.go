@@ -0,0 +1,40 @@
+package counter
+
+import (
+	"sync"
+	"time"
+
+	"github.com/ysyesilyurt/lets-go/bootstrap/stages/go_rulez/clock"
+)
+
+// janitor calls sweep every interval on its own goroutine until stopped.
+type janitor struct {
+	done chan struct{}
+	wg   sync.WaitGroup
+	once sync.Once
+}
+
+func startJanitor(clk clock.Clock, every time.Duration, sweep func()) *janitor {
+	j := &janitor{done: make(chan struct{})}
+	j.wg.Add(1)
+	go func() {
+		defer j.wg.Done()
+		for {
+			t := clk.NewTimer(every)
+			select {
+			case <-t.C():
+				sweep()
+			case <-j.done:
+				t.Stop()
+				return
+			}
+		}
+	}()
+	return j
+}
+
+// stop stops the janitor and waits for its goroutine to return, calling it again does nothing.
+func (j *janitor) stop() {
+	j.once.Do(func() { close(j.done) })
+	j.wg.Wait()
+}
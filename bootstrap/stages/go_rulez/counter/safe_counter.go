@@ -0,0 +1,57 @@
+package counter
+
+import (
+	"maps"
+	"sync"
+)
+
+// SafeCounter is safe to use concurrently.
+type SafeCounter struct {
+	v   map[string]int
+	mux sync.Mutex
+}
+
+// NewSafeCounter returns a SafeCounter with all counts at zero.
+func NewSafeCounter() *SafeCounter {
+	return &SafeCounter{v: make(map[string]int)}
+}
+
+// Inc increments the counter for the given key.
+func (c *SafeCounter) Inc(key string) {
+	c.mux.Lock()
+	// Lock so only one goroutine at a time can access the map c.v.
+	c.v[key]++
+	c.mux.Unlock()
+}
+
+// Add adds delta (which may be negative) to the counter for the given key and returns the new value.
+func (c *SafeCounter) Add(key string, delta int) int {
+	c.mux.Lock()
+	defer c.mux.Unlock()
+	c.v[key] += delta
+	return c.v[key]
+}
+
+// Delete drops the counter for the given key and reports whether there was one.
+func (c *SafeCounter) Delete(key string) bool {
+	c.mux.Lock()
+	defer c.mux.Unlock()
+	_, ok := c.v[key]
+	delete(c.v, key)
+	return ok
+}
+
+// Value returns the current value of the counter for the given key.
+func (c *SafeCounter) Value(key string) int {
+	c.mux.Lock()
+	// Lock so only one goroutine at a time can access the map c.v.
+	defer c.mux.Unlock() // defer unlock
+	return c.v[key]
+}
+
+// Snapshot returns a copy of all counts.
+func (c *SafeCounter) Snapshot() map[string]int {
+	c.mux.Lock()
+	defer c.mux.Unlock()
+	return maps.Clone(c.v)
+}
@@ -0,0 +1,129 @@
+package counter
+
+import (
+	"hash/maphash"
+	"runtime"
+	"slices"
+	"sync"
+	"sync/atomic"
+)
+
+/*
+	Sharded counter.
+
+	SafeCounter serializes every Inc and Value on one mutex, so with hundreds of goroutines incrementing
+	they mostly wait for each other. Sharded spreads the keys over N shards by hash, each shard with its own lock,
+	so goroutines working on different keys rarely meet. On top of that every count is an atomic.Int64:
+	once a key exists, Add only needs the shard's read lock and an atomic add, and any number of goroutines
+	can do that at the same time, even on the same key. The write lock is only taken to add a new key or to Reset.
+*/
+
+// Sharded is a counter split into independently locked shards. Use NewSharded to create one.
+type Sharded struct {
+	seed   maphash.Seed
+	shards []shard
+	mask   uint64
+}
+
+type shard struct {
+	mu sync.RWMutex
+	m  map[string]*atomic.Int64
+	_  [32]byte // pads a shard to a 64 byte cache line, so neighbouring shards do not slow each other down
+}
+
+// NewSharded returns a counter with n shards, rounded up to a power of two.
+// For n <= 0 it picks 4 shards per CPU.
+func NewSharded(n int) *Sharded {
+	if n <= 0 {
+		n = 4 * runtime.GOMAXPROCS(0)
+	}
+	size := 1
+	for size < n {
+		size <<= 1
+	}
+	c := &Sharded{seed: maphash.MakeSeed(), shards: make([]shard, size), mask: uint64(size - 1)}
+	for i := range c.shards {
+		c.shards[i].m = make(map[string]*atomic.Int64)
+	}
+	return c
+}
+
+func (c *Sharded) shard(key string) *shard {
+	return &c.shards[maphash.String(c.seed, key)&c.mask]
+}
+
+// Inc increments the counter for the given key.
+func (c *Sharded) Inc(key string) { c.Add(key, 1) }
+
+// Add adds delta (which may be negative) to the counter for the given key and returns the new value.
+func (c *Sharded) Add(key string, delta int) int {
+	s := c.shard(key)
+	s.mu.RLock()
+	if v, ok := s.m[key]; ok { // fast path: the key is there, an atomic add under the shared lock will do
+		n := v.Add(int64(delta))
+		s.mu.RUnlock()
+		return int(n)
+	}
+	s.mu.RUnlock()
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	v, ok := s.m[key] // someone may have added it while we were not holding any lock
+	if !ok {
+		v = new(atomic.Int64)
+		s.m[key] = v
+	}
+	return int(v.Add(int64(delta)))
+}
+
+// Value returns the current value of the counter for the given key.
+func (c *Sharded) Value(key string) int {
+	s := c.shard(key)
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	if v, ok := s.m[key]; ok {
+		return int(v.Load())
+	}
+	return 0
+}
+
+// Snapshot returns a copy of all counts. Every count is read atomically, but Adds to existing keys only take
+// the read lock of their shard and go on while it is copied, so not even a single shard is a point-in-time view:
+// increments racing with Snapshot may show up in it for some keys and not for others.
+func (c *Sharded) Snapshot() map[string]int {
+	snap := make(map[string]int)
+	for i := range c.shards {
+		s := &c.shards[i]
+		s.mu.RLock()
+		for k, v := range s.m {
+			snap[k] = int(v.Load())
+		}
+		s.mu.RUnlock()
+	}
+	return snap
+}
+
+// Keys returns the keys that have a counter, sorted.
+func (c *Sharded) Keys() []string {
+	var keys []string
+	for i := range c.shards {
+		s := &c.shards[i]
+		s.mu.RLock()
+		for k := range s.m {
+			keys = append(keys, k)
+		}
+		s.mu.RUnlock()
+	}
+	slices.Sort(keys)
+	return keys
+}
+
+// Reset drops all counters.
+func (c *Sharded) Reset() {
+	for i := range c.shards {
+		s := &c.shards[i]
+		s.mu.Lock()
+		s.m = make(map[string]*atomic.Int64)
+		s.mu.Unlock()
+	}
+}
@@ -0,0 +1,88 @@
+package counter
+
+import (
+	"maps"
+	"slices"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"testing"
+)
+
+func TestShardedConcurrentIncrements(t *testing.T) {
+	c := NewSharded(0)
+	var wg sync.WaitGroup
+	for i := 0; i < 1000; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			c.Inc("somekey")
+			c.Add("other", 2)
+			c.Inc("key-" + strconv.Itoa(i%10))
+		}()
+	}
+	wg.Wait()
+
+	want := map[string]int{"somekey": 1000, "other": 2000}
+	for i := 0; i < 10; i++ {
+		want["key-"+strconv.Itoa(i)] = 100
+	}
+	if got := c.Snapshot(); !maps.Equal(got, want) {
+		t.Fatalf("Snapshot = %v, want %v", got, want)
+	}
+	if got := c.Value("somekey"); got != 1000 {
+		t.Fatalf("Value(somekey) = %d, want 1000", got)
+	}
+	keys := c.Keys()
+	slices.Sort(keys)
+	if want := slices.Sorted(maps.Keys(want)); !slices.Equal(keys, want) {
+		t.Fatalf("Keys = %v, want %v", keys, want)
+	}
+	c.Reset()
+	if got := c.Snapshot(); len(got) != 0 || c.Value("somekey") != 0 {
+		t.Fatalf("Snapshot after Reset = %v", got)
+	}
+}
+
+/*
+SafeCounter against Sharded under contention, 90% Inc and 10% Value.
+SetParallelism(100) runs the benchmark bodies on 100 goroutines per CPU, that is the contention we are after.
+*/
+func BenchmarkCounters(b *testing.B) {
+	keys := make([]string, 1024)
+	for i := range keys {
+		keys[i] = "key-" + strconv.Itoa(i)
+	}
+	workloads := []struct {
+		name string
+		keys []string
+	}{{"hot-key", keys[:1]}, {"1024-keys", keys}}
+	counters := []struct {
+		name string
+		new  func() Counter
+	}{
+		{"SafeCounter", func() Counter { return NewSafeCounter() }},
+		{"Sharded", func() Counter { return NewSharded(0) }},
+	}
+	for _, w := range workloads {
+		for _, c := range counters {
+			b.Run(w.name+"/"+c.name, func(b *testing.B) {
+				ctr := c.new()
+				var next atomic.Uint64
+				b.SetParallelism(100)
+				b.RunParallel(func(pb *testing.PB) {
+					i := next.Add(7919) // every goroutine starts somewhere else in the key space
+					for pb.Next() {
+						key := w.keys[i%uint64(len(w.keys))]
+						if i%10 == 0 {
+							ctr.Value(key)
+						} else {
+							ctr.Inc(key)
+						}
+						i++
+					}
+				})
+			})
+		}
+	}
+}
@@ -0,0 +1,133 @@
+package counter
+
+import (
+	"container/heap"
+	"fmt"
+	"slices"
+	"sync"
+)
+
+/*
+	Space-Saving top-K (Metwally, Agrawal and El Abbadi, 2005).
+
+	SpaceSaving monitors a fixed number of keys. A monitored key is simply counted, a new key takes over
+	the slot of the key with the smallest count and inherits that count (+1), remembering it as its error.
+	So counts only overestimate, by at most their Error, which never exceeds N/capacity (N being the sum
+	of all increments), and every key that occurred more than N/capacity times is guaranteed to be monitored.
+	Pick a capacity a few times larger than the k you will ask TopK for.
+*/
+
+// Entry is a monitored key of SpaceSaving. Its true count is between Count-Error and Count.
+type Entry struct {
+	Key   string
+	Count int
+	Error int
+}
+
+// SpaceSaving tracks the most frequent keys in a fixed amount of memory, it is safe for concurrent use.
+// Use NewSpaceSaving to create one.
+type SpaceSaving struct {
+	mu       sync.Mutex
+	capacity int
+	total    int
+	index    map[string]*ssEntry
+	heap     ssHeap // min-heap on Count, so the slot to give away is always on top
+}
+
+type ssEntry struct {
+	Entry
+	pos int // in the heap
+}
+
+// NewSpaceSaving returns a tracker monitoring at most capacity keys.
+func NewSpaceSaving(capacity int) *SpaceSaving {
+	return &SpaceSaving{capacity: max(capacity, 1), index: make(map[string]*ssEntry, capacity)}
+}
+
+// Inc increments the counter for the given key.
+func (s *SpaceSaving) Inc(key string) { s.Add(key, 1) }
+
+// Add adds delta to the counter for the given key. It panics if delta is not positive, the error bounds
+// of Space-Saving only hold for counts that grow.
+func (s *SpaceSaving) Add(key string, delta int) {
+	if delta <= 0 {
+		panic(fmt.Sprintf("counter: Space-Saving delta must be positive, got %d", delta))
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.total += delta
+	if e, ok := s.index[key]; ok {
+		e.Count += delta
+		heap.Fix(&s.heap, e.pos)
+		return
+	}
+	if len(s.heap) < s.capacity {
+		e := &ssEntry{Entry: Entry{Key: key, Count: delta}}
+		s.index[key] = e
+		heap.Push(&s.heap, e)
+		return
+	}
+	// evict the least counted key, the newcomer may have occurred up to that many times unnoticed
+	e := s.heap[0]
+	delete(s.index, e.Key)
+	e.Entry = Entry{Key: key, Count: e.Count + delta, Error: e.Count}
+	s.index[key] = e
+	heap.Fix(&s.heap, 0)
+}
+
+// Value returns the estimated count for the given key, 0 if it is not monitored
+// (in which case its true count is at most the smallest monitored Count).
+func (s *SpaceSaving) Value(key string) int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if e, ok := s.index[key]; ok {
+		return e.Count
+	}
+	return 0
+}
+
+// TopK returns the k monitored keys with the highest counts, highest first, none if k <= 0.
+func (s *SpaceSaving) TopK(k int) []Entry {
+	s.mu.Lock()
+	entries := make([]Entry, len(s.heap))
+	for i, e := range s.heap {
+		entries[i] = e.Entry
+	}
+	s.mu.Unlock()
+	slices.SortFunc(entries, func(a, b Entry) int { return b.Count - a.Count })
+	return entries[:max(min(k, len(entries)), 0)]
+}
+
+// Total returns the sum of all increments so far.
+func (s *SpaceSaving) Total() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.total
+}
+
+// ErrorBound returns Total/capacity, the most any Count exceeds its true count by.
+func (s *SpaceSaving) ErrorBound() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.total / s.capacity
+}
+
+type ssHeap []*ssEntry
+
+func (h ssHeap) Len() int           { return len(h) }
+func (h ssHeap) Less(i, j int) bool { return h[i].Count < h[j].Count }
+func (h ssHeap) Swap(i, j int) {
+	h[i], h[j] = h[j], h[i]
+	h[i].pos, h[j].pos = i, j
+}
+func (h *ssHeap) Push(x any) {
+	e := x.(*ssEntry)
+	e.pos = len(*h)
+	*h = append(*h, e)
+}
+func (h *ssHeap) Pop() any {
+	old := *h
+	e := old[len(old)-1]
+	*h = old[:len(old)-1]
+	return e
+}
@@ -0,0 +1,70 @@
+package counter
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestSpaceSavingBounds(t *testing.T) {
+	const capacity = 100
+	stream, exact := zipfStream(200000)
+	top := NewSpaceSaving(capacity)
+	feed(top, stream)
+
+	/* Count-Error <= true count <= Count for every monitored key, and Count exceeds it by at most Total/capacity */
+	entries := top.TopK(capacity)
+	if len(entries) != capacity {
+		t.Fatalf("TopK(%d) returned %d entries", capacity, len(entries))
+	}
+	for _, e := range entries {
+		if n := exact[e.Key]; e.Count-e.Error > n || n > e.Count || e.Count-n > top.ErrorBound() {
+			t.Fatalf("%s: count %d, error %d, but the true count is %d (error bound %d)", e.Key, e.Count, e.Error, n, top.ErrorBound())
+		}
+	}
+	if !slices.IsSortedFunc(entries, func(a, b Entry) int { return b.Count - a.Count }) {
+		t.Fatalf("TopK is not sorted by count: %v", entries)
+	}
+	/* a key heavier than Total/capacity must be monitored */
+	for key, n := range exact {
+		if n > top.Total()/capacity && top.Value(key) == 0 {
+			t.Fatalf("%s was seen %d times but is not monitored", key, n)
+		}
+	}
+}
+
+func TestTopKClampsK(t *testing.T) {
+	top := NewSpaceSaving(10)
+	for _, key := range []string{"a", "b", "b", "c", "c", "c"} {
+		top.Inc(key)
+	}
+	for k, want := range map[int]int{-1: 0, 0: 0, 2: 2, 3: 3, 100: 3} {
+		if got := top.TopK(k); len(got) != want {
+			t.Errorf("TopK(%d) returned %d entries, want %d", k, len(got), want)
+		}
+	}
+	if got := top.TopK(1); got[0].Key != "c" || got[0].Count != 3 {
+		t.Errorf("TopK(1) = %v, want c with 3", got)
+	}
+}
+
+func TestAddRejectsBadDeltas(t *testing.T) {
+	cm, top := NewCountMin(0.01, 0.01), NewSpaceSaving(10)
+	cm.Add("a", 0) // adds nothing, but is no harm either
+	for name, add := range map[string]func(){
+		"CountMin.Add(-1)":    func() { cm.Add("a", -1) },
+		"SpaceSaving.Add(-1)": func() { top.Add("a", -1) },
+		"SpaceSaving.Add(0)":  func() { top.Add("a", 0) },
+	} {
+		func() {
+			defer func() {
+				if recover() == nil {
+					t.Errorf("%s did not panic", name)
+				}
+			}()
+			add()
+		}()
+	}
+	if cm.Value("a") != 0 || top.Value("a") != 0 || len(top.TopK(10)) != 0 {
+		t.Fatal("a rejected delta was counted")
+	}
+}
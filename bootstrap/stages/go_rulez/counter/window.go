@@ -0,0 +1,164 @@
+package counter
+
+import (
+	"sync"
+	"time"
+
+	"github.com/ysyesilyurt/lets-go/bootstrap/stages/go_rulez/clock"
+)
+
+/*
+	Sliding window counter.
+
+	SafeCounter counts since the beginning of time, for rate tracking we want "how many in the last minute".
+	Window splits the window into buckets, every key keeps a ring buffer with one count per bucket:
+
+		window = 1m, buckets = 6  ->  each bucket covers 10s
+
+		| 3 | 0 | 5 | 1 | 2 | 4 |   <- the newest bucket takes the increments, Value sums them all
+		          ^ newest
+
+	As time passes the ring moves on and buckets that fell out of the window are zeroed before they are reused.
+	So Value(key) covers the current, partly filled bucket plus the buckets-1 full ones before it,
+	the window slides one bucket at a time: more buckets means a smoother window but more memory per key.
+
+	Keys that saw no increment for TTL are dropped by a janitor goroutine, call Close to stop it.
+*/
+
+// WindowConfig configures a Window counter, only Window is required.
+type WindowConfig struct {
+	Window  time.Duration // the span Value counts over, e.g. time.Minute
+	Buckets int           // how many steps the window slides in, defaults to 60
+	TTL     time.Duration // keys idle for this long are dropped, defaults to Window
+	Clock   clock.Clock   // defaults to clock.Real()
+}
+
+// Window counts increments per key over a sliding window of time. Use NewWindow to create one.
+type Window struct {
+	mu      sync.Mutex
+	clock   clock.Clock
+	width   time.Duration // of a single bucket
+	buckets int
+	ttl     time.Duration
+	keys    map[string]*windowKey
+	janitor *janitor
+}
+
+type windowKey struct {
+	counts []int
+	newest int64 // number of the newest bucket, counting buckets since the Unix epoch
+	total  int
+	last   time.Time // of the last increment, for the TTL
+}
+
+// NewWindow returns a sliding window counter and starts its janitor. It panics if cfg.Window is not positive.
+func NewWindow(cfg WindowConfig) *Window {
+	if cfg.Window <= 0 {
+		panic("counter: Window must be positive")
+	}
+	if cfg.Buckets <= 0 {
+		cfg.Buckets = 60
+	}
+	if cfg.TTL <= 0 {
+		cfg.TTL = cfg.Window
+	}
+	if cfg.Clock == nil {
+		cfg.Clock = clock.Real()
+	}
+	w := &Window{
+		clock:   cfg.Clock,
+		width:   max(cfg.Window/time.Duration(cfg.Buckets), 1),
+		buckets: cfg.Buckets,
+		ttl:     cfg.TTL,
+		keys:    make(map[string]*windowKey),
+	}
+	w.janitor = startJanitor(cfg.Clock, max(cfg.TTL/2, w.width), w.Sweep)
+	return w
+}
+
+func (w *Window) bucket(t time.Time) int64 { return t.UnixNano() / int64(w.width) }
+
+// Inc increments the counter for the given key.
+func (w *Window) Inc(key string) { w.Add(key, 1) }
+
+// Add adds delta to the counter for the given key and returns its count over the window.
+func (w *Window) Add(key string, delta int) int {
+	now := w.clock.Now()
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	k, ok := w.keys[key]
+	if !ok {
+		k = &windowKey{counts: make([]int, w.buckets), newest: w.bucket(now)}
+		w.keys[key] = k
+	}
+	k.advance(w.bucket(now))
+	k.counts[slot(k.newest, w.buckets)] += delta
+	k.total += delta
+	k.last = now
+	return k.total
+}
+
+// Value returns the count for the given key over the window.
+func (w *Window) Value(key string) int {
+	now := w.clock.Now()
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	k, ok := w.keys[key]
+	if !ok {
+		return 0
+	}
+	k.advance(w.bucket(now))
+	return k.total
+}
+
+// Snapshot returns the counts over the window of all keys that are not expired yet.
+func (w *Window) Snapshot() map[string]int {
+	now := w.clock.Now()
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	snap := make(map[string]int, len(w.keys))
+	for key, k := range w.keys {
+		k.advance(w.bucket(now))
+		snap[key] = k.total
+	}
+	return snap
+}
+
+// Sweep drops the keys that saw no increment for TTL. The janitor calls it periodically,
+// calling it directly is handy with a fake clock.
+func (w *Window) Sweep() {
+	now := w.clock.Now()
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	for key, k := range w.keys {
+		if now.Sub(k.last) >= w.ttl {
+			delete(w.keys, key)
+		}
+	}
+}
+
+// Close stops the janitor, the counter keeps working but idle keys are no longer dropped.
+func (w *Window) Close() { w.janitor.stop() }
+
+// advance moves the ring forward to bucket, zeroing the buckets that fell out of the window.
+func (k *windowKey) advance(bucket int64) {
+	steps := bucket - k.newest
+	if steps <= 0 {
+		return
+	}
+	if steps >= int64(len(k.counts)) {
+		clear(k.counts)
+		k.total = 0
+	} else {
+		for b := k.newest + 1; b <= bucket; b++ {
+			i := slot(b, len(k.counts))
+			k.total -= k.counts[i]
+			k.counts[i] = 0
+		}
+	}
+	k.newest = bucket
+}
+
+func slot(bucket int64, buckets int) int {
+	return int((bucket%int64(buckets) + int64(buckets)) % int64(buckets))
+}
@@ -0,0 +1,105 @@
+package counter
+
+import (
+	"testing"
+	"time"
+
+	"github.com/ysyesilyurt/lets-go/bootstrap/stages/go_rulez/clock"
+)
+
+func TestWindowSlides(t *testing.T) {
+	clk := clock.NewFake(time.Unix(1000, 0))
+	w := NewWindow(WindowConfig{Window: time.Minute, Buckets: 6, Clock: clk})
+	defer w.Close()
+
+	w.Inc("a")
+	w.Inc("a")
+	clk.Advance(25 * time.Second)
+	w.Inc("a")
+	if got := w.Value("a"); got != 3 {
+		t.Fatalf("Value after 25s = %d, want 3", got)
+	}
+	clk.Advance(40 * time.Second) // the first two increments are 65s old now
+	if got := w.Value("a"); got != 1 {
+		t.Fatalf("Value after 65s = %d, want 1", got)
+	}
+	clk.Advance(time.Minute)
+	if got := w.Value("a"); got != 0 {
+		t.Fatalf("Value after another minute = %d, want 0", got)
+	}
+}
+
+func TestWindowJanitorDropsIdleKeys(t *testing.T) {
+	clk := clock.NewFake(time.Unix(1000, 0))
+	w := NewWindow(WindowConfig{Window: time.Minute, Buckets: 6, Clock: clk})
+	defer w.Close()
+
+	w.Inc("a")
+	clk.BlockUntil(1) // the janitor is waiting for its next sweep
+	clk.Advance(2 * time.Minute)
+	clk.BlockUntil(1) // and swept, it waits for the one after
+	if snap := w.Snapshot(); len(snap) != 0 {
+		t.Fatalf("Snapshot after the TTL = %v, want it empty", snap)
+	}
+}
+
+func TestExpiring(t *testing.T) {
+	clk := clock.NewFake(time.Unix(0, 0))
+	c := NewExpiring(time.Minute, clk)
+	defer c.Close()
+
+	c.Inc("x")
+	clk.BlockUntil(1) // the janitor sweeps every 30s, let it keep up with the clock
+	clk.Advance(30 * time.Second)
+	if got := c.Add("x", 2); got != 3 {
+		t.Fatalf("Add after 30s = %d, want 3", got)
+	}
+	clk.BlockUntil(1)
+	clk.Advance(time.Minute)
+	if got := c.Value("x"); got != 0 {
+		t.Fatalf("Value after a minute idle = %d, want 0", got)
+	}
+	clk.BlockUntil(1)
+	if c.Len() != 0 {
+		t.Fatalf("Len after the janitor swept = %d, want 0", c.Len())
+	}
+	c.Close() // twice is fine
+}
+
+func TestClosedJanitorLeavesNoTimer(t *testing.T) {
+	clk := clock.NewFake(time.Unix(0, 0))
+	c1, c2 := NewExpiring(time.Minute, clk), NewExpiring(time.Minute, clk)
+	defer c2.Close()
+	clk.BlockUntil(2)
+	c1.Close()
+
+	two := make(chan struct{})
+	go func() {
+		clk.BlockUntil(2)
+		close(two)
+	}()
+	select {
+	case <-two:
+		t.Fatal("the timer of a closed janitor still counts in BlockUntil")
+	case <-time.After(50 * time.Millisecond):
+	}
+	c3 := NewExpiring(time.Minute, clk)
+	defer c3.Close()
+	<-two
+}
+
+func TestNonPositiveDurationsPanic(t *testing.T) {
+	for name, f := range map[string]func(){
+		"NewWindow":   func() { NewWindow(WindowConfig{}) },
+		"NewExpiring": func() { NewExpiring(0, nil) },
+	} {
+		func() {
+			defer func() {
+				if recover() == nil {
+					t.Errorf("%s did not panic", name)
+				}
+			}()
+			f()
+		}()
+	}
+}
@@ -0,0 +1,45 @@
+package main
+
+/* Counter service
+Serves a counter.SafeCounter over HTTP as a JSON REST API (see counterapi/), until Ctrl-C:
+	go run counter_service.go -addr :8081
+	curl -X POST localhost:8081/counters/visits/inc
+	curl -X POST localhost:8081/counters/visits/inc -H 'Content-Type: application/json' -d '{"by": 5}'
+	curl localhost:8081/counters/visits
+	curl -H 'Accept: text/plain' localhost:8081/counters/visits
+	curl 'localhost:8081/counters?prefix=vis&limit=10'
+	curl -X DELETE localhost:8081/counters/visits
+*/
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"log/slog"
+	"os"
+
+	"github.com/ysyesilyurt/lets-go/bootstrap/stages/go_rulez/counter"
+	"github.com/ysyesilyurt/lets-go/bootstrap/stages/go_rulez/counterapi"
+	"github.com/ysyesilyurt/lets-go/bootstrap/stages/go_rulez/middleware"
+	"github.com/ysyesilyurt/lets-go/bootstrap/stages/go_rulez/server"
+)
+
+func main() {
+	addr := flag.String("addr", ":8081", "address to listen on")
+	flag.Parse()
+
+	logger := slog.New(slog.NewTextHandler(os.Stderr, nil))
+	api := counterapi.New(counter.NewSafeCounter())
+	h := middleware.RequestID(middleware.AccessLog(logger)(middleware.Recover(logger)(middleware.Gzip(api))))
+
+	srv := server.New(server.Config{Addr: *addr}, h)
+	if err := srv.Start(); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+	logger.Info("serving counters", "url", srv.URL()+"/counters")
+	if err := srv.Run(context.Background()); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+}
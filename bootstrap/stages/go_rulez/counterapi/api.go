@@ -0,0 +1,215 @@
+// Package counterapi serves a counter.SafeCounter over HTTP, a tiny shared counter service:
+//
+//	POST   /counters/{key}/inc   increments the counter, by 1 or by the "by" of a {"by": n} body
+//	GET    /counters/{key}       a single counter, unknown keys count 0 like they do in SafeCounter
+//	GET    /counters             all counters sorted by key, ?prefix= filters them and ?limit= and ?after= page through them
+//	DELETE /counters/{key}       drops the counter
+//
+// Responses are JSON, or plain text for clients that Accept only that, and errors are RFC 9457 problem details
+// (application/problem+json) whatever the client accepts.
+package counterapi
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"net/url"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/ysyesilyurt/lets-go/bootstrap/stages/go_rulez/counter"
+	"github.com/ysyesilyurt/lets-go/bootstrap/stages/go_rulez/router"
+)
+
+const (
+	// DefaultLimit is the page size of GET /counters without a limit, MaxLimit the largest one allowed.
+	DefaultLimit = 100
+	MaxLimit     = 1000
+
+	maxKeyLen  = 256
+	maxBodyLen = 1 << 10
+)
+
+// Counter is a single counter as the API shows it.
+type Counter struct {
+	Key   string `json:"key"`
+	Value int    `json:"value"`
+}
+
+// Page is a page of GET /counters. Next is the URL of the following page, empty on the last one.
+type Page struct {
+	Counters []Counter `json:"counters"`
+	Next     string    `json:"next,omitempty"`
+}
+
+// IncRequest is the optional body of POST /counters/{key}/inc.
+type IncRequest struct {
+	By int `json:"by"`
+}
+
+type api struct {
+	counters *counter.SafeCounter
+}
+
+// New returns the handler of the API, serving c.
+func New(c *counter.SafeCounter) http.Handler {
+	a := &api{counters: c}
+	r := router.New()
+	r.NotFound = http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		writeProblem(w, req, http.StatusNotFound, "no such resource")
+	})
+	r.MethodNotAllowed = http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		writeProblem(w, req, http.StatusMethodNotAllowed, req.Method+" is not supported here, see the Allow header")
+	})
+	r.HandleFunc("POST", "/counters/{key}/inc", a.inc)
+	r.HandleFunc("GET", "/counters/{key}", a.get)
+	r.HandleFunc("DELETE", "/counters/{key}", a.delete)
+	r.HandleFunc("GET", "/counters", a.list)
+	return r
+}
+
+func (a *api) inc(w http.ResponseWriter, r *http.Request) {
+	key, ok := pathKey(w, r)
+	if !ok {
+		return
+	}
+	typ, ok := negotiate(w, r)
+	if !ok {
+		return
+	}
+	body := IncRequest{By: 1}
+	if err := decodeBody(r, &body); err != nil {
+		var p *problemError
+		if errors.As(err, &p) {
+			writeProblem(w, r, p.status, p.detail)
+		} else {
+			writeProblem(w, r, http.StatusBadRequest, "invalid JSON body: "+err.Error())
+		}
+		return
+	}
+	if body.By < 1 {
+		writeProblem(w, r, http.StatusUnprocessableEntity, fmt.Sprintf(`"by" must be at least 1, got %d`, body.By))
+		return
+	}
+	write(w, typ, http.StatusOK, Counter{key, a.counters.Add(key, body.By)})
+}
+
+func (a *api) get(w http.ResponseWriter, r *http.Request) {
+	key, ok := pathKey(w, r)
+	if !ok {
+		return
+	}
+	typ, ok := negotiate(w, r)
+	if !ok {
+		return
+	}
+	write(w, typ, http.StatusOK, Counter{key, a.counters.Value(key)})
+}
+
+func (a *api) delete(w http.ResponseWriter, r *http.Request) {
+	key, ok := pathKey(w, r)
+	if !ok {
+		return
+	}
+	if !a.counters.Delete(key) {
+		writeProblem(w, r, http.StatusNotFound, fmt.Sprintf("there is no counter %q", key))
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// list pages through the counters by key: ?after=k starts the page after key k, so pages stay put
+// while counters come and go, unlike an offset would.
+func (a *api) list(w http.ResponseWriter, r *http.Request) {
+	typ, ok := negotiate(w, r)
+	if !ok {
+		return
+	}
+	q := r.URL.Query()
+	prefix, after := q.Get("prefix"), q.Get("after")
+	limit := DefaultLimit
+	if s := q.Get("limit"); s != "" {
+		n, err := strconv.Atoi(s)
+		if err != nil || n < 1 || n > MaxLimit {
+			writeProblem(w, r, http.StatusBadRequest, fmt.Sprintf("limit must be a number from 1 to %d, got %q", MaxLimit, s))
+			return
+		}
+		limit = n
+	}
+
+	snapshot := a.counters.Snapshot()
+	var keys []string
+	for k := range snapshot {
+		if strings.HasPrefix(k, prefix) && k > after {
+			keys = append(keys, k)
+		}
+	}
+	slices.Sort(keys)
+
+	page := Page{Counters: []Counter{}}
+	for _, k := range keys[:min(limit, len(keys))] {
+		page.Counters = append(page.Counters, Counter{k, snapshot[k]})
+	}
+	if len(keys) > limit {
+		next := url.Values{"limit": {strconv.Itoa(limit)}, "after": {keys[limit-1]}}
+		if prefix != "" {
+			next.Set("prefix", prefix)
+		}
+		page.Next = r.URL.Path + "?" + next.Encode()
+		w.Header().Set("Link", fmt.Sprintf(`<%s>; rel="next"`, page.Next))
+	}
+	write(w, typ, http.StatusOK, page)
+}
+
+// pathKey returns the {key} of the request, or writes a problem if it is no good.
+func pathKey(w http.ResponseWriter, r *http.Request) (string, bool) {
+	key := r.PathValue("key")
+	if len(key) > maxKeyLen {
+		writeProblem(w, r, http.StatusBadRequest, fmt.Sprintf("keys are at most %d bytes long", maxKeyLen))
+		return "", false
+	}
+	return key, true
+}
+
+// decodeBody decodes the JSON body of r into v, leaving v alone if the body is empty.
+func decodeBody(r *http.Request, v any) error {
+	if ct := r.Header.Get("Content-Type"); ct != "" {
+		if mt, _, err := mime.ParseMediaType(ct); err != nil || mt != "application/json" {
+			return &problemError{http.StatusUnsupportedMediaType, "request bodies must be application/json"}
+		}
+	}
+	dec := json.NewDecoder(io.LimitReader(r.Body, maxBodyLen))
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(v); err != nil && err != io.EOF {
+		return err
+	}
+	if dec.More() {
+		return errors.New("more than one JSON value")
+	}
+	return nil
+}
+
+// write writes v as typ, one of the offers of negotiate.
+func write(w http.ResponseWriter, typ string, status int, v any) {
+	w.Header().Set("Content-Type", typ+"; charset=utf-8")
+	w.Header().Add("Vary", "Accept")
+	w.WriteHeader(status)
+	if typ == textType {
+		switch v := v.(type) {
+		case Counter:
+			fmt.Fprintln(w, v.Value)
+		case Page:
+			for _, c := range v.Counters {
+				fmt.Fprintf(w, "%s\t%d\n", c.Key, c.Value)
+			}
+		}
+		return
+	}
+	enc := json.NewEncoder(w)
+	enc.SetEscapeHTML(false) // keeps the & of Next readable
+	enc.Encode(v)
+}
@@ -0,0 +1,200 @@
+package counterapi
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/ysyesilyurt/lets-go/bootstrap/stages/go_rulez/counter"
+)
+
+// do serves a request with the body and header name, value pairs given through h.
+func do(h http.Handler, method, target, body string, header ...string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(method, target, strings.NewReader(body))
+	for i := 0; i+1 < len(header); i += 2 {
+		req.Header.Add(header[i], header[i+1])
+	}
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, req)
+	return rec
+}
+
+// problem decodes the problem details of rec, failing unless it is one with the given status.
+func problem(t *testing.T, rec *httptest.ResponseRecorder, status int) Problem {
+	t.Helper()
+	var p Problem
+	if ct := rec.Header().Get("Content-Type"); ct != "application/problem+json" {
+		t.Fatalf("status %d with Content-Type %q, want a problem: %s", rec.Code, ct, rec.Body)
+	}
+	if err := json.NewDecoder(rec.Body).Decode(&p); err != nil {
+		t.Fatal(err)
+	}
+	if rec.Code != status || p.Status != status || p.Title != http.StatusText(status) || p.Type != "about:blank" {
+		t.Fatalf("got %d %+v, want a %d problem", rec.Code, p, status)
+	}
+	return p
+}
+
+func TestInc(t *testing.T) {
+	api := New(counter.NewSafeCounter())
+	for _, tt := range []struct {
+		body, contentType, want string
+	}{
+		{"", "", `{"key":"visits","value":1}`},
+		{`{"by": 5}`, "application/json", `{"key":"visits","value":6}`},
+		{`{"by": 2}`, "application/json; charset=utf-8", `{"key":"visits","value":8}`},
+		{`{}`, "application/json", `{"key":"visits","value":9}`},
+	} {
+		rec := do(api, "POST", "/counters/visits/inc", tt.body, "Content-Type", tt.contentType)
+		if rec.Code != 200 || strings.TrimSpace(rec.Body.String()) != tt.want {
+			t.Fatalf("POST %q: %d %s, want %s", tt.body, rec.Code, rec.Body, tt.want)
+		}
+	}
+
+	if rec := do(api, "GET", "/counters/visits", ""); strings.TrimSpace(rec.Body.String()) != `{"key":"visits","value":9}` {
+		t.Fatalf("GET after the increments: %s", rec.Body)
+	}
+	if rec := do(api, "GET", "/counters/unknown", ""); rec.Code != 200 || strings.TrimSpace(rec.Body.String()) != `{"key":"unknown","value":0}` {
+		t.Fatalf("GET of an unknown key: %d %s, want it to count 0", rec.Code, rec.Body)
+	}
+	if rec := do(api, "DELETE", "/counters/visits", ""); rec.Code != http.StatusNoContent {
+		t.Fatalf("DELETE: %d", rec.Code)
+	}
+	if p := problem(t, do(api, "DELETE", "/counters/visits", ""), 404); p.Instance != "/counters/visits" {
+		t.Fatalf("deleting twice: %+v", p)
+	}
+}
+
+func TestIncRejectsBadBodies(t *testing.T) {
+	c := counter.NewSafeCounter()
+	api := New(c)
+	for _, tt := range []struct {
+		body, contentType string
+		status            int
+	}{
+		{`{"by": 5}`, "text/plain", http.StatusUnsupportedMediaType},
+		{`{"by": 5}`, "application/json;;", http.StatusUnsupportedMediaType},
+		{`{"by": 0}`, "application/json", http.StatusUnprocessableEntity},
+		{`{"by": -3}`, "application/json", http.StatusUnprocessableEntity},
+		{`{"by": `, "application/json", http.StatusBadRequest},
+		{`{"by": "5"}`, "application/json", http.StatusBadRequest},
+		{`{"step": 5}`, "application/json", http.StatusBadRequest},
+		{`{"by": 1} {"by": 1}`, "application/json", http.StatusBadRequest},
+	} {
+		problem(t, do(api, "POST", "/counters/visits/inc", tt.body, "Content-Type", tt.contentType), tt.status)
+	}
+	problem(t, do(api, "POST", "/counters/"+strings.Repeat("k", maxKeyLen+1)+"/inc", ""), http.StatusBadRequest)
+	if v := c.Value("visits"); v != 0 {
+		t.Fatalf("rejected requests counted %d", v)
+	}
+}
+
+func TestNegotiation(t *testing.T) {
+	c := counter.NewSafeCounter()
+	c.Add("k", 7)
+	api := New(c)
+	for _, tt := range []struct {
+		accept []string
+		want   string // the Content-Type, or empty for 406
+	}{
+		{nil, "application/json; charset=utf-8"},
+		{[]string{"*/*"}, "application/json; charset=utf-8"},
+		{[]string{"text/plain"}, "text/plain; charset=utf-8"},
+		{[]string{"text/*"}, "text/plain; charset=utf-8"},
+		{[]string{"application/json;q=0.5, text/plain"}, "text/plain; charset=utf-8"},
+		{[]string{"application/json;q=0.5", "text/plain;q=0.9"}, "text/plain; charset=utf-8"},
+		{[]string{"text/plain;q=0, */*"}, "application/json; charset=utf-8"},
+		{[]string{"text/*;q=0.2, application/*;q=0.1"}, "text/plain; charset=utf-8"},
+		{[]string{"image/png"}, ""},
+		{[]string{"application/json;q=0, text/*;q=0"}, ""},
+	} {
+		var header []string
+		for _, a := range tt.accept {
+			header = append(header, "Accept", a)
+		}
+		rec := do(api, "GET", "/counters/k", "", header...)
+		if tt.want == "" {
+			problem(t, rec, http.StatusNotAcceptable)
+			continue
+		}
+		if ct := rec.Header().Get("Content-Type"); rec.Code != 200 || ct != tt.want || rec.Header().Get("Vary") != "Accept" {
+			t.Errorf("Accept %q: %d with Content-Type %q, want %q", tt.accept, rec.Code, ct, tt.want)
+		}
+	}
+
+	if rec := do(api, "GET", "/counters/k", "", "Accept", "text/plain"); rec.Body.String() != "7\n" {
+		t.Fatalf("plain text counter %q, want \"7\\n\"", rec.Body)
+	}
+	if rec := do(api, "GET", "/counters", "", "Accept", "text/plain"); rec.Body.String() != "k\t7\n" {
+		t.Fatalf("plain text page %q, want \"k\\t7\\n\"", rec.Body)
+	}
+}
+
+func TestPaging(t *testing.T) {
+	c := counter.NewSafeCounter()
+	for i, k := range []string{"vis/e", "vis/a", "other", "vis/c", "vis/b", "vis/d"} {
+		c.Add(k, i+1)
+	}
+	api := New(c)
+
+	/* follow the next links until the last page, which has none */
+	var got []string
+	target := "/counters?prefix=vis/&limit=2"
+	for pages := 0; target != ""; pages++ {
+		if pages == 3 {
+			t.Fatalf("more than 3 pages of 2 for 5 counters, at %s", target)
+		}
+		rec := do(api, "GET", target, "")
+		var page Page
+		if err := json.NewDecoder(rec.Body).Decode(&page); rec.Code != 200 || err != nil {
+			t.Fatalf("GET %s: %d %v", target, rec.Code, err)
+		}
+		if link, want := rec.Header().Get("Link"), `<`+page.Next+`>; rel="next"`; (page.Next == "") != (link == "") || page.Next != "" && link != want {
+			t.Fatalf("GET %s: Link %q with next %q", target, link, page.Next)
+		}
+		for _, c := range page.Counters {
+			got = append(got, c.Key)
+		}
+		target = page.Next
+	}
+	if want := []string{"vis/a", "vis/b", "vis/c", "vis/d", "vis/e"}; !slices.Equal(got, want) {
+		t.Fatalf("paged through %v, want %v", got, want)
+	}
+
+	rec := do(api, "GET", "/counters?after=vis/d", "")
+	if body := strings.TrimSpace(rec.Body.String()); body != `{"counters":[{"key":"vis/e","value":1}]}` {
+		t.Fatalf("the page after vis/d: %s", body)
+	}
+	if rec := do(api, "GET", "/counters?prefix=none", ""); strings.TrimSpace(rec.Body.String()) != `{"counters":[]}` {
+		t.Fatalf("an empty page: %s", rec.Body)
+	}
+	for _, limit := range []string{"0", "-1", "1001", "ten"} {
+		problem(t, do(api, "GET", "/counters?limit="+limit, ""), http.StatusBadRequest)
+	}
+}
+
+func TestRoutingProblems(t *testing.T) {
+	api := New(counter.NewSafeCounter())
+	for _, tt := range []struct {
+		method, path string
+		status       int
+		allow        string
+	}{
+		{"GET", "/nothing/here", 404, ""},
+		{"GET", "/counters/a/b", 404, ""},
+		{"GET", "/counters/k/inc", 405, "OPTIONS, POST"},
+		{"PUT", "/counters/k", 405, "DELETE, GET, HEAD, OPTIONS"},
+		{"POST", "/counters", 405, "GET, HEAD, OPTIONS"},
+	} {
+		rec := do(api, tt.method, tt.path, "")
+		if p := problem(t, rec, tt.status); p.Instance != tt.path || p.Detail == "" {
+			t.Errorf("%s %s: %+v", tt.method, tt.path, p)
+		}
+		if allow := rec.Header().Get("Allow"); allow != tt.allow {
+			t.Errorf("%s %s: Allow %q, want %q", tt.method, tt.path, allow, tt.allow)
+		}
+	}
+}
@@ -0,0 +1,70 @@
+package counterapi
+
+import (
+	"mime"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+const (
+	jsonType = "application/json"
+	textType = "text/plain"
+)
+
+// offers are the media types the API can answer with, the first one wins ties.
+var offers = []string{jsonType, textType}
+
+// negotiate picks the offer the Accept header of r likes best, or writes a 406 problem if it likes none.
+// A missing Accept header accepts anything.
+func negotiate(w http.ResponseWriter, r *http.Request) (string, bool) {
+	accept := r.Header.Values("Accept")
+	if len(accept) == 0 {
+		return offers[0], true
+	}
+	best, bestQ := "", 0.0
+	for _, offer := range offers {
+		if q := quality(accept, offer); q > bestQ {
+			best, bestQ = offer, q
+		}
+	}
+	if best == "" {
+		writeProblem(w, r, http.StatusNotAcceptable, "we can answer with "+strings.Join(offers, " or "))
+		return "", false
+	}
+	return best, true
+}
+
+// quality returns the q the Accept header values give offer, from the most specific media range matching it:
+// "text/plain" beats "text/*" beats "*/*". It is 0 if no range matches.
+func quality(accept []string, offer string) float64 {
+	typ, _, _ := strings.Cut(offer, "/")
+	q, specificity := 0.0, -1
+	for _, value := range accept {
+		for _, r := range strings.Split(value, ",") {
+			mt, params, err := mime.ParseMediaType(strings.TrimSpace(r))
+			if err != nil {
+				continue
+			}
+			s := -1
+			switch {
+			case mt == offer:
+				s = 2
+			case mt == typ+"/*":
+				s = 1
+			case mt == "*/*":
+				s = 0
+			}
+			if s <= specificity {
+				continue
+			}
+			specificity, q = s, 1
+			if v, ok := params["q"]; ok {
+				if f, err := strconv.ParseFloat(v, 64); err == nil && f >= 0 && f <= 1 {
+					q = f
+				}
+			}
+		}
+	}
+	return q
+}
@@ -0,0 +1,40 @@
+package counterapi
+
+import (
+	"encoding/json"
+	"net/http"
+)
+
+// Problem is an RFC 9457 problem details body, the API answers every error with one:
+//
+//	{"type": "about:blank", "title": "Not Found", "status": 404, "detail": "there is no counter \"x\"", "instance": "/counters/x"}
+//
+// All our problems are plain HTTP errors, so the type is always about:blank and the title the status text.
+type Problem struct {
+	Type     string `json:"type"`
+	Title    string `json:"title"`
+	Status   int    `json:"status"`
+	Detail   string `json:"detail,omitempty"`
+	Instance string `json:"instance,omitempty"`
+}
+
+// problemError is an error that knows which problem to answer with.
+type problemError struct {
+	status int
+	detail string
+}
+
+func (e *problemError) Error() string { return e.detail }
+
+func writeProblem(w http.ResponseWriter, r *http.Request, status int, detail string) {
+	w.Header().Set("Content-Type", "application/problem+json")
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(Problem{
+		Type:     "about:blank",
+		Title:    http.StatusText(status),
+		Status:   status,
+		Detail:   detail,
+		Instance: r.URL.Path,
+	})
+}
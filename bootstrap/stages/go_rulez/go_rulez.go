@@ -6,11 +6,22 @@ package main
 */
 
 import (
+	"context"
 	"fmt"
 	"time"
-	"sync"
+	"strconv"
 	"net/http"
-	"io/ioutil"
+	"log/slog"
+	"os"
+
+	"github.com/ysyesilyurt/lets-go/bootstrap/stages/go_rulez/counter"
+	"github.com/ysyesilyurt/lets-go/bootstrap/stages/go_rulez/loadgen"
+	"github.com/ysyesilyurt/lets-go/bootstrap/stages/go_rulez/metrics"
+	"github.com/ysyesilyurt/lets-go/bootstrap/stages/go_rulez/middleware"
+	"github.com/ysyesilyurt/lets-go/bootstrap/stages/go_rulez/pool"
+	"github.com/ysyesilyurt/lets-go/bootstrap/stages/go_rulez/ratelimit"
+	"github.com/ysyesilyurt/lets-go/bootstrap/stages/go_rulez/router"
+	"github.com/ysyesilyurt/lets-go/bootstrap/stages/go_rulez/server"
 )
 
 func main() {
@@ -80,6 +91,9 @@ func channels(args ...interface{}) {
 
 		By default, sends and receives 'block until the other side is ready'. 
 		This allows goroutines to synchronize without explicit locks or condition variables.
+
+		Once many parts of a program want the same events, handing channels around by hand gets messy,
+		a pubsub.Broker (see pubsub/ and pubsub_events.go) routes them by topic instead.
 	*/
 
 	sli := []int{7, 2, 8, -9, 4, 0}
@@ -214,6 +228,9 @@ func select_and_close() {
 		to ensure that it never gets selected again.
 
 		https://stackoverflow.com/questions/13666253/breaking-out-of-a-select-statement-when-all-channels-are-closed
+
+		Instead of writing this loop for every pair of channels, chans.Merge (see chans/) merges any number of them,
+		and chans.Tee is built on the same trick (see channel_combinators.go).
 	*/
 
 
@@ -253,6 +270,8 @@ func select_and_close() {
 func select_with_default() {
 	tick := time.Tick(250 * time.Millisecond)
 	boom := time.After(1500 * time.Millisecond)
+	/* without a throttle the default case would print as fast as the CPU goes, a limiter lets it through 20 times a second (see ratelimit/) */
+	throttle := ratelimit.NewTokenBucket(ratelimit.TokenBucketConfig{Rate: 20})
 	for {
 		select {
 		case <-tick:
@@ -263,33 +282,11 @@ func select_with_default() {
 		default:
 			/* Both channels are not ready */
 			fmt.Println("    .")
-			time.Sleep(50 * time.Millisecond)
+			throttle.Wait(context.Background())
 		}
 	}
 }
 
-// SafeCounter is safe to use concurrently.
-type SafeCounter struct {
-	v   map[string]int
-	mux sync.Mutex
-}
-
-// Inc increments the counter for the given key.
-func (c *SafeCounter) Inc(key string) {
-	c.mux.Lock()
-	// Lock so only one goroutine at a time can access the map c.v.
-	c.v[key]++
-	c.mux.Unlock()
-}
-
-// Value returns the current value of the counter for the given key.
-func (c *SafeCounter) Value(key string) int {
-	c.mux.Lock()
-	// Lock so only one goroutine at a time can access the map c.v.
-	defer c.mux.Unlock() // defer unlock
-	return c.v[key]
-}
-
 func mutexes(args ...interface{}) {
 	/* 
 		For sync. purposes as many language supports, Go also supports mutexes with sync.Mutex
@@ -299,20 +296,37 @@ func mutexes(args ...interface{}) {
 		We can define a block of code to be executed in mutual exclusion by surrounding it with a call to Lock and Unlock.
 		Also remember we can use Unlock()'s with defers...	
 
-		We have defined a counter named SafeCounter which has a map (which will be shared among goroutines)
+		We have defined a counter named SafeCounter (see counter/safe_counter.go) which has a map (which will be shared among goroutines)
 		and a mutex to synchronize the control between these goroutines that want to access that map.
 
 		Inc and Value methods are implemented for SafeCounter and map accesses are managed by mutex'ed blocks.
 		If we were to omit such kind of a synchronization mechanism in SafeCounter struct, then all those goroutines
 		would go for race conditions and corrupted results/errors would took place.
+
+		With hundreds of goroutines that single mutex becomes the bottleneck, counter.Sharded splits the keys
+		over many independently locked shards instead (see sharded_counter.go for a benchmark).
 	*/
 
-	c := SafeCounter{v: make(map[string]int)}
+	/*
+		Instead of a goroutine per Inc and a time.Sleep in the hope that they are all done by then,
+		we hand the 1000 Inc's to the workers of a pool (see pool/), Close returns once every one of them ran.
+	*/
+	c := counter.NewSafeCounter()
+	p := pool.New(context.Background(), pool.Config{Workers: 8})
 	for i := 0; i < 1000; i++ {
-		go c.Inc("somekey")
+		err := p.Submit(context.Background(), func(context.Context) error {
+			c.Inc("somekey")
+			return nil
+		})
+		if err != nil {
+			fmt.Println("Could not submit an Inc", err) // don't ignore errors
+			break
+		}
 	}
 
-	time.Sleep(time.Second)
+	if err := p.Close(); err != nil {
+		fmt.Println("An Inc failed", err) // don't ignore errors
+	}
 	fmt.Println(c.Value("somekey"))
 }
 
@@ -330,10 +344,13 @@ func (p pair) String() string { // p is called the "receiver"
 
 // Make pair an http.Handler by implementing its only method, ServeHTTP.
 func (p pair) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	// Let us wait for 2 seconds and then respond (Just for fun :D), unless the client or a timeout gives up on us first.
+	select {
+	case <-time.After(2000 * time.Millisecond):
+	case <-r.Context().Done():
+		return
+	}
     // Serve data with a method of http.ResponseWriter.
-	fmt.Println("SERVER: Got a request! Let us wait for 2 seconds and then respond (Just for fun :D)")
-	time.Sleep(2000 * time.Millisecond)
-	fmt.Println("SERVER: Ok, Processing Request")
     w.Write([]byte("Go Rulez!"))
 }
 
@@ -344,36 +361,86 @@ func web(args ...interface{}) {
 
 			* First parameter of ListenAndServe is TCP address to listen to.
     		* Second parameter is an interface, specifically http.Handler.
+
+		Our router (see router/) is an http.Handler too, it picks another handler by the method and path of the request,
+		and hands the {x} and {y} parts of /pairs/{x}/{y} to it as r.PathValue("x") and r.PathValue("y").
+		Next to pair we serve /metrics in the Prometheus text format (see metrics/), so monitoring can scrape
+		how many requests each route got (kept in a counter.SafeCounter) and how many are being served right now.
+
+		Everything every route needs goes into middleware (see middleware/), handlers wrapped by router.Use:
+			* RequestID tags each request with an X-Request-Id, AccessLog logs it with its status and latency
+			* Recover answers 500 if a handler panics, instead of the connection just dying
+			* Gzip compresses responses, and Timeout gives up on slow routes
 	*/
-	serve := func() {
-			fmt.Println("SERVER: Go Server Started listening from port 8080!")
-	        err := http.ListenAndServe(":8080", pair{})
-	        if err != nil {
-	        	fmt.Println("SERVER: Could not serve requests", err) // don't ignore errors
-	        } else {
-	        	fmt.Println("SERVER: Shutting down server...")
-	        }
-	    }
-
-    go func() {
-    	/* let us send 5 sample HTTP GET request to our Go Server within 0.75 secs intervals */
-	    for i := 0; i < 5; i++ {
-			time.Sleep(750 * time.Millisecond)
-    	    go mockRequestServer()
-	    }
-    }()
-    serve()
-}
+	logger := slog.New(slog.NewTextHandler(os.Stdout, nil))
+	requests := counter.NewSafeCounter()
+	registry := metrics.NewRegistry()
+	registry.Register(metrics.SnapshotFunc("gorulez_requests_total", "Requests served, by route.", metrics.CounterType, "route", requests.Snapshot))
+	inFlight := registry.NewGauge("gorulez_requests_in_flight", "Requests being served right now.")
+	/*
+		Every label value is a time series of its own, so the route label only takes values from a fixed set:
+		one per route we register below, and "other" for everything else. Labelling by the raw path would give
+		every /pairs/1/2 and every 404 a series of its own, and anybody could make us keep as many as they like.
+	*/
+	routeLabels := map[string]string{"GET /": "pair", "GET /metrics": "metrics", "GET /pairs/{x}/{y}": "pairs"}
+
+	rt := router.New()
+	/* the first middleware wraps all the others, so the access log knows the request ID and sees the 500 of Recover */
+	rt.Use(middleware.RequestID, middleware.AccessLog(logger), middleware.Recover(logger))
+	rt.Use(func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			inFlight.Inc()
+			defer inFlight.Dec()
+			next.ServeHTTP(w, r)
+			/* the router fills in r.Pattern once it found a route, so /pairs/1/2 and /pairs/3/4 count as the same route */
+			route, ok := routeLabels[r.Pattern]
+			if !ok {
+				route = "other"
+			}
+			requests.Inc(route)
+		})
+	}, middleware.Gzip)
+	rt.Handle("GET", "/", middleware.Timeout(3*time.Second)(pair{}))
+	rt.Handle("GET", "/metrics", registry)
+	pairs := rt.Group("/pairs")
+	pairs.HandleFunc("GET", "/{x}/{y}", func(w http.ResponseWriter, r *http.Request) {
+		x, errX := strconv.Atoi(r.PathValue("x"))
+		y, errY := strconv.Atoi(r.PathValue("y"))
+		if errX != nil || errY != nil {
+			http.Error(w, "x and y must be integers", http.StatusBadRequest)
+			return
+		}
+		fmt.Fprintln(w, pair{x, y})
+	})
 
-func mockRequestServer() {
-	fmt.Println("CLIENT: Sending a GET request to http://localhost:8080")
-    resp, err := http.Get("http://localhost:8080")
-	defer resp.Body.Close()
-    
-    if err != nil {
-    	fmt.Println("CLIENT: Could not complete request", err) // don't ignore errors
-    }
-    
-    body, err := ioutil.ReadAll(resp.Body)
-    fmt.Printf("CLIENT: Webserver said: `%s` terminating...\n", string(body))
-}
\ No newline at end of file
+	/*
+		http.ListenAndServe can not be stopped, so we serve with our server package instead (see server/).
+		It takes its address and timeouts from a server.Config, and shuts down gracefully on Ctrl-C (SIGINT), SIGTERM
+		or once ctx is canceled: it stops accepting connections, but lets the requests pair is still sleeping on finish.
+	*/
+	srv := server.New(server.Config{Addr: ":8080"}, rt)
+	if err := srv.Start(); err != nil {
+		fmt.Println("SERVER: Could not listen on port 8080", err) // don't ignore errors
+		return
+	}
+	fmt.Println("SERVER: Go Server Started listening from port 8080!")
+	ctx, cancel := context.WithCancel(context.Background())
+
+	go func() {
+		/* let us load our Go Server with 5 sample HTTP GET requests at 0.75 secs intervals, and see how it coped (see loadgen/) */
+		report, err := loadgen.Run(ctx, loadgen.Config{URL: srv.URL(), Concurrency: 5, Requests: 5, Rate: 1 / 0.75})
+		if err != nil {
+			fmt.Println("CLIENT: Could not load the server", err) // don't ignore errors
+		} else {
+			report.WriteText(os.Stdout)
+		}
+		fmt.Println("SERVER: Shutting down server, waiting for in-flight requests...")
+		cancel()
+	}()
+
+	if err := srv.Run(ctx); err != nil {
+		fmt.Println("SERVER: Could not serve requests", err) // don't ignore errors
+	} else {
+		fmt.Println("SERVER: All requests served, bye!")
+	}
+}
@@ -0,0 +1,129 @@
+package main
+
+/* Goroutine leaks
+A goroutine blocked on a channel nobody will ever use again is never collected, it just sits there until the program
+exits. Our demos have their share of them:
+	* tree.Walk blocks on its next send when nobody reads the rest, tree.Same returns at the first difference
+	  and closes a quit channel so that its walkers do not (see tree/walk.go)
+	* a generator like fib_2 in select_channels runs until told to quit, forget to tell it and it never returns
+	* a goroutine like receive_from_chan in channels waits to hand over a value nobody reads
+leakcheck.Take snapshots the goroutines before such code runs, Leaked tells which ones are still around after it,
+and with which stacks. In a test, leakcheck.Check(t, ...) does both and fails the test.
+Goroutines that do not leak but block each other for good are found with a watchdog, see the end of main.
+*/
+
+import (
+	"fmt"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/ysyesilyurt/lets-go/bootstrap/stages/go_rulez/leakcheck"
+	"github.com/ysyesilyurt/lets-go/bootstrap/stages/go_rulez/tree"
+)
+
+// fib sends Fibonacci numbers until quit is closed, like fib_2 of select_channels.
+func fib(c chan<- int, quit <-chan struct{}) {
+	x, y := 0, 1
+	for {
+		select {
+		case c <- x:
+			x, y = y, x+y
+		case <-quit:
+			return
+		}
+	}
+}
+
+func check(name string, f func()) {
+	s := leakcheck.Take()
+	f()
+	leaked := s.Leaked(leakcheck.Config{Grace: 100 * time.Millisecond})
+	if len(leaked) == 0 {
+		fmt.Printf("%s: no leaks\n\n", name)
+		return
+	}
+	fmt.Printf("%s: goroutines leaked (%d)\n%s\n\n", name, len(leaked), leakcheck.Format(leaked))
+}
+
+func main() {
+	t1, t2 := tree.New(1, 2, 3, 4, 5), tree.New(1, 2, 30, 40, 50)
+
+	check("a walk abandoned halfway", func() {
+		ch := make(chan int)
+		go tree.Walk(t1, ch)
+		<-ch // we only wanted the smallest key, Walk is stuck sending the next one
+	})
+	check("Same returning at the first difference", func() { tree.Same(t1, t2) })
+
+	check("a generator nobody tells to quit", func() {
+		c := make(chan int)
+		go fib(c, make(chan struct{}))
+		for i := 0; i < 10; i++ {
+			<-c
+		}
+	})
+	check("a generator told to quit", func() {
+		c, quit := make(chan int), make(chan struct{})
+		go fib(c, quit)
+		for i := 0; i < 10; i++ {
+			<-c
+		}
+		close(quit)
+	})
+
+	check("an answer nobody waits for", func() {
+		c := make(chan int)
+		go func() {
+			time.Sleep(50 * time.Millisecond) // working out the answer takes a while
+			c <- 42                           // we gave up waiting below, so this send blocks forever
+		}()
+		select {
+		case <-c:
+		case <-time.After(10 * time.Millisecond):
+		}
+	})
+	check("an answer with room to be dropped", func() {
+		c := make(chan int, 1) // the one value fits the buffer, the sender returns either way
+		go func() {
+			time.Sleep(50 * time.Millisecond)
+			c <- 42
+		}()
+		select {
+		case <-c:
+		case <-time.After(10 * time.Millisecond):
+		}
+	})
+
+	/*
+		Two goroutines taking the same two locks in opposite order: each holds one and waits for the other, forever.
+		Without the watchdog we would only learn that the program hangs, with it we see where.
+	*/
+	var a, b sync.Mutex
+	var wg sync.WaitGroup
+	transfer := func(first, second *sync.Mutex) {
+		defer wg.Done()
+		first.Lock()
+		defer first.Unlock()
+		time.Sleep(10 * time.Millisecond) // make sure the other one got its first lock as well
+		second.Lock()
+		defer second.Unlock()
+	}
+	wg.Add(2)
+	go transfer(&a, &b)
+	go transfer(&b, &a)
+
+	stop := leakcheck.StartWatchdog("transfers", leakcheck.WatchdogConfig{After: 200 * time.Millisecond, Output: os.Stdout})
+	finished := make(chan struct{})
+	go func() {
+		wg.Wait()
+		close(finished)
+	}()
+	select {
+	case <-finished:
+		fmt.Println("transfers finished")
+	case <-time.After(300 * time.Millisecond):
+		fmt.Println("transfers are deadlocked, giving up on them")
+	}
+	stop()
+}
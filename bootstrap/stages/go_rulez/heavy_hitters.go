@@ -0,0 +1,81 @@
+package main
+
+/* Approximate counting
+An exact map of counts grows with every new key, with millions of distinct keys that hurts.
+	* counter.CountMin estimates any key's count in a fixed grid of counters, off by at most epsilon*N with probability 1-delta
+	* counter.SpaceSaving keeps only the heaviest keys and answers TopK(k)
+Below we feed both a skewed (Zipf) stream and check their error bounds against the exact counts.
+The tests of the counter package hold them to the same bounds: go test -run 'CountMin|SpaceSaving|TopK' ./counter
+*/
+
+import (
+	"fmt"
+	"math/rand"
+	"strconv"
+	"sync"
+
+	"github.com/ysyesilyurt/lets-go/bootstrap/stages/go_rulez/counter"
+)
+
+func main() {
+	const (
+		events   = 1000000
+		epsilon  = 0.0005
+		delta    = 0.01
+		capacity = 100
+		k        = 10
+	)
+	cms := counter.NewCountMin(epsilon, delta)
+	top := counter.NewSpaceSaving(capacity)
+	exact := make(map[string]int)
+
+	/* 4 producers feed the approximate counters concurrently, the exact counts are kept on the side */
+	zipf := rand.NewZipf(rand.New(rand.NewSource(1)), 1.1, 1, 1<<20)
+	stream := make([]string, events)
+	for i := range stream {
+		stream[i] = "key-" + strconv.FormatUint(zipf.Uint64(), 10)
+		exact[stream[i]]++
+	}
+	var wg sync.WaitGroup
+	for p := 0; p < 4; p++ {
+		wg.Add(1)
+		go func(part []string) {
+			defer wg.Done()
+			for _, key := range part {
+				cms.Inc(key)
+				top.Inc(key)
+			}
+		}(stream[p*events/4 : (p+1)*events/4])
+	}
+	wg.Wait()
+
+	/* Count-Min: never below the truth, and above truth+epsilon*N for at most a delta share of the keys */
+	under, over := 0, 0
+	for key, n := range exact {
+		est := cms.Value(key)
+		if est < n {
+			under++
+		}
+		if est > n+cms.ErrorBound() {
+			over++
+		}
+	}
+	fmt.Printf("count-min: %d distinct keys, error bound %d, %d underestimates, %.4f%% beyond the bound (allowed %.2f%%)\n",
+		len(exact), cms.ErrorBound(), under, 100*float64(over)/float64(len(exact)), 100*delta)
+
+	/* Space-Saving: Count-Error <= true count <= Count for every reported key, and the true top k are all in there */
+	fmt.Printf("space-saving top %d (error bound %d):\n", k, top.ErrorBound())
+	reported := make(map[string]bool)
+	for _, e := range top.TopK(k) {
+		ok := e.Count-e.Error <= exact[e.Key] && exact[e.Key] <= e.Count
+		fmt.Printf("  %-12s count %6d  error %4d  exact %6d  within bounds: %v\n", e.Key, e.Count, e.Error, exact[e.Key], ok)
+		reported[e.Key] = true
+	}
+	missed := 0
+	for key, n := range exact {
+		if n > top.Total()/capacity && !reported[key] && top.Value(key) == 0 {
+			missed++ // a key heavier than N/capacity must be monitored
+		}
+	}
+	fmt.Println("heavy keys that are not monitored:", missed)
+}
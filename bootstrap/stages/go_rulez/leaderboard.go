@@ -0,0 +1,31 @@
+package main
+
+/* Order statistics
+Every node of a tree.OrderedSet knows the size of its subtree, which makes it a handy sorted set for leaderboards:
+	* Select(i) -> the i-th smallest key
+	* Rank(key) -> how many keys are smaller than key
+	* CountRange(lo, hi) and Range(lo, hi) -> the keys in [lo, hi)
+all in O(log n) (+ k for Range) on the balanced variants.
+The randomized checks against a sorted slice are in tree/order_test.go: go test -run OrderStatistics ./tree
+*/
+
+import (
+	"fmt"
+	"math/rand"
+	"slices"
+
+	"github.com/ysyesilyurt/lets-go/bootstrap/stages/go_rulez/tree"
+)
+
+func main() {
+	/* A leaderboard of unique scores */
+	scores := tree.NewRedBlack[int]()
+	for _, s := range rand.Perm(1000) {
+		scores.Insert(s * 7)
+	}
+	best, _ := scores.Select(scores.Len() - 1)
+	third, _ := scores.Select(scores.Len() - 3)
+	fmt.Println("best score:", best, "third best:", third)
+	fmt.Println("a score of 3500 beats", scores.Rank(3500), "scores")
+	fmt.Println("scores in [1000, 1100):", scores.CountRange(1000, 1100), slices.Collect(scores.Range(1000, 1100)))
+}
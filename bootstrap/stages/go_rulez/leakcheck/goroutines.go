@@ -0,0 +1,138 @@
+package leakcheck
+
+import (
+	"bytes"
+	"fmt"
+	"runtime"
+	"strconv"
+	"strings"
+)
+
+/*
+	Reading goroutines.
+
+	runtime.Stack(buf, true) writes the stack of every goroutine, one block each, separated by a blank line:
+
+		goroutine 18 [chan send]:
+		main.Walk(...)
+			/home/gopher/walk.go:12 +0x4c
+		created by main.Same in goroutine 1
+			/home/gopher/walk.go:30 +0x8f
+
+	The header gives the ID and the state, then come pairs of lines: a function with its arguments and,
+	indented, the file and line it is at. The innermost call comes first. That format is meant for people,
+	not programs, but it has been the same for years and it is what go test -timeout and panics print as well.
+*/
+
+// Goroutine is a goroutine as found in a stack dump.
+type Goroutine struct {
+	ID    int
+	State string   // what it is doing, e.g. "running", "chan send" or "select"
+	Funcs []string // the functions on its stack, innermost first, with the one that created it last
+	Stack string   // its whole block of the dump
+}
+
+// String returns the stack of g without the frames of the runtime and internal packages, which only tell
+// how it blocks. Its State says that already.
+func (g Goroutine) String() string {
+	lines := strings.Split(strings.TrimRight(g.Stack, "\n"), "\n")
+	var b strings.Builder
+	b.WriteString(lines[0])
+	for i := 1; i < len(lines); i++ {
+		if !strings.HasPrefix(lines[i], "\t") && (strings.HasPrefix(lines[i], "runtime.") || strings.HasPrefix(lines[i], "internal/")) {
+			i++ // and its file:line
+			continue
+		}
+		b.WriteString("\n" + lines[i])
+	}
+	return b.String()
+}
+
+// has reports whether any function on the stack of g contains one of names.
+func (g Goroutine) has(names []string) bool {
+	for _, f := range g.Funcs {
+		for _, n := range names {
+			if strings.Contains(f, n) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// Running returns every goroutine that is running right now, including the calling one.
+func Running() []Goroutine {
+	var gs []Goroutine
+	for _, block := range bytes.Split(stacks(true), []byte("\n\n")) {
+		if g, ok := parse(string(block)); ok {
+			gs = append(gs, g)
+		}
+	}
+	return gs
+}
+
+// current returns the ID of the calling goroutine.
+func current() int {
+	g, _ := parse(string(stacks(false)))
+	return g.ID
+}
+
+// stacks returns what runtime.Stack writes, growing the buffer until all of it fits.
+func stacks(all bool) []byte {
+	buf := make([]byte, 64<<10)
+	for {
+		n := runtime.Stack(buf, all)
+		if n < len(buf) {
+			return buf[:n]
+		}
+		buf = make([]byte, 2*len(buf))
+	}
+}
+
+// parse reads a single block of a stack dump.
+func parse(block string) (Goroutine, bool) {
+	block = strings.TrimSpace(block)
+	header, rest, _ := strings.Cut(block, "\n")
+	// goroutine 18 [chan send, 2 minutes]:   (with GOTRACEBACK=system there is more between the ID and the state)
+	var g Goroutine
+	fields, ok := strings.CutPrefix(header, "goroutine ")
+	from, to := strings.Index(fields, "["), strings.LastIndex(fields, "]")
+	if !ok || from < 0 || to < from {
+		return g, false
+	}
+	id, _, _ := strings.Cut(fields, " ")
+	var err error
+	if g.ID, err = strconv.Atoi(id); err != nil {
+		return g, false
+	}
+	g.State, _, _ = strings.Cut(fields[from+1:to], ",")
+	g.Stack = block + "\n"
+
+	for _, line := range strings.Split(rest, "\n") {
+		switch {
+		case line == "" || strings.HasPrefix(line, "\t"):
+			// file:line
+		case strings.HasPrefix(line, "created by "):
+			creator, _, _ := strings.Cut(strings.TrimPrefix(line, "created by "), " in goroutine ")
+			g.Funcs = append(g.Funcs, creator)
+		default:
+			if i := strings.LastIndex(line, "("); i > 0 {
+				line = line[:i] // the arguments
+			}
+			g.Funcs = append(g.Funcs, line)
+		}
+	}
+	return g, true
+}
+
+// Format writes out gs one after the other, as String does.
+func Format(gs []Goroutine) string {
+	var b strings.Builder
+	for i, g := range gs {
+		if i > 0 {
+			b.WriteString("\n\n")
+		}
+		fmt.Fprint(&b, g)
+	}
+	return b.String()
+}
@@ -0,0 +1,88 @@
+// Package leakcheck finds goroutines that outlive the code that started them, and goroutines stuck for good.
+//
+// A goroutine blocked on a channel nobody will ever use again is never collected, it keeps its stack and
+// everything it references until the program exits. Check catches those in tests:
+//
+//	func TestSame(t *testing.T) {
+//		leakcheck.Check(t, leakcheck.Config{})
+//		...
+//	}
+//
+// fails the test if goroutines started during it are still there once it is over, with their stacks.
+// Outside of tests, Take and Leaked do the same.
+//
+// A deadlocked test never ends at all, go test -timeout kills it after 10 minutes by default.
+// Watchdog prints the stacks of all goroutines much earlier, while the test is still stuck.
+package leakcheck
+
+import (
+	"slices"
+	"testing"
+	"time"
+)
+
+// Config configures Check and Leaked. Zero fields take the defaults given below.
+type Config struct {
+	// Grace is how long new goroutines get to return after the code under test is done. Defaults to 1s.
+	// Goroutines often finish a moment after whatever they did for is over, such as the drainers of a channel.
+	Grace time.Duration
+	// Ignore lists functions that mark a goroutine as expected, by a part of their name such as
+	// "net/http.(*persistConn)" or "mypkg.worker". Goroutines with one of them on their stack are not leaks.
+	Ignore []string
+}
+
+// goroutines of the standard library that start on first use and stay on purpose.
+var ignoreAlways = []string{"os/signal.signal_recv", "os/signal.loop", "runtime.ensureSigM"}
+
+// Snapshot is the set of goroutines running at some point. Use Take to make one.
+type Snapshot struct {
+	ids map[int]bool
+}
+
+// Take takes a snapshot of the goroutines running now.
+func Take() Snapshot {
+	s := Snapshot{ids: make(map[int]bool)}
+	for _, g := range Running() {
+		s.ids[g.ID] = true
+	}
+	return s
+}
+
+// Leaked returns the goroutines started since s was taken that are still running, ignoring the calling one.
+// It waits up to cfg.Grace for them to return first, so it only takes that long if something leaked.
+func (s Snapshot) Leaked(cfg Config) []Goroutine {
+	if cfg.Grace <= 0 {
+		cfg.Grace = time.Second
+	}
+	ignore := slices.Concat(ignoreAlways, cfg.Ignore)
+	self := current()
+
+	deadline := time.Now().Add(cfg.Grace)
+	backoff := time.Millisecond
+	for {
+		var leaked []Goroutine
+		for _, g := range Running() {
+			if !s.ids[g.ID] && g.ID != self && !g.has(ignore) {
+				leaked = append(leaked, g)
+			}
+		}
+		if len(leaked) == 0 || time.Now().After(deadline) {
+			return leaked
+		}
+		time.Sleep(backoff)
+		backoff = min(2*backoff, 100*time.Millisecond)
+	}
+}
+
+// Check fails t if goroutines started during the test are still running when it is over, printing their stacks.
+// Call it first thing in the test, before anything starts goroutines. Tests running in parallel start
+// goroutines of their own, so do not use Check in tests that call t.Parallel, nor next to them.
+func Check(t testing.TB, cfg Config) {
+	t.Helper()
+	s := Take()
+	t.Cleanup(func() {
+		if leaked := s.Leaked(cfg); len(leaked) > 0 {
+			t.Errorf("leakcheck: goroutines still running after the test (%d):\n\n%s", len(leaked), Format(leaked))
+		}
+	})
+}
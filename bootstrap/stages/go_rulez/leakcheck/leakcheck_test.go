@@ -0,0 +1,114 @@
+package leakcheck
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+)
+
+// fakeT stands in for the *testing.T of a test under Check or Watchdog: it records what they report
+// instead of failing the real test, and runs the cleanups when told to.
+type fakeT struct {
+	testing.TB
+	cleanups []func()
+	errors   []string
+}
+
+func (f *fakeT) Helper()           {}
+func (f *fakeT) Name() string      { return "TestFake" }
+func (f *fakeT) Cleanup(fn func()) { f.cleanups = append(f.cleanups, fn) }
+func (f *fakeT) Errorf(format string, args ...any) {
+	f.errors = append(f.errors, fmt.Sprintf(format, args...))
+}
+
+// end runs the cleanups the way the testing package does once a test is over, last registered first.
+func (f *fakeT) end() {
+	for i := len(f.cleanups) - 1; i >= 0; i-- {
+		f.cleanups[i]()
+	}
+}
+
+func TestCheck(t *testing.T) {
+	/* a test leaving a goroutine blocked on a send fails, with the stack of that goroutine */
+	leaky := &fakeT{TB: t}
+	Check(leaky, Config{Grace: 50 * time.Millisecond})
+	block := make(chan int)
+	go func() { block <- 1 }()
+	leaky.end()
+	if len(leaky.errors) != 1 || !strings.Contains(leaky.errors[0], "goroutines still running after the test (1)") ||
+		!strings.Contains(leaky.errors[0], "[chan send]") || !strings.Contains(leaky.errors[0], "TestCheck.func1") {
+		t.Fatalf("a leaking test got the errors %q, want one naming the leaked goroutine", leaky.errors)
+	}
+	<-block
+
+	/* a test whose goroutines are gone by the time it ends passes, also if they needed a moment to return */
+	clean := &fakeT{TB: t}
+	Check(clean, Config{})
+	done := make(chan bool)
+	go func() { done <- true }()
+	<-done
+	go func() { time.Sleep(30 * time.Millisecond) }()
+	clean.end()
+	if len(clean.errors) != 0 {
+		t.Fatalf("a clean test got the errors %q", clean.errors)
+	}
+}
+
+func TestLeaked(t *testing.T) {
+	s := Take()
+	block := make(chan int)
+	go func() { block <- 1 }()
+	leaked := s.Leaked(Config{Grace: 50 * time.Millisecond})
+	if len(leaked) != 1 || leaked[0].State != "chan send" || !strings.Contains(Format(leaked), "TestLeaked.func1") {
+		t.Fatalf("Leaked = %v, want the goroutine blocked on block", leaked)
+	}
+	if got := s.Leaked(Config{Grace: 50 * time.Millisecond, Ignore: []string{"TestLeaked"}}); len(got) != 0 {
+		t.Fatalf("Leaked ignoring TestLeaked = %v", got)
+	}
+	<-block
+
+	/* it only waits out the grace period if something leaked */
+	start := time.Now()
+	if got := s.Leaked(Config{Grace: time.Minute}); len(got) != 0 || time.Since(start) > time.Second {
+		t.Fatalf("Leaked = %v after %v, want nothing right away", got, time.Since(start))
+	}
+}
+
+func TestWatchdog(t *testing.T) {
+	var buf bytes.Buffer
+	stuck := &fakeT{TB: t}
+	Watchdog(stuck, WatchdogConfig{After: 20 * time.Millisecond, Output: &buf})
+	time.Sleep(60 * time.Millisecond)
+	stuck.end()
+	if len(stuck.errors) != 1 || !strings.Contains(buf.String(), "TestFake is still running") || !strings.Contains(buf.String(), "TestWatchdog") {
+		t.Fatalf("a stuck test got the errors %q and the dump %q", stuck.errors, buf.String())
+	}
+
+	buf.Reset()
+	quick := &fakeT{TB: t}
+	Watchdog(quick, WatchdogConfig{After: time.Second, Output: &buf})
+	quick.end()
+	if len(quick.errors) != 0 || buf.Len() != 0 {
+		t.Fatalf("a quick test got the errors %q and the dump %q", quick.errors, buf.String())
+	}
+}
+
+func TestParse(t *testing.T) {
+	// as printed with GOTRACEBACK=system, with more in the header
+	g, ok := parse("goroutine 7 gp=0xc000 m=nil [chan send, 3 minutes]:\nruntime.gopark(0x1)\n\t/x/proc.go:1 +0x1\n" +
+		"main.f(...)\n\t/x/m.go:3\ncreated by main.main in goroutine 1\n\t/x/m.go:9 +0x2\n")
+	if !ok || g.ID != 7 || g.State != "chan send" || strings.Join(g.Funcs, ",") != "runtime.gopark,main.f,main.main" {
+		t.Fatalf("parse = %+v", g)
+	}
+	if s := g.String(); strings.Contains(s, "gopark") || !strings.Contains(s, "main.f") {
+		t.Fatalf("String kept the runtime frames or dropped ours:\n%s", s)
+	}
+	if _, ok := parse("not a goroutine"); ok {
+		t.Fatal("parse accepted garbage")
+	}
+	if current() == 0 {
+		t.Fatal("current found no goroutine ID")
+	}
+}
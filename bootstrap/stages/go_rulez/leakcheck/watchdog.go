@@ -0,0 +1,58 @@
+package leakcheck
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"slices"
+	"sync"
+	"testing"
+	"time"
+)
+
+// WatchdogConfig configures a watchdog. Zero fields take the defaults given below.
+type WatchdogConfig struct {
+	After  time.Duration // how long to wait before dumping the stacks, defaults to 10s
+	Output io.Writer     // where to dump them, defaults to os.Stderr
+}
+
+// StartWatchdog dumps the stacks of all goroutines to cfg.Output, under a line naming what is stuck,
+// unless stop is called within cfg.After. It dumps at most once, stop reports whether it did.
+func StartWatchdog(name string, cfg WatchdogConfig) (stop func() (fired bool)) {
+	if cfg.After <= 0 {
+		cfg.After = 10 * time.Second
+	}
+	if cfg.Output == nil {
+		cfg.Output = os.Stderr
+	}
+	var mu sync.Mutex // so that stop waits for a dump in progress
+	fired := false
+	timer := time.AfterFunc(cfg.After, func() {
+		mu.Lock()
+		defer mu.Unlock()
+		self := current()
+		gs := slices.DeleteFunc(Running(), func(g Goroutine) bool { return g.ID == self }) // the dump is not what is stuck
+		fmt.Fprintf(cfg.Output, "leakcheck: %s is still running after %v, here are its %d goroutines:\n\n%s\n\n",
+			name, cfg.After, len(gs), Format(gs))
+		fired = true
+	})
+	return func() bool {
+		timer.Stop()
+		mu.Lock()
+		defer mu.Unlock()
+		return fired
+	}
+}
+
+// Watchdog dumps the stacks of all goroutines and fails t if the test is still running after cfg.After.
+// The stacks go straight to cfg.Output rather than through t.Log, which a test that never returns would never print.
+// The watchdog does not stop the test, it may still get unblocked later, or killed by go test -timeout.
+func Watchdog(t testing.TB, cfg WatchdogConfig) {
+	t.Helper()
+	stop := StartWatchdog(t.Name(), cfg)
+	t.Cleanup(func() {
+		if stop() {
+			t.Errorf("leakcheck: the test blocked for too long, see the goroutine dump above")
+		}
+	})
+}
@@ -0,0 +1,55 @@
+package main
+
+/* Load generator
+Hammers a URL with requests and reports throughput, errors by reason and latency percentiles (see loadgen/):
+	go run load_generator.go -url http://localhost:8080/pairs/1/2 -c 50 -d 10s
+	go run load_generator.go -url http://localhost:8080 -n 20 -rate 5 -json
+Stop it early with Ctrl-C and it still reports what it sent so far.
+*/
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"os/signal"
+	"syscall"
+
+	"github.com/ysyesilyurt/lets-go/bootstrap/stages/go_rulez/loadgen"
+)
+
+func main() {
+	var cfg loadgen.Config
+	flag.StringVar(&cfg.URL, "url", "http://localhost:8080", "target URL")
+	flag.StringVar(&cfg.Method, "method", "GET", "HTTP method")
+	flag.IntVar(&cfg.Concurrency, "c", 10, "concurrent workers")
+	flag.IntVar(&cfg.Requests, "n", 0, "total requests, 0 for no limit")
+	flag.DurationVar(&cfg.Duration, "d", 0, "how long to run, 0 for no limit")
+	flag.Float64Var(&cfg.Rate, "rate", 0, "requests per second, 0 for as fast as possible")
+	flag.DurationVar(&cfg.Timeout, "timeout", 0, "per request timeout (default 10s)")
+	asJSON := flag.Bool("json", false, "report in JSON")
+	flag.Parse()
+	if cfg.Requests == 0 && cfg.Duration == 0 {
+		cfg.Requests = 100
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	report, err := loadgen.Run(ctx, cfg)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(2)
+	}
+	if *asJSON {
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		err = enc.Encode(report)
+	} else {
+		err = report.WriteText(os.Stdout)
+	}
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+}
@@ -0,0 +1,119 @@
+package loadgen
+
+import (
+	"math"
+	"math/bits"
+	"time"
+)
+
+// subBits sets the precision of a Histogram: every power of two is split into 2^subBits/2 linear buckets,
+// so a recorded value is off by less than 2/2^subBits (1.6%) of itself.
+const subBits = 7
+
+const (
+	subCount = 1 << subBits
+	subHalf  = subCount / 2
+)
+
+// Histogram records durations HdrHistogram style, in buckets whose width grows with the value: values below
+// 128ns are exact, and every range [2^e, 2^(e+1)) above is split into 64 buckets. That keeps the relative error
+// bounded for anything from nanoseconds to hours in a few KB, and makes quantiles cheap to read.
+//
+// The zero value is an empty histogram ready to use. A Histogram is not safe for concurrent use,
+// record into one per goroutine and Merge them.
+type Histogram struct {
+	counts   []uint64
+	count    uint64
+	sum      float64
+	min, max int64
+}
+
+// Record adds d to h, negative durations count as 0.
+func (h *Histogram) Record(d time.Duration) {
+	v := max(int64(d), 0)
+	i := bucket(v)
+	if i >= len(h.counts) {
+		h.counts = append(h.counts, make([]uint64, i+1-len(h.counts))...)
+	}
+	h.counts[i]++
+	if h.count == 0 || v < h.min {
+		h.min = v
+	}
+	h.max = max(h.max, v)
+	h.count++
+	h.sum += float64(v)
+}
+
+// Merge adds everything recorded in o to h.
+func (h *Histogram) Merge(o *Histogram) {
+	if o.count == 0 {
+		return
+	}
+	if len(o.counts) > len(h.counts) {
+		h.counts = append(h.counts, make([]uint64, len(o.counts)-len(h.counts))...)
+	}
+	for i, c := range o.counts {
+		h.counts[i] += c
+	}
+	if h.count == 0 || o.min < h.min {
+		h.min = o.min
+	}
+	h.max = max(h.max, o.max)
+	h.count += o.count
+	h.sum += o.sum
+}
+
+// Count returns the number of recorded durations.
+func (h *Histogram) Count() int { return int(h.count) }
+
+// Min returns the smallest recorded duration, exactly.
+func (h *Histogram) Min() time.Duration { return time.Duration(h.min) }
+
+// Max returns the largest recorded duration, exactly.
+func (h *Histogram) Max() time.Duration { return time.Duration(h.max) }
+
+// Mean returns the average of the recorded durations, exactly.
+func (h *Histogram) Mean() time.Duration {
+	if h.count == 0 {
+		return 0
+	}
+	return time.Duration(h.sum / float64(h.count))
+}
+
+// Quantile returns the duration below which the fraction q of the recorded durations fall, Quantile(0.99) is the p99.
+// It is the upper end of the bucket holding that duration, so it never underestimates by more than the bucket width.
+func (h *Histogram) Quantile(q float64) time.Duration {
+	if h.count == 0 {
+		return 0
+	}
+	rank := uint64(math.Ceil(min(max(q, 0), 1) * float64(h.count)))
+	if rank == 0 {
+		return time.Duration(h.min)
+	}
+	var seen uint64
+	for i, c := range h.counts {
+		if seen += c; seen >= rank {
+			return time.Duration(min(max(upper(i), h.min), h.max))
+		}
+	}
+	return time.Duration(h.max)
+}
+
+// bucket returns the index of the bucket holding v >= 0.
+func bucket(v int64) int {
+	if v < subCount {
+		return int(v)
+	}
+	e := bits.Len64(uint64(v)) - subBits // v >> e lands in [subHalf, subCount)
+	return subCount + (e-1)*subHalf + int(v>>e) - subHalf
+}
+
+// upper returns the largest value that falls in bucket i.
+func upper(i int) int64 {
+	if i < subCount {
+		return int64(i)
+	}
+	e := (i-subCount)/subHalf + 1
+	m := int64((i-subCount)%subHalf + subHalf)
+	return (m+1)<<e - 1
+}
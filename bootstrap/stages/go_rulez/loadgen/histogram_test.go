@@ -0,0 +1,88 @@
+package loadgen
+
+import (
+	"math"
+	"math/rand"
+	"slices"
+	"testing"
+	"time"
+)
+
+func TestBuckets(t *testing.T) {
+	/* below subCount every value has a bucket of its own */
+	for v := range int64(subCount) {
+		if bucket(v) != int(v) || upper(int(v)) != v {
+			t.Fatalf("value %d lands in bucket %d with upper bound %d, want exact", v, bucket(v), upper(bucket(v)))
+		}
+	}
+
+	/* above, the bucket of v starts after the previous bucket's upper bound, ends at or after v, and is at most v/64 wide */
+	values := []int64{subCount, subCount + 1, 1<<20 - 1, 1 << 20, 1<<20 + 1, math.MaxInt64 - 1, math.MaxInt64}
+	for range 10000 {
+		values = append(values, rand.Int63n(1<<uint(rand.Intn(63))+1))
+	}
+	for _, v := range values {
+		i := bucket(v)
+		if up := upper(i); up < v || up-v > v/subHalf || upper(i-1) >= v {
+			t.Fatalf("value %d lands in bucket %d of (%d, %d], want v within a bucket at most v/%d wide",
+				v, i, upper(i-1), up, subHalf)
+		}
+	}
+	if upper(bucket(math.MaxInt64)) != math.MaxInt64 {
+		t.Fatalf("the last bucket ends at %d, want MaxInt64", upper(bucket(math.MaxInt64)))
+	}
+}
+
+// reference returns the q quantile of the sorted values the way Quantile defines it: the value of rank ceil(q*n).
+func reference(sorted []int64, q float64) int64 {
+	rank := int(math.Ceil(q * float64(len(sorted))))
+	return sorted[max(rank-1, 0)]
+}
+
+func TestQuantile(t *testing.T) {
+	var h, even, odd Histogram
+	var values []int64
+	var sum float64
+	for i := range 100000 {
+		v := int64(rand.ExpFloat64() * float64(time.Millisecond))
+		values = append(values, v)
+		sum += float64(v)
+		h.Record(time.Duration(v))
+		if i%2 == 0 {
+			even.Record(time.Duration(v))
+		} else {
+			odd.Record(time.Duration(v))
+		}
+	}
+	even.Merge(&odd)
+	slices.Sort(values)
+
+	for _, q := range []float64{0, 0.001, 0.5, 0.9, 0.99, 0.999, 1} {
+		want, got := reference(values, q), int64(h.Quantile(q))
+		if got < want || got-want > want/subHalf {
+			t.Errorf("Quantile(%v) = %d, want %d up to its bucket width of %d", q, got, want, want/subHalf)
+		}
+		if even.Quantile(q) != h.Quantile(q) {
+			t.Errorf("Quantile(%v) of the merged halves = %v, want %v", q, even.Quantile(q), h.Quantile(q))
+		}
+	}
+	if h.Count() != len(values) || int64(h.Min()) != values[0] || int64(h.Max()) != values[len(values)-1] ||
+		h.Mean() != time.Duration(sum/float64(len(values))) {
+		t.Fatalf("Count %d, Min %v, Max %v, Mean %v are not exact", h.Count(), h.Min(), h.Max(), h.Mean())
+	}
+	if even.Count() != h.Count() || even.Min() != h.Min() || even.Max() != h.Max() {
+		t.Fatalf("the merged halves have Count %d, Min %v, Max %v", even.Count(), even.Min(), even.Max())
+	}
+}
+
+func TestEmptyAndNegative(t *testing.T) {
+	var h Histogram
+	if h.Quantile(0.5) != 0 || h.Mean() != 0 || h.Count() != 0 {
+		t.Fatal("an empty histogram reports durations")
+	}
+	h.Merge(&Histogram{})
+	h.Record(-time.Second)
+	if h.Count() != 1 || h.Min() != 0 || h.Quantile(1) != 0 {
+		t.Fatalf("a negative duration was recorded as %v, want 0", h.Min())
+	}
+}
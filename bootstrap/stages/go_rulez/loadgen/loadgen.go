@@ -0,0 +1,157 @@
+// Package loadgen sends HTTP requests at a target and measures how it copes: throughput, which requests failed
+// and why, and latency percentiles from a Histogram.
+//
+//	report, err := loadgen.Run(ctx, loadgen.Config{URL: "http://localhost:8080", Concurrency: 10, Requests: 1000})
+//	report.WriteText(os.Stdout)
+//
+// With a Rate, requests are sent on a fixed schedule and their latency is measured from the time they were due,
+// not the time a worker got around to sending them. A slow server then shows up in the percentiles instead of
+// quietly lowering the request rate (the "coordinated omission" problem).
+package loadgen
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// Config describes a load test, zero fields take the defaults below. One of Requests and Duration is required,
+// the run ends with whichever is reached first.
+type Config struct {
+	URL         string
+	Method      string        // defaults to GET
+	Concurrency int           // workers sending requests in parallel; defaults to 1
+	Requests    int           // total requests to send, 0 for no limit
+	Duration    time.Duration // how long to send requests for, 0 for no limit
+	Rate        float64       // requests per second over all workers, 0 for as fast as the workers go
+	Timeout     time.Duration // per request; defaults to 10s
+	Client      *http.Client  // defaults to a client with Timeout and a connection per worker
+}
+
+// Run sends the requests described by cfg and reports how they went. It stops early when ctx is canceled,
+// and still reports the requests sent until then. The error is only for an invalid cfg.
+func Run(ctx context.Context, cfg Config) (*Report, error) {
+	if cfg.URL == "" {
+		return nil, errors.New("loadgen: no URL")
+	}
+	if cfg.Requests <= 0 && cfg.Duration <= 0 {
+		return nil, errors.New("loadgen: one of Requests and Duration is required")
+	}
+	if cfg.Method == "" {
+		cfg.Method = http.MethodGet
+	}
+	if cfg.Concurrency <= 0 {
+		cfg.Concurrency = 1
+	}
+	if cfg.Timeout <= 0 {
+		cfg.Timeout = 10 * time.Second
+	}
+	client := cfg.Client
+	if client == nil {
+		transport := http.DefaultTransport.(*http.Transport).Clone()
+		transport.MaxIdleConnsPerHost = cfg.Concurrency
+		defer transport.CloseIdleConnections()
+		client = &http.Client{Timeout: cfg.Timeout, Transport: transport}
+	}
+	if _, err := http.NewRequest(cfg.Method, cfg.URL, nil); err != nil {
+		return nil, fmt.Errorf("loadgen: %w", err)
+	}
+
+	if cfg.Duration > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, cfg.Duration)
+		defer cancel()
+	}
+	start := time.Now()
+	s := &schedule{limit: cfg.Requests, next: start}
+	if cfg.Rate > 0 {
+		s.interval = time.Duration(float64(time.Second) / cfg.Rate)
+	}
+
+	results := make([]*result, cfg.Concurrency)
+	var wg sync.WaitGroup
+	for i := range results {
+		results[i] = newResult()
+		wg.Add(1)
+		go func(res *result) {
+			defer wg.Done()
+			for {
+				due, ok := s.take(ctx)
+				if !ok {
+					return
+				}
+				res.record(ctx, send(ctx, client, cfg.Method, cfg.URL, due))
+			}
+		}(results[i])
+	}
+	wg.Wait()
+
+	total := newResult()
+	for _, res := range results {
+		total.merge(res)
+	}
+	return total.report(cfg, time.Since(start)), nil
+}
+
+// schedule hands out requests to the workers until the limit is reached, paced at one per interval if set.
+type schedule struct {
+	mu       sync.Mutex
+	limit    int // 0 for no limit
+	taken    int
+	interval time.Duration
+	next     time.Time
+}
+
+// take waits for the next request to be due and returns the time it was due,
+// false once there are no more requests to send or ctx is done.
+func (s *schedule) take(ctx context.Context) (time.Time, bool) {
+	s.mu.Lock()
+	if s.limit > 0 && s.taken == s.limit {
+		s.mu.Unlock()
+		return time.Time{}, false
+	}
+	s.taken++
+	due := s.next
+	s.next = s.next.Add(s.interval)
+	s.mu.Unlock()
+
+	if s.interval == 0 {
+		return time.Now(), ctx.Err() == nil
+	}
+	t := time.NewTimer(time.Until(due))
+	defer t.Stop()
+	select {
+	case <-t.C:
+		return due, true
+	case <-ctx.Done():
+		return time.Time{}, false
+	}
+}
+
+// outcome is what became of a single request.
+type outcome struct {
+	status  int // 0 if there was no response
+	err     error
+	latency time.Duration
+}
+
+func send(ctx context.Context, client *http.Client, method, url string, due time.Time) outcome {
+	req, err := http.NewRequestWithContext(ctx, method, url, nil)
+	if err != nil {
+		return outcome{err: err}
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return outcome{err: err}
+	}
+	defer resp.Body.Close()
+	// the latency includes reading the whole body, a response is not served until the client has it
+	if _, err := io.Copy(io.Discard, resp.Body); err != nil {
+		return outcome{status: resp.StatusCode, err: err}
+	}
+	return outcome{status: resp.StatusCode, latency: time.Since(due)}
+}
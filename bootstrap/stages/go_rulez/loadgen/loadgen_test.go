@@ -0,0 +1,116 @@
+package loadgen
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"strings"
+	"sync/atomic"
+	"syscall"
+	"testing"
+	"time"
+)
+
+func TestClassify(t *testing.T) {
+	tests := []struct {
+		status int
+		err    error
+		want   string
+	}{
+		{503, nil, "HTTP 503"},
+		{404, nil, "HTTP 404"},
+		{0, context.DeadlineExceeded, "timeout"},
+		{0, &url.Error{Op: "Get", URL: "http://x", Err: os.ErrDeadlineExceeded}, "timeout"},
+		{0, &url.Error{Op: "Get", URL: "http://x", Err: &net.OpError{Op: "dial", Err: os.NewSyscallError("connect", syscall.ECONNREFUSED)}}, "connection refused"},
+		{0, &net.OpError{Op: "read", Err: syscall.ECONNRESET}, "connection reset"},
+		{0, fmt.Errorf("write: %w", syscall.EPIPE), "connection reset"},
+		{200, io.ErrUnexpectedEOF, "EOF"},
+		{0, errors.New("tls: bad certificate"), "other"},
+	}
+	for _, tt := range tests {
+		if got := Classify(tt.status, tt.err); got != tt.want {
+			t.Errorf("Classify(%d, %v) = %q, want %q", tt.status, tt.err, got, tt.want)
+		}
+	}
+}
+
+func TestRun(t *testing.T) {
+	var n atomic.Int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if n.Add(1)%4 == 0 {
+			http.Error(w, "try again later", http.StatusServiceUnavailable)
+			return
+		}
+		io.WriteString(w, "ok")
+	}))
+	defer srv.Close()
+
+	/* 20 requests at 200 per second: every 4th fails, and the schedule takes about 100ms */
+	report, err := Run(context.Background(), Config{URL: srv.URL, Concurrency: 4, Requests: 20, Rate: 200})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n.Load() != 20 || report.Requests != 20 || report.Succeeded != 15 {
+		t.Fatalf("server got %d, report says %d requests, %d succeeded; want 20, 20, 15", n.Load(), report.Requests, report.Succeeded)
+	}
+	if report.Statuses[200] != 15 || report.Statuses[503] != 5 || len(report.Errors) != 1 || report.Errors["HTTP 503"] != 5 {
+		t.Fatalf("statuses %v, errors %v", report.Statuses, report.Errors)
+	}
+	if report.Elapsed < 90*time.Millisecond || report.Latency.Count() != 20 {
+		t.Fatalf("took %v for 20 requests at 200/s with %d latencies, want about 100ms and 20", report.Elapsed, report.Latency.Count())
+	}
+
+	var text strings.Builder
+	report.WriteText(&text)
+	for _, want := range []string{"GET " + srv.URL, "requests    20 in", "15 succeeded", "statuses    200 x15  503 x5", "errors      HTTP 503  5", "latency     min "} {
+		if !strings.Contains(text.String(), want) {
+			t.Fatalf("report\n%s\nlacks %q", text.String(), want)
+		}
+	}
+	data, _ := json.Marshal(report)
+	var decoded map[string]any
+	if err := json.Unmarshal(data, &decoded); err != nil || decoded["requests"] != 20.0 || decoded["latency_ms"] == nil || decoded["elapsed_ms"] == nil {
+		t.Fatalf("JSON report %s: %v", data, err)
+	}
+
+	/* by duration, until the time is up */
+	n.Store(0)
+	report, _ = Run(context.Background(), Config{URL: srv.URL, Concurrency: 2, Duration: 50 * time.Millisecond, Rate: 200})
+	if report.Requests == 0 || report.Requests != int(n.Load()) || report.Elapsed > time.Second {
+		t.Fatalf("%d requests in %v, server got %d", report.Requests, report.Elapsed, n.Load())
+	}
+}
+
+func TestRunAgainstNobody(t *testing.T) {
+	srv := httptest.NewServer(http.NotFoundHandler())
+	srv.Close() // nobody listens on its address now
+	report, err := Run(context.Background(), Config{URL: srv.URL, Requests: 3})
+	if err != nil || report.Requests != 3 || report.Succeeded != 0 || report.Errors["connection refused"] != 3 {
+		t.Fatalf("report %+v, %v; want 3 refused connections", report, err)
+	}
+}
+
+func TestRunRejectsBadConfig(t *testing.T) {
+	for _, cfg := range []Config{
+		{Requests: 1},
+		{URL: "http://localhost"},
+		{URL: "http://localhost", Method: "BAD METHOD", Requests: 1},
+	} {
+		if _, err := Run(context.Background(), cfg); err == nil {
+			t.Errorf("Run(%+v) did not fail", cfg)
+		}
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if report, err := Run(ctx, Config{URL: "http://localhost", Requests: 5}); err != nil || report.Requests != 0 {
+		t.Fatalf("a canceled run sent %d requests, %v", report.Requests, err)
+	}
+}
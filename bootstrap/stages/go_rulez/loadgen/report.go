@@ -0,0 +1,177 @@
+package loadgen
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"maps"
+	"net"
+	"net/http"
+	"os"
+	"slices"
+	"strings"
+	"syscall"
+	"text/tabwriter"
+	"time"
+)
+
+// Report sums up a load test. It marshals to JSON with the durations in milliseconds.
+type Report struct {
+	Method     string         `json:"method"`
+	URL        string         `json:"url"`
+	Requests   int            `json:"requests"`  // sent and finished, before the run ended
+	Succeeded  int            `json:"succeeded"` // answered with a status below 400
+	Elapsed    time.Duration  `json:"-"`
+	Throughput float64        `json:"throughput_rps"` // finished requests per second
+	Statuses   map[int]int    `json:"statuses"`       // responses by status code
+	Errors     map[string]int `json:"errors"`         // failed requests by reason, see Classify
+	Latency    *Histogram     `json:"-"`              // of the requests that got a complete response
+}
+
+// latencySummary is how the latency histogram shows up in JSON.
+type latencySummary struct {
+	Min  float64 `json:"min"`
+	Mean float64 `json:"mean"`
+	P50  float64 `json:"p50"`
+	P90  float64 `json:"p90"`
+	P99  float64 `json:"p99"`
+	Max  float64 `json:"max"`
+}
+
+func (r *Report) MarshalJSON() ([]byte, error) {
+	type plain Report // without the methods, so json.Marshal does not call back into this one
+	ms := func(d time.Duration) float64 { return float64(d) / float64(time.Millisecond) }
+	h := r.Latency
+	return json.Marshal(struct {
+		*plain
+		ElapsedMS float64        `json:"elapsed_ms"`
+		LatencyMS latencySummary `json:"latency_ms"`
+	}{
+		(*plain)(r),
+		ms(r.Elapsed),
+		latencySummary{ms(h.Min()), ms(h.Mean()), ms(h.Quantile(.5)), ms(h.Quantile(.9)), ms(h.Quantile(.99)), ms(h.Max())},
+	})
+}
+
+// WriteText writes r for humans:
+//
+//	GET http://localhost:8080
+//	requests    100 in 2.012s, 48 succeeded
+//	throughput  49.7 req/s
+//	latency     min 1.1ms  mean 3.4ms  p50 2.9ms  p90 5.1ms  p99 9.8ms  max 12ms
+//	statuses    200 x48  503 x2
+//	errors      connection refused  50
+//	            HTTP 503            2
+func (r *Report) WriteText(w io.Writer) error {
+	tw := tabwriter.NewWriter(w, 0, 4, 2, ' ', 0)
+	fmt.Fprintf(tw, "%s %s\n", r.Method, r.URL)
+	fmt.Fprintf(tw, "requests\t%d in %v, %d succeeded\n", r.Requests, r.Elapsed.Round(time.Millisecond), r.Succeeded)
+	fmt.Fprintf(tw, "throughput\t%.1f req/s\n", r.Throughput)
+	if h := r.Latency; h.Count() > 0 {
+		fmt.Fprintf(tw, "latency\tmin %v  mean %v  p50 %v  p90 %v  p99 %v  max %v\n",
+			round(h.Min()), round(h.Mean()), round(h.Quantile(.5)), round(h.Quantile(.9)), round(h.Quantile(.99)), round(h.Max()))
+	}
+	if len(r.Statuses) > 0 {
+		var statuses []string
+		for _, code := range slices.Sorted(maps.Keys(r.Statuses)) {
+			statuses = append(statuses, fmt.Sprintf("%d x%d", code, r.Statuses[code]))
+		}
+		fmt.Fprintf(tw, "statuses\t%s\n", strings.Join(statuses, "  "))
+	}
+	label := "errors"
+	for _, reason := range slices.Sorted(maps.Keys(r.Errors)) {
+		fmt.Fprintf(tw, "%s\t%s\t%d\n", label, reason, r.Errors[reason])
+		label = ""
+	}
+	return tw.Flush()
+}
+
+// round keeps the 3 most significant digits of d, a p99 of 3.141592ms reads better as 3.14ms.
+func round(d time.Duration) time.Duration {
+	for unit := time.Duration(1000); unit <= time.Hour; unit *= 10 {
+		if d < unit {
+			return d.Round(unit / 1000)
+		}
+	}
+	return d.Round(time.Second)
+}
+
+// Classify names the reason a request failed, so failures can be counted by reason:
+// "HTTP 503" for error statuses, "timeout", "connection refused", "connection reset" or "EOF"
+// for the usual transport errors, and "other" for the rest.
+func Classify(status int, err error) string {
+	var netErr net.Error
+	switch {
+	case err == nil:
+		return fmt.Sprintf("HTTP %d", status)
+	case errors.Is(err, context.DeadlineExceeded), errors.Is(err, os.ErrDeadlineExceeded),
+		errors.As(err, &netErr) && netErr.Timeout():
+		return "timeout"
+	case errors.Is(err, syscall.ECONNREFUSED):
+		return "connection refused"
+	case errors.Is(err, syscall.ECONNRESET), errors.Is(err, syscall.EPIPE):
+		return "connection reset"
+	case errors.Is(err, io.EOF), errors.Is(err, io.ErrUnexpectedEOF):
+		return "EOF"
+	}
+	return "other"
+}
+
+// result gathers the outcomes of one worker, so workers do not contend over a shared one.
+type result struct {
+	requests, succeeded int
+	statuses            map[int]int
+	errors              map[string]int
+	latency             Histogram
+}
+
+func newResult() *result {
+	return &result{statuses: make(map[int]int), errors: make(map[string]int)}
+}
+
+func (r *result) record(ctx context.Context, o outcome) {
+	if o.err != nil && ctx.Err() != nil {
+		return // cut short by the end of the run, not a failure of the target
+	}
+	r.requests++
+	if o.status != 0 {
+		r.statuses[o.status]++
+	}
+	switch {
+	case o.err != nil || o.status >= http.StatusBadRequest:
+		r.errors[Classify(o.status, o.err)]++
+	default:
+		r.succeeded++
+	}
+	if o.err == nil {
+		r.latency.Record(o.latency)
+	}
+}
+
+func (r *result) merge(o *result) {
+	r.requests += o.requests
+	r.succeeded += o.succeeded
+	for code, n := range o.statuses {
+		r.statuses[code] += n
+	}
+	for reason, n := range o.errors {
+		r.errors[reason] += n
+	}
+	r.latency.Merge(&o.latency)
+}
+
+func (r *result) report(cfg Config, elapsed time.Duration) *Report {
+	return &Report{
+		Method:     cfg.Method,
+		URL:        cfg.URL,
+		Requests:   r.requests,
+		Succeeded:  r.succeeded,
+		Elapsed:    elapsed,
+		Throughput: float64(r.requests) / elapsed.Seconds(),
+		Statuses:   r.statuses,
+		Errors:     r.errors,
+		Latency:    &r.latency,
+	}
+}
@@ -0,0 +1,181 @@
+// Package metrics exposes our counters over HTTP in the Prometheus text exposition format
+// (https://prometheus.io/docs/instrumenting/exposition_formats/), so they can be scraped:
+//
+//	# HELP gorulez_requests_total Requests served, by route.
+//	# TYPE gorulez_requests_total counter
+//	gorulez_requests_total{route="pair"} 5
+//
+// A Registry is an http.Handler, mount it at /metrics.
+package metrics
+
+import (
+	"bufio"
+	"fmt"
+	"math"
+	"net/http"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// Type is the metric type announced on the TYPE line.
+type Type string
+
+const (
+	CounterType Type = "counter"
+	GaugeType   Type = "gauge"
+	UntypedType Type = "untyped"
+)
+
+// Family is a metric with all its samples, as written out under one HELP and TYPE line.
+type Family struct {
+	Name    string
+	Help    string
+	Type    Type
+	Samples []Sample
+}
+
+// Sample is a single line of a family.
+type Sample struct {
+	Labels Labels
+	Value  float64
+}
+
+// Labels are the name="value" pairs of a sample, they are written out sorted by name.
+type Labels map[string]string
+
+// Collector produces metric families on every scrape.
+type Collector interface {
+	Collect() []Family
+}
+
+// Registry holds the collectors to scrape. The zero value is ready to use.
+type Registry struct {
+	mu         sync.Mutex
+	collectors []Collector
+	names      map[string]bool
+}
+
+// NewRegistry returns an empty registry.
+func NewRegistry() *Registry { return &Registry{} }
+
+var (
+	metricName = regexp.MustCompile(`^[a-zA-Z_:][a-zA-Z0-9_:]*$`)
+	labelName  = regexp.MustCompile(`^[a-zA-Z_][a-zA-Z0-9_]*$`)
+)
+
+// Register adds c to the registry. It panics if a family of c has an invalid or already registered name,
+// like http.Handle does for duplicate patterns, since that is a programming error.
+func (r *Registry) Register(c Collector) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.names == nil {
+		r.names = make(map[string]bool)
+	}
+	for _, f := range c.Collect() {
+		if !metricName.MatchString(f.Name) {
+			panic(fmt.Sprintf("metrics: invalid metric name %q", f.Name))
+		}
+		if r.names[f.Name] {
+			panic(fmt.Sprintf("metrics: metric %q registered twice", f.Name))
+		}
+		r.names[f.Name] = true
+	}
+	r.collectors = append(r.collectors, c)
+}
+
+// Gather collects all families, sorted by name.
+func (r *Registry) Gather() []Family {
+	r.mu.Lock()
+	collectors := slices.Clone(r.collectors)
+	r.mu.Unlock()
+
+	var families []Family
+	for _, c := range collectors {
+		families = append(families, c.Collect()...)
+	}
+	slices.SortFunc(families, func(a, b Family) int { return strings.Compare(a.Name, b.Name) })
+	return families
+}
+
+// ServeHTTP writes all families in the text exposition format.
+func (r *Registry) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+	bw := bufio.NewWriter(w)
+	for _, f := range r.Gather() {
+		writeFamily(bw, f)
+	}
+	bw.Flush()
+}
+
+// writeFamily writes f with its HELP and TYPE lines, samples sorted by labels.
+func writeFamily(w *bufio.Writer, f Family) {
+	if f.Help != "" {
+		fmt.Fprintf(w, "# HELP %s %s\n", f.Name, escapeHelp(f.Help))
+	}
+	typ := f.Type
+	if typ == "" {
+		typ = UntypedType
+	}
+	fmt.Fprintf(w, "# TYPE %s %s\n", f.Name, typ)
+
+	lines := make([]string, len(f.Samples))
+	for i, s := range f.Samples {
+		lines[i] = f.Name + formatLabels(s.Labels) + " " + formatValue(s.Value) + "\n"
+	}
+	slices.Sort(lines)
+	for _, l := range lines {
+		w.WriteString(l)
+	}
+}
+
+func formatLabels(labels Labels) string {
+	if len(labels) == 0 {
+		return ""
+	}
+	names := make([]string, 0, len(labels))
+	for name := range labels {
+		names = append(names, name)
+	}
+	slices.Sort(names)
+	var b strings.Builder
+	b.WriteByte('{')
+	for i, name := range names {
+		if i > 0 {
+			b.WriteByte(',')
+		}
+		fmt.Fprintf(&b, "%s=\"%s\"", name, escapeLabel(labels[name]))
+	}
+	b.WriteByte('}')
+	return b.String()
+}
+
+func formatValue(v float64) string {
+	switch {
+	case math.IsInf(v, 1):
+		return "+Inf"
+	case math.IsInf(v, -1):
+		return "-Inf"
+	case math.IsNaN(v):
+		return "NaN"
+	}
+	return strconv.FormatFloat(v, 'g', -1, 64)
+}
+
+var (
+	helpEscaper  = strings.NewReplacer(`\`, `\\`, "\n", `\n`)
+	labelEscaper = strings.NewReplacer(`\`, `\\`, "\n", `\n`, `"`, `\"`)
+)
+
+func escapeHelp(s string) string  { return helpEscaper.Replace(s) }
+func escapeLabel(s string) string { return labelEscaper.Replace(s) }
+
+func checkLabelNames(names []string) {
+	for _, n := range names {
+		if !labelName.MatchString(n) || strings.HasPrefix(n, "__") {
+			panic(fmt.Sprintf("metrics: invalid label name %q", n))
+		}
+	}
+}
@@ -0,0 +1,82 @@
+package metrics
+
+import (
+	"io"
+	"math"
+	"net/http/httptest"
+	"testing"
+)
+
+// families is a Collector returning the same families on every scrape.
+type families []Family
+
+func (f families) Collect() []Family { return f }
+
+func TestExposition(t *testing.T) {
+	r := NewRegistry()
+	c := r.NewCounter("http_requests_total", "Requests.\nBy \\ method", "method", "code")
+	c.Inc("GET", "200")
+	c.Inc("GET", "200")
+	c.Add(2.5, "POST", "500")
+	g := r.NewGauge("temperature", "")
+	g.Set(-3)
+	g.Add(0.5)
+	g.Inc()
+	g.Dec()
+	r.Register(SnapshotFunc("keys_total", "Keys.", CounterType, "key", func() map[string]int {
+		return map[string]int{"a\"b\\c\nd": 3, "b": 1}
+	}))
+	r.Register(families{{Name: "z_custom", Samples: []Sample{
+		{Labels: Labels{"x": "1"}, Value: math.NaN()},
+		{Value: math.Inf(1)},
+	}}})
+
+	rec := httptest.NewRecorder()
+	r.ServeHTTP(rec, httptest.NewRequest("GET", "/metrics", nil))
+	if ct := rec.Header().Get("Content-Type"); ct != "text/plain; version=0.0.4; charset=utf-8" {
+		t.Fatalf("Content-Type %q", ct)
+	}
+	body, _ := io.ReadAll(rec.Body)
+
+	/* families sorted by name, series by their labels, and \, " and newlines escaped */
+	want := `# HELP http_requests_total Requests.\nBy \\ method
+# TYPE http_requests_total counter
+http_requests_total{code="200",method="GET"} 2
+http_requests_total{code="500",method="POST"} 2.5
+# HELP keys_total Keys.
+# TYPE keys_total counter
+keys_total{key="a\"b\\c\nd"} 3
+keys_total{key="b"} 1
+# TYPE temperature gauge
+temperature -2.5
+# TYPE z_custom untyped
+z_custom +Inf
+z_custom{x="1"} NaN
+`
+	if string(body) != want {
+		t.Fatalf("got\n%s\nwant\n%s", body, want)
+	}
+}
+
+func TestProgrammingErrorsPanic(t *testing.T) {
+	r := NewRegistry()
+	c := r.NewCounter("taken", "", "code")
+	for name, f := range map[string]func(){
+		"a name registered twice":   func() { r.NewGauge("taken", "") },
+		"an invalid metric name":    func() { r.NewCounter("1st", "") },
+		"an invalid label name":     func() { r.NewCounter("ok_total", "", "a-b") },
+		"a reserved label name":     func() { r.NewCounter("ok_total", "", "__name") },
+		"a wrong number of labels":  func() { c.Inc() },
+		"a counter going down":      func() { c.Add(-1, "200") },
+		"an invalid snapshot label": func() { SnapshotFunc("ok_total", "", CounterType, "x y", nil) },
+	} {
+		func() {
+			defer func() {
+				if recover() == nil {
+					t.Errorf("%s did not panic", name)
+				}
+			}()
+			f()
+		}()
+	}
+}
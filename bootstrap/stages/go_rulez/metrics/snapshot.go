@@ -0,0 +1,25 @@
+package metrics
+
+// SnapshotFunc exposes a keyed counter we already have, such as counter.SafeCounter or counter.Sharded,
+// without copying every increment into the registry: on every scrape it calls snapshot and
+// turns each key into a sample labeled label="key".
+func SnapshotFunc(name, help string, typ Type, label string, snapshot func() map[string]int) Collector {
+	checkLabelNames([]string{label})
+	return snapshotCollector{name, help, typ, label, snapshot}
+}
+
+type snapshotCollector struct {
+	name, help string
+	typ        Type
+	label      string
+	snapshot   func() map[string]int
+}
+
+func (c snapshotCollector) Collect() []Family {
+	snap := c.snapshot()
+	f := Family{Name: c.name, Help: c.help, Type: c.typ, Samples: make([]Sample, 0, len(snap))}
+	for key, v := range snap {
+		f.Samples = append(f.Samples, Sample{Labels: Labels{c.label: key}, Value: float64(v)})
+	}
+	return []Family{f}
+}
@@ -0,0 +1,107 @@
+package metrics
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+)
+
+// vec holds one value per combination of label values, it is what Counter and Gauge are made of.
+type vec struct {
+	name, help string
+	typ        Type
+	labelNames []string
+	mu         sync.Mutex
+	values     map[string]*series // keyed by the joined label values
+}
+
+type series struct {
+	labels Labels
+	value  float64
+}
+
+func newVec(name, help string, typ Type, labelNames []string) *vec {
+	checkLabelNames(labelNames)
+	return &vec{name: name, help: help, typ: typ, labelNames: labelNames, values: make(map[string]*series)}
+}
+
+func (v *vec) add(delta float64, labelValues []string, set bool) {
+	if len(labelValues) != len(v.labelNames) {
+		panic(fmt.Sprintf("metrics: %s wants %d label values, got %d", v.name, len(v.labelNames), len(labelValues)))
+	}
+	key := strings.Join(labelValues, "\xff")
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	s, ok := v.values[key]
+	if !ok {
+		s = &series{labels: make(Labels, len(labelValues))}
+		for i, name := range v.labelNames {
+			s.labels[name] = labelValues[i]
+		}
+		v.values[key] = s
+	}
+	if set {
+		s.value = delta
+	} else {
+		s.value += delta
+	}
+}
+
+func (v *vec) Collect() []Family {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	f := Family{Name: v.name, Help: v.help, Type: v.typ, Samples: make([]Sample, 0, len(v.values))}
+	for _, s := range v.values {
+		f.Samples = append(f.Samples, Sample{Labels: s.labels, Value: s.value})
+	}
+	return []Family{f}
+}
+
+// Counter is a value that only goes up, kept per combination of label values.
+type Counter struct{ v *vec }
+
+// NewCounter creates a counter with the given label names and registers it.
+func (r *Registry) NewCounter(name, help string, labelNames ...string) *Counter {
+	c := &Counter{newVec(name, help, CounterType, labelNames)}
+	r.Register(c)
+	return c
+}
+
+// Inc adds 1 to the counter with the given label values, one per label name.
+func (c *Counter) Inc(labelValues ...string) { c.v.add(1, labelValues, false) }
+
+// Add adds delta to the counter with the given label values. It panics on a negative delta, counters never go down.
+func (c *Counter) Add(delta float64, labelValues ...string) {
+	if delta < 0 {
+		panic(fmt.Sprintf("metrics: counter %s can not decrease", c.v.name))
+	}
+	c.v.add(delta, labelValues, false)
+}
+
+// Collect implements Collector.
+func (c *Counter) Collect() []Family { return c.v.Collect() }
+
+// Gauge is a value that goes up and down, kept per combination of label values.
+type Gauge struct{ v *vec }
+
+// NewGauge creates a gauge with the given label names and registers it.
+func (r *Registry) NewGauge(name, help string, labelNames ...string) *Gauge {
+	g := &Gauge{newVec(name, help, GaugeType, labelNames)}
+	r.Register(g)
+	return g
+}
+
+// Set sets the gauge with the given label values to value.
+func (g *Gauge) Set(value float64, labelValues ...string) { g.v.add(value, labelValues, true) }
+
+// Add adds delta, which may be negative, to the gauge with the given label values.
+func (g *Gauge) Add(delta float64, labelValues ...string) { g.v.add(delta, labelValues, false) }
+
+// Inc adds 1 to the gauge with the given label values.
+func (g *Gauge) Inc(labelValues ...string) { g.v.add(1, labelValues, false) }
+
+// Dec subtracts 1 from the gauge with the given label values.
+func (g *Gauge) Dec(labelValues ...string) { g.v.add(-1, labelValues, false) }
+
+// Collect implements Collector.
+func (g *Gauge) Collect() []Family { return g.v.Collect() }
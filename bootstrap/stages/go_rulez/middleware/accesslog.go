@@ -0,0 +1,54 @@
+package middleware
+
+import (
+	"log/slog"
+	"net/http"
+	"time"
+)
+
+// AccessLog logs a line for every request once it is served:
+//
+//	level=INFO msg=request method=GET path=/pairs/1/2 route="GET /pairs/{x}/{y}" status=200 bytes=7 duration=61.2µs request_id=...
+//
+// The route is only known for requests a router matched, and the request ID only if RequestID runs before AccessLog.
+// Server errors are logged at level ERROR, as are responses aborted by a panic (aborted=true, status=0 if nothing was written).
+func AccessLog(logger *slog.Logger) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			start := time.Now()
+			rw := &responseWriter{ResponseWriter: w}
+			completed := false
+			defer func() {
+				status := rw.status
+				if status == 0 && completed {
+					status = http.StatusOK // net/http answers 200 for handlers that write nothing
+				}
+				level := slog.LevelInfo
+				if !completed || status >= 500 {
+					level = slog.LevelError
+				}
+				attrs := []slog.Attr{
+					slog.String("method", r.Method),
+					slog.String("path", r.URL.Path),
+				}
+				if r.Pattern != "" {
+					attrs = append(attrs, slog.String("route", r.Pattern))
+				}
+				attrs = append(attrs,
+					slog.Int("status", status),
+					slog.Int64("bytes", rw.bytes),
+					slog.Duration("duration", time.Since(start)),
+				)
+				if !completed {
+					attrs = append(attrs, slog.Bool("aborted", true))
+				}
+				if id := RequestIDFrom(r.Context()); id != "" {
+					attrs = append(attrs, slog.String("request_id", id))
+				}
+				logger.LogAttrs(r.Context(), level, "request", attrs...)
+			}()
+			next.ServeHTTP(rw, r)
+			completed = true
+		})
+	}
+}
@@ -0,0 +1,51 @@
+// Package middleware holds reusable http.Handler middleware, each a func(http.Handler) http.Handler
+// that fits router.Use, router.Group or plain handler wrapping:
+//   - RequestID tags every request with an ID, taken from the X-Request-Id header or generated
+//   - AccessLog logs every request with its status, size and latency through log/slog
+//   - Recover turns a panicking handler into a 500 and logs the stack
+//   - Timeout gives up on a handler after a deadline
+//   - Gzip compresses responses for clients that accept it
+//
+// Order matters, the first middleware wraps the others. A typical stack is
+//
+//	r.Use(middleware.RequestID, middleware.AccessLog(logger), middleware.Recover(logger), middleware.Gzip)
+//
+// so the access log knows the request ID and sees the 500 Recover answers with.
+package middleware
+
+import "net/http"
+
+// responseWriter remembers the status and size of a response for the middleware that wrapped it.
+type responseWriter struct {
+	http.ResponseWriter
+	status int // 0 until the header is written
+	bytes  int64
+}
+
+func (w *responseWriter) WriteHeader(code int) {
+	// 1xx responses other than 101 Switching Protocols are informational, the final status is yet to come
+	if w.status == 0 && (code >= 200 || code == http.StatusSwitchingProtocols) {
+		w.status = code
+	}
+	w.ResponseWriter.WriteHeader(code)
+}
+
+func (w *responseWriter) Write(p []byte) (int, error) {
+	if w.status == 0 {
+		w.status = http.StatusOK
+	}
+	n, err := w.ResponseWriter.Write(p)
+	w.bytes += int64(n)
+	return n, err
+}
+
+// Flush lets streaming handlers, such as server-sent events, flush through the middleware.
+func (w *responseWriter) Flush() {
+	if w.status == 0 {
+		w.status = http.StatusOK
+	}
+	http.NewResponseController(w.ResponseWriter).Flush()
+}
+
+// Unwrap gives http.ResponseController access to the underlying writer, for Hijack and the deadlines.
+func (w *responseWriter) Unwrap() http.ResponseWriter { return w.ResponseWriter }
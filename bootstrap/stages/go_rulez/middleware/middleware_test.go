@@ -0,0 +1,248 @@
+package middleware
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// serve runs req through h and returns what the client gets.
+func serve(h http.Handler, req *http.Request) *http.Response {
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, req)
+	return rec.Result()
+}
+
+// logBuffer collects the log lines written by handlers still running on server goroutines.
+type logBuffer struct {
+	mu sync.Mutex
+	b  bytes.Buffer
+}
+
+func (l *logBuffer) Write(p []byte) (int, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.b.Write(p)
+}
+
+// await returns the log once it has a line with msg, the access log is written after the client got its response.
+func (l *logBuffer) await(t *testing.T, msg string) string {
+	t.Helper()
+	for deadline := time.Now().Add(5 * time.Second); time.Now().Before(deadline); time.Sleep(time.Millisecond) {
+		l.mu.Lock()
+		s := l.b.String()
+		l.mu.Unlock()
+		if strings.Contains(s, "msg="+msg) {
+			return s
+		}
+	}
+	t.Fatalf("no %s in the log", msg)
+	return ""
+}
+
+func (l *logBuffer) reset() {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.b.Reset()
+}
+
+func TestRequestID(t *testing.T) {
+	var seen string
+	h := RequestID(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		seen = RequestIDFrom(r.Context())
+	}))
+
+	req := httptest.NewRequest("GET", "/", nil)
+	req.Header.Set(RequestIDHeader, "abc-123")
+	if resp := serve(h, req); seen != "abc-123" || resp.Header.Get(RequestIDHeader) != "abc-123" {
+		t.Fatalf("incoming ID abc-123: handler saw %q, response header %q", seen, resp.Header.Get(RequestIDHeader))
+	}
+
+	for _, incoming := range []string{"", "has space", "bad\nline", strings.Repeat("x", 129)} {
+		req := httptest.NewRequest("GET", "/", nil)
+		req.Header.Set(RequestIDHeader, incoming)
+		resp := serve(h, req)
+		if len(seen) != 32 || seen == incoming || resp.Header.Get(RequestIDHeader) != seen {
+			t.Fatalf("incoming ID %q: handler saw %q, response header %q, want a new ID", incoming, seen, resp.Header.Get(RequestIDHeader))
+		}
+	}
+}
+
+func TestPropagate(t *testing.T) {
+	var got string
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		got = r.Header.Get(RequestIDHeader)
+	}))
+	defer upstream.Close()
+
+	client := &http.Client{Transport: Propagate(nil)}
+	req, _ := http.NewRequestWithContext(WithRequestID(context.Background(), "prop-1"), "GET", upstream.URL, nil)
+	resp, err := client.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if got != "prop-1" {
+		t.Fatalf("upstream got request ID %q, want prop-1", got)
+	}
+	if req.Header.Get(RequestIDHeader) != "" {
+		t.Fatal("Propagate modified the request it was given")
+	}
+}
+
+func TestAccessLog(t *testing.T) {
+	var buf bytes.Buffer
+	logger := slog.New(slog.NewTextHandler(&buf, nil))
+	h := RequestID(AccessLog(logger)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/fail" {
+			http.Error(w, "no", http.StatusBadGateway)
+			return
+		}
+		io.WriteString(w, "hello")
+	})))
+
+	req := httptest.NewRequest("GET", "/hello", nil)
+	req.Header.Set(RequestIDHeader, "abc-123")
+	serve(h, req)
+	for _, want := range []string{"level=INFO", "method=GET", "path=/hello", "status=200", "bytes=5", "request_id=abc-123", "duration="} {
+		if !strings.Contains(buf.String(), want) {
+			t.Fatalf("access log %q lacks %s", buf.String(), want)
+		}
+	}
+
+	buf.Reset()
+	serve(h, httptest.NewRequest("GET", "/fail", nil))
+	if !strings.Contains(buf.String(), "level=ERROR") || !strings.Contains(buf.String(), "status=502") {
+		t.Fatalf("access log of a 502 = %q, want it at level ERROR", buf.String())
+	}
+}
+
+func TestRecover(t *testing.T) {
+	var buf logBuffer
+	logger := slog.New(slog.NewTextHandler(&buf, nil))
+	srv := httptest.NewServer(RequestID(AccessLog(logger)(Recover(logger)(Gzip(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/late" {
+			io.WriteString(w, "half a response")
+			w.(http.Flusher).Flush()
+		}
+		panic("boom")
+	}))))))
+	defer srv.Close()
+
+	/* nothing written yet: the client gets a plain 500, the log the panic with its stack */
+	resp, err := http.Get(srv.URL + "/early")
+	if err != nil {
+		t.Fatal(err)
+	}
+	body, _ := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusInternalServerError || resp.Header.Get("Content-Encoding") != "" {
+		t.Fatalf("got %d with Content-Encoding %q, want a plain 500", resp.StatusCode, resp.Header.Get("Content-Encoding"))
+	}
+	if !strings.Contains(string(body), "Internal Server Error") {
+		t.Fatalf("body %q", body)
+	}
+	log := buf.await(t, "request")
+	for _, want := range []string{`msg="handler panicked"`, "panic=boom", "stack=", "status=500"} {
+		if !strings.Contains(log, want) {
+			t.Fatalf("log %q lacks %s", log, want)
+		}
+	}
+
+	/* the response had started: the client sees it cut short instead of a truncated success */
+	buf.reset()
+	resp, err = http.Get(srv.URL + "/late")
+	if err == nil {
+		_, err = io.ReadAll(resp.Body)
+		resp.Body.Close()
+	}
+	if err == nil {
+		t.Fatal("a response aborted by a panic read fine")
+	}
+	if log := buf.await(t, "request"); !strings.Contains(log, "aborted=true") {
+		t.Fatalf("access log %q does not say the response was aborted", log)
+	}
+}
+
+func TestTimeout(t *testing.T) {
+	canceled := make(chan struct{})
+	h := Timeout(50 * time.Millisecond)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/fast" {
+			io.WriteString(w, "fast")
+			return
+		}
+		<-r.Context().Done()
+		close(canceled)
+	}))
+
+	if resp := serve(h, httptest.NewRequest("GET", "/fast", nil)); resp.StatusCode != http.StatusOK {
+		t.Fatalf("fast handler got %d", resp.StatusCode)
+	}
+	if resp := serve(h, httptest.NewRequest("GET", "/slow", nil)); resp.StatusCode != http.StatusServiceUnavailable {
+		t.Fatalf("slow handler got %d, want 503", resp.StatusCode)
+	}
+	select {
+	case <-canceled:
+	case <-time.After(time.Second):
+		t.Fatal("the context of the timed out handler was not canceled")
+	}
+}
+
+func TestGzip(t *testing.T) {
+	text := strings.Repeat("hello ", 100)
+	h := Gzip(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/empty":
+			w.WriteHeader(http.StatusNoContent)
+		case "/encoded":
+			w.Header().Set("Content-Encoding", "br")
+			io.WriteString(w, "already compressed")
+		default:
+			io.WriteString(w, text)
+		}
+	}))
+
+	tests := []struct {
+		path, accept string
+		gzipped      bool
+	}{
+		{"/", "gzip", true},
+		{"/", "br, GZIP;q=0.5", true},
+		{"/", "gzip;q=0, br", false},
+		{"/", "", false},
+		{"/empty", "gzip", false},
+		{"/encoded", "gzip", false},
+	}
+	for _, tt := range tests {
+		req := httptest.NewRequest("GET", tt.path, nil)
+		req.Header.Set("Accept-Encoding", tt.accept)
+		resp := serve(h, req)
+		if got := resp.Header.Get("Content-Encoding") == "gzip"; got != tt.gzipped {
+			t.Errorf("%s with Accept-Encoding %q: gzipped %v, want %v", tt.path, tt.accept, got, tt.gzipped)
+		}
+		if !strings.Contains(resp.Header.Get("Vary"), "Accept-Encoding") {
+			t.Errorf("%s: no Vary: Accept-Encoding", tt.path)
+		}
+		if !tt.gzipped || tt.path != "/" {
+			continue
+		}
+		if ct := resp.Header.Get("Content-Type"); !strings.HasPrefix(ct, "text/plain") {
+			t.Errorf("Content-Type %q was sniffed from the compressed bytes", ct)
+		}
+		zr, err := gzip.NewReader(resp.Body)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if b, _ := io.ReadAll(zr); string(b) != text {
+			t.Errorf("decompressed body %q", b)
+		}
+	}
+}
@@ -0,0 +1,41 @@
+package middleware
+
+import (
+	"log/slog"
+	"net/http"
+	"runtime/debug"
+)
+
+// Recover keeps a panicking handler from taking the connection down with it. The panic is logged with its stack,
+// and the client gets a 500 Internal Server Error. If the handler had already started its response a 500 can not be
+// sent anymore, so the response is aborted instead and the client sees it cut short rather than a truncated success.
+//
+// http.ErrAbortHandler is passed on untouched, it is how handlers abort a response on purpose.
+func Recover(logger *slog.Logger) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			rw := &responseWriter{ResponseWriter: w}
+			defer func() {
+				p := recover()
+				if p == nil {
+					return
+				}
+				if p == http.ErrAbortHandler {
+					panic(p)
+				}
+				attrs := []any{"method", r.Method, "path", r.URL.Path, "panic", p, "stack", string(debug.Stack())}
+				if id := RequestIDFrom(r.Context()); id != "" {
+					attrs = append(attrs, "request_id", id)
+				}
+				logger.ErrorContext(r.Context(), "handler panicked", attrs...)
+				if rw.status != 0 {
+					panic(http.ErrAbortHandler)
+				}
+				// drop what the handler prepared for its own response
+				rw.Header().Del("Content-Encoding")
+				http.Error(rw, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+			}()
+			next.ServeHTTP(rw, r)
+		})
+	}
+}
@@ -0,0 +1,19 @@
+package middleware
+
+import (
+	"net/http"
+	"time"
+)
+
+// Timeout answers 503 Service Unavailable when the handler takes longer than d, and cancels the request context
+// so the handler can stop working on a response nobody waits for. It is meant for single routes or groups,
+//
+//	r.Handle("GET", "/report", middleware.Timeout(5*time.Second)(report))
+//
+// and is built on http.TimeoutHandler, which buffers the response. That rules out streaming handlers,
+// and a panic in the handler is raised again from the middleware, so Recover logs that stack instead of the original.
+func Timeout(d time.Duration) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.TimeoutHandler(next, d, "request timed out")
+	}
+}
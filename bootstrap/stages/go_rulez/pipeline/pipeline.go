@@ -0,0 +1,94 @@
+// Package pipeline builds concurrent pipelines out of generic stages connected by channels,
+// the fan-out/fan-in of sum_together in go_rulez.go made reusable:
+//
+//	p := pipeline.New(ctx)
+//	nums := pipeline.Source(p, 1, 2, 3, 4, 5, 6)
+//	squares := pipeline.ParallelMap(p, nums, 3, func(ctx context.Context, n int) (int, error) { return n * n, nil })
+//	evens := pipeline.Filter(p, squares, func(n int) bool { return n%2 == 0 })
+//	result, err := pipeline.Collect(p, evens) // [4 16 36]
+//
+// Every stage runs in its own goroutines under the Pipeline, closes its output channel when it is done,
+// and gives up as soon as the pipeline is canceled. The first error a stage returns cancels the pipeline,
+// so the whole pipeline shuts down and Wait (or Collect) returns that error once every goroutine is gone.
+package pipeline
+
+import (
+	"context"
+	"errors"
+	"sync"
+)
+
+// errStopped cancels a pipeline stopped on purpose.
+var errStopped = errors.New("pipeline: stopped")
+
+// Pipeline runs stages and tracks their goroutines and errors. Use New to create one.
+type Pipeline struct {
+	parent context.Context
+	ctx    context.Context
+	cancel context.CancelCauseFunc
+	wg     sync.WaitGroup
+
+	mu  sync.Mutex
+	err error // the first error of a stage
+}
+
+// New returns an empty pipeline, canceled along with ctx.
+func New(ctx context.Context) *Pipeline {
+	p := &Pipeline{parent: ctx}
+	p.ctx, p.cancel = context.WithCancelCause(ctx)
+	return p
+}
+
+// Context returns the context the stages run under, it is done once the pipeline is canceled.
+func (p *Pipeline) Context() context.Context { return p.ctx }
+
+// Go runs f as a stage of p, to build stages of your own. The context given to f is done once the pipeline is
+// canceled, f must return soon after. If f returns an error, the pipeline is canceled.
+func (p *Pipeline) Go(f func(ctx context.Context) error) {
+	p.wg.Add(1)
+	go func() {
+		defer p.wg.Done()
+		if err := f(p.ctx); err != nil {
+			p.fail(err)
+		}
+	}()
+}
+
+// Stop cancels p without an error, for consumers that need no more values. Stages stop early,
+// and Wait returns nil unless a stage had failed already.
+func (p *Pipeline) Stop() { p.cancel(errStopped) }
+
+// Wait waits for all stages to return and then returns the first error of a stage, or the error of the parent
+// context if it was canceled.
+func (p *Pipeline) Wait() error {
+	p.wg.Wait()
+	p.cancel(errStopped) // release the context
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.err != nil {
+		return p.err
+	}
+	return p.parent.Err()
+}
+
+func (p *Pipeline) fail(err error) {
+	if p.ctx.Err() != nil && errors.Is(err, p.ctx.Err()) {
+		return // a stage giving up because the pipeline was canceled, not a failure of its own
+	}
+	p.mu.Lock()
+	if p.err == nil {
+		p.err = err
+	}
+	p.mu.Unlock()
+	p.cancel(err)
+}
+
+// send sends v on out unless ctx is done first, and reports whether it did.
+func send[T any](ctx context.Context, out chan<- T, v T) bool {
+	select {
+	case out <- v:
+		return true
+	case <-ctx.Done():
+		return false
+	}
+}
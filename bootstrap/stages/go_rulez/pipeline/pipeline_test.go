@@ -0,0 +1,177 @@
+package pipeline
+
+import (
+	"context"
+	"errors"
+	"math/rand"
+	"slices"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/ysyesilyurt/lets-go/bootstrap/stages/go_rulez/leakcheck"
+)
+
+// naturals counts up forever, or until the pipeline stops asking.
+func naturals(yield func(int) bool) {
+	for i := 0; yield(i); i++ {
+	}
+}
+
+func square(_ context.Context, n int) (int, error) { return n * n, nil }
+
+// slowSquare takes up to 5ms, or until ctx is done.
+func slowSquare(ctx context.Context, n int) (int, error) {
+	select {
+	case <-time.After(time.Duration(rand.Intn(5000)) * time.Microsecond):
+		return n * n, nil
+	case <-ctx.Done():
+		return 0, ctx.Err()
+	}
+}
+
+func TestStages(t *testing.T) {
+	leakcheck.Check(t, leakcheck.Config{})
+
+	p := New(context.Background())
+	nums := Source(p, 1, 2, 3, 4, 5, 6)
+	squares := ParallelMap(p, nums, 3, square)
+	evens := Filter(p, squares, func(n int) bool { return n%2 == 0 })
+	if got, err := Collect(p, evens); err != nil || !slices.Equal(got, []int{4, 16, 36}) {
+		t.Fatalf("got %v, %v; want [4 16 36]", got, err)
+	}
+
+	/* every value goes through exactly one of the workers */
+	p = New(context.Background())
+	var workers []<-chan int
+	for _, in := range FanOut(p, Source(p, 1, 2, 3, 4, 5, 6, 7, 8, 9, 10), 3) {
+		workers = append(workers, Map(p, in, square))
+	}
+	got, err := Collect(p, FanIn(p, workers...))
+	slices.Sort(got)
+	if want := []int{1, 4, 9, 16, 25, 36, 49, 64, 81, 100}; err != nil || !slices.Equal(got, want) {
+		t.Fatalf("fanned in %v, %v; want %v", got, err, want)
+	}
+}
+
+func TestBatch(t *testing.T) {
+	leakcheck.Check(t, leakcheck.Config{})
+	p := New(context.Background())
+	in := make(chan int)
+	batches := Batch(p, in, 3, 50*time.Millisecond)
+
+	/* two values wait for maxWait, three fill a batch at once, and the rest goes out when in is closed */
+	in <- 1
+	in <- 2
+	if b := <-batches; !slices.Equal(b, []int{1, 2}) {
+		t.Fatalf("got %v after maxWait, want [1 2]", b)
+	}
+	go func() {
+		for _, v := range []int{3, 4, 5, 6} {
+			in <- v
+		}
+		close(in)
+	}()
+	if got, err := Collect(p, batches); err != nil || !slices.EqualFunc(got, [][]int{{3, 4, 5}, {6}}, slices.Equal) {
+		t.Fatalf("got %v, %v; want [[3 4 5] [6]]", got, err)
+	}
+}
+
+func TestParallelMapKeepsOrder(t *testing.T) {
+	leakcheck.Check(t, leakcheck.Config{})
+	var running, most atomic.Int32
+	p := New(context.Background())
+	nums := FromSeq(p, func(yield func(int) bool) {
+		for i := range 200 {
+			if !yield(i) {
+				return
+			}
+		}
+	})
+	squares := ParallelMap(p, nums, 8, func(ctx context.Context, n int) (int, error) {
+		now := running.Add(1)
+		defer running.Add(-1)
+		for m := most.Load(); now > m && !most.CompareAndSwap(m, now); m = most.Load() {
+		}
+		return slowSquare(ctx, n)
+	})
+	got, err := Collect(p, squares)
+	if err != nil || len(got) != 200 {
+		t.Fatalf("got %d squares, %v", len(got), err)
+	}
+	for i, sq := range got {
+		if sq != i*i {
+			t.Fatalf("square %d is %d, want the results in the order of their values", i, sq)
+		}
+	}
+	if m := most.Load(); m < 2 || m > 8 {
+		t.Fatalf("%d calls ran at once, want more than one and at most 8", m)
+	}
+}
+
+func TestFirstErrorCancels(t *testing.T) {
+	leakcheck.Check(t, leakcheck.Config{})
+	tooMuch := errors.New("100 is too much")
+	check := func(ctx context.Context, n int) (int, error) {
+		if n == 100 {
+			return 0, tooMuch
+		}
+		return slowSquare(ctx, n)
+	}
+
+	/* an endless pipeline still ends, with the error that canceled it rather than the ctx.Err() of the stages it stopped */
+	for name, stage := range map[string]func(p *Pipeline, in <-chan int) <-chan int{
+		"Map":         func(p *Pipeline, in <-chan int) <-chan int { return Map(p, in, check) },
+		"ParallelMap": func(p *Pipeline, in <-chan int) <-chan int { return ParallelMap(p, in, 4, check) },
+	} {
+		p := New(context.Background())
+		got, err := Collect(p, stage(p, FromSeq(p, naturals)))
+		if err != tooMuch || len(got) > 100 {
+			t.Errorf("%s: %d values, %v; want at most 100 values and %q", name, len(got), err, tooMuch)
+		}
+		if !errors.Is(context.Cause(p.Context()), tooMuch) {
+			t.Errorf("%s: the pipeline was canceled by %v", name, context.Cause(p.Context()))
+		}
+	}
+
+	/* Go stages fail the same way, and only the first error counts */
+	p := New(context.Background())
+	p.Go(func(context.Context) error { return tooMuch })
+	p.Go(func(ctx context.Context) error {
+		<-ctx.Done()
+		return errors.New("later")
+	})
+	if err := p.Wait(); err != tooMuch {
+		t.Fatalf("Wait = %v, want %q", err, tooMuch)
+	}
+}
+
+func TestParentCancel(t *testing.T) {
+	leakcheck.Check(t, leakcheck.Config{})
+	ctx, cancel := context.WithCancel(context.Background())
+	p := New(ctx)
+	squares := ParallelMap(p, FromSeq(p, naturals), 4, slowSquare)
+	for range 10 {
+		<-squares
+	}
+	cancel()
+	if _, err := Collect(p, squares); err != context.Canceled {
+		t.Fatalf("Wait = %v, want %v", err, context.Canceled)
+	}
+}
+
+func TestConsumerStopsEarly(t *testing.T) {
+	leakcheck.Check(t, leakcheck.Config{})
+	p := New(context.Background())
+	evens := Filter(p, Map(p, FromSeq(p, naturals), square), func(n int) bool { return n%2 == 0 })
+	batches := Batch(p, evens, 2, time.Second)
+	if b := <-batches; !slices.Equal(b, []int{0, 4}) {
+		t.Fatalf("first batch %v, want [0 4]", b)
+	}
+
+	/* nobody reads batches anymore, Stop alone has to end every stage */
+	p.Stop()
+	if err := p.Wait(); err != nil {
+		t.Fatalf("Wait = %v after Stop, want nil", err)
+	}
+}
@@ -0,0 +1,235 @@
+package pipeline
+
+import (
+	"context"
+	"iter"
+	"sync"
+	"time"
+)
+
+// Source sends items into the pipeline.
+func Source[T any](p *Pipeline, items ...T) <-chan T {
+	return FromSeq(p, func(yield func(T) bool) {
+		for _, v := range items {
+			if !yield(v) {
+				return
+			}
+		}
+	})
+}
+
+// FromSeq sends the values of seq into the pipeline, seq stops early if the pipeline is canceled.
+func FromSeq[T any](p *Pipeline, seq iter.Seq[T]) <-chan T {
+	out := make(chan T)
+	p.Go(func(ctx context.Context) error {
+		defer close(out)
+		for v := range seq {
+			if !send(ctx, out, v) {
+				return nil
+			}
+		}
+		return nil
+	})
+	return out
+}
+
+// Map sends f of every value of in. An error of f cancels the pipeline.
+func Map[In, Out any](p *Pipeline, in <-chan In, f func(context.Context, In) (Out, error)) <-chan Out {
+	out := make(chan Out)
+	p.Go(func(ctx context.Context) error {
+		defer close(out)
+		for v := range recv(ctx, in) {
+			r, err := f(ctx, v)
+			if err != nil {
+				return err
+			}
+			if !send(ctx, out, r) {
+				return nil
+			}
+		}
+		return nil
+	})
+	return out
+}
+
+// Filter passes on the values of in that keep returns true for.
+func Filter[T any](p *Pipeline, in <-chan T, keep func(T) bool) <-chan T {
+	out := make(chan T)
+	p.Go(func(ctx context.Context) error {
+		defer close(out)
+		for v := range recv(ctx, in) {
+			if keep(v) && !send(ctx, out, v) {
+				return nil
+			}
+		}
+		return nil
+	})
+	return out
+}
+
+// Batch groups the values of in into slices of size values. A batch is sent early once maxWait has passed
+// since its first value, if maxWait > 0, and whatever is left when in is closed goes out as a last, shorter batch.
+func Batch[T any](p *Pipeline, in <-chan T, size int, maxWait time.Duration) <-chan []T {
+	size = max(size, 1)
+	out := make(chan []T)
+	p.Go(func(ctx context.Context) error {
+		defer close(out)
+		var batch []T
+		var timeout <-chan time.Time // nil, so never ready, unless a batch with a deadline is open
+		var timer *time.Timer
+		flush := func() bool {
+			if timer != nil {
+				timer.Stop()
+				timer, timeout = nil, nil
+			}
+			b := batch
+			batch = nil
+			return len(b) == 0 || send(ctx, out, b)
+		}
+		for {
+			select {
+			case v, ok := <-in:
+				if !ok {
+					flush()
+					return nil
+				}
+				batch = append(batch, v)
+				if len(batch) == 1 && maxWait > 0 {
+					timer = time.NewTimer(maxWait)
+					timeout = timer.C
+				}
+				if len(batch) == size && !flush() {
+					return nil
+				}
+			case <-timeout:
+				if !flush() {
+					return nil
+				}
+			case <-ctx.Done():
+				if timer != nil {
+					timer.Stop()
+				}
+				return nil
+			}
+		}
+	})
+	return out
+}
+
+// FanOut spreads the values of in over n channels, each value goes to one of them, whichever is ready to take it.
+// Hand each channel to its own worker to share the work like sum_together does.
+func FanOut[T any](p *Pipeline, in <-chan T, n int) []<-chan T {
+	outs := make([]<-chan T, max(n, 1))
+	for i := range outs {
+		out := make(chan T)
+		outs[i] = out
+		p.Go(func(ctx context.Context) error {
+			defer close(out)
+			for v := range recv(ctx, in) {
+				if !send(ctx, out, v) {
+					return nil
+				}
+			}
+			return nil
+		})
+	}
+	return outs
+}
+
+// FanIn merges the values of all ins into one channel, in no particular order. It is closed once all ins are.
+func FanIn[T any](p *Pipeline, ins ...<-chan T) <-chan T {
+	out := make(chan T)
+	var wg sync.WaitGroup
+	wg.Add(len(ins))
+	for _, in := range ins {
+		p.Go(func(ctx context.Context) error {
+			defer wg.Done()
+			for v := range recv(ctx, in) {
+				if !send(ctx, out, v) {
+					return nil
+				}
+			}
+			return nil
+		})
+	}
+	p.Go(func(context.Context) error {
+		wg.Wait()
+		close(out)
+		return nil
+	})
+	return out
+}
+
+// ParallelMap is Map with n calls of f running at once, that still sends the results in the order of in.
+// A slow value holds back the results behind it, at most n of them wait, so memory stays bounded.
+func ParallelMap[In, Out any](p *Pipeline, in <-chan In, n int, f func(context.Context, In) (Out, error)) <-chan Out {
+	n = max(n, 1)
+	out := make(chan Out)
+	// every value gets a slot its result will land in, queued in the order of in
+	type slot = chan Out
+	slots := make(chan slot, n)
+	sem := make(chan struct{}, n)
+
+	p.Go(func(ctx context.Context) error {
+		defer close(slots)
+		for v := range recv(ctx, in) {
+			if !send(ctx, sem, struct{}{}) {
+				return nil
+			}
+			s := make(slot, 1)
+			p.Go(func(ctx context.Context) error {
+				defer func() { <-sem }()
+				r, err := f(ctx, v)
+				if err != nil {
+					return err
+				}
+				s <- r // buffered, never blocks
+				return nil
+			})
+			if !send(ctx, slots, s) {
+				return nil
+			}
+		}
+		return nil
+	})
+	p.Go(func(ctx context.Context) error {
+		defer close(out)
+		for s := range recv(ctx, slots) {
+			select {
+			case r := <-s:
+				if !send(ctx, out, r) {
+					return nil
+				}
+			case <-ctx.Done():
+				return nil
+			}
+		}
+		return nil
+	})
+	return out
+}
+
+// Collect reads everything out of in, then waits for the pipeline. It returns the values read and the error of Wait.
+func Collect[T any](p *Pipeline, in <-chan T) ([]T, error) {
+	var all []T
+	for v := range in {
+		all = append(all, v)
+	}
+	return all, p.Wait()
+}
+
+// recv yields the values of in until it is closed or ctx is done.
+func recv[T any](ctx context.Context, in <-chan T) iter.Seq[T] {
+	return func(yield func(T) bool) {
+		for {
+			select {
+			case v, ok := <-in:
+				if !ok || !yield(v) {
+					return
+				}
+			case <-ctx.Done():
+				return
+			}
+		}
+	}
+}
@@ -0,0 +1,110 @@
+package main
+
+/* Pipelines
+sum_together() in go_rulez.go fans a slice out to two goroutines and fans their sums back in by hand.
+Package pipeline (see pipeline/) makes such stages reusable and generic: Source, Map, Filter, Batch,
+FanOut, FanIn and ParallelMap, all sharing one context so that an error anywhere shuts everything down.
+The tests in pipeline check that every goroutine is gone afterwards with leakcheck.Check: go test ./pipeline
+*/
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"iter"
+	"math/rand"
+	"slices"
+	"time"
+
+	"github.com/ysyesilyurt/lets-go/bootstrap/stages/go_rulez/pipeline"
+)
+
+func sum(_ context.Context, sli []int) (int, error) {
+	s := 0
+	for _, v := range sli {
+		s += v
+	}
+	return s, nil
+}
+
+// slowSquare takes up to 20ms, so the squares of a parallel map come back out of order.
+func slowSquare(ctx context.Context, n int) (int, error) {
+	select {
+	case <-time.After(time.Duration(rand.Intn(20)) * time.Millisecond):
+		return n * n, nil
+	case <-ctx.Done():
+		return 0, ctx.Err()
+	}
+}
+
+// naturals counts up forever, or until the pipeline stops asking.
+func naturals(yield func(int) bool) {
+	for i := 0; yield(i); i++ {
+	}
+}
+
+func main() {
+	ctx := context.Background()
+
+	/* sum_together as a pipeline: each half is summed in parallel, and the sums come back in order */
+	sli := []int{7, 2, 8, -9, 4, 0}
+	p := pipeline.New(ctx)
+	sums, _ := pipeline.Collect(p, pipeline.ParallelMap(p, pipeline.Source(p, sli[:len(sli)/2], sli[len(sli)/2:]), 2, sum))
+	fmt.Printf("Got %d and %d; so the sum is %d!\n", sums[0], sums[1], sums[0]+sums[1])
+
+	/* ParallelMap keeps the order, and 8 squares at a time are a lot faster than one at a time */
+	for _, n := range []int{1, 8} {
+		start := time.Now()
+		p := pipeline.New(ctx)
+		squares, _ := pipeline.Collect(p, pipeline.ParallelMap(p, pipeline.FromSeq(p, limit(naturals, 50)), n, slowSquare))
+		fmt.Printf("%d workers: %d squares in order: %v, took %v\n", n, len(squares), slices.IsSorted(squares), time.Since(start).Round(time.Millisecond))
+	}
+
+	/* FanOut to 3 workers and FanIn their results, in whatever order they finish */
+	p = pipeline.New(ctx)
+	var workers []<-chan int
+	for _, in := range pipeline.FanOut(p, pipeline.FromSeq(p, limit(naturals, 10)), 3) {
+		workers = append(workers, pipeline.Map(p, in, slowSquare))
+	}
+	merged, _ := pipeline.Collect(p, pipeline.FanIn(p, workers...))
+	fmt.Println("fanned in:", merged)
+
+	/* Batch sends full batches, or whatever it has after maxWait */
+	p = pipeline.New(ctx)
+	ticks := pipeline.Map(p, pipeline.FromSeq(p, limit(naturals, 7)), func(_ context.Context, n int) (int, error) {
+		time.Sleep(15 * time.Millisecond)
+		return n, nil
+	})
+	batches, _ := pipeline.Collect(p, pipeline.Batch(p, ticks, 3, 25*time.Millisecond))
+	fmt.Println("batches:", batches)
+
+	/* An error anywhere cancels the whole pipeline, even an endless one, and every goroutine exits */
+	p = pipeline.New(ctx)
+	evens := pipeline.Filter(p, pipeline.FromSeq(p, naturals), func(n int) bool { return n%2 == 0 })
+	checked := pipeline.ParallelMap(p, evens, 4, func(ctx context.Context, n int) (int, error) {
+		if n == 100 {
+			return 0, errors.New("100 is too much")
+		}
+		return slowSquare(ctx, n)
+	})
+	got, err := pipeline.Collect(p, checked)
+	fmt.Printf("endless pipeline: %d values, error %q\n", len(got), err)
+}
+
+// limit yields the first n values of seq.
+func limit[T any](seq iter.Seq[T], n int) iter.Seq[T] {
+	return func(yield func(T) bool) {
+		if n <= 0 {
+			return
+		}
+		i := 0
+		for v := range seq {
+			if !yield(v) {
+				return
+			}
+			if i++; i == n {
+				return
+			}
+		}
+	}
+}
@@ -0,0 +1,59 @@
+package pool
+
+import "context"
+
+// Future is the result of a task submitted with Go or TryGo, available once the task is done.
+type Future[T any] struct {
+	done  chan struct{}
+	value T
+	err   error
+}
+
+// Done returns a channel that is closed once the result is available.
+func (f *Future[T]) Done() <-chan struct{} { return f.done }
+
+// Get waits for the result of the task, or for ctx to be done. A task skipped because the pool failed
+// or was canceled first gets the error that canceled the pool.
+func (f *Future[T]) Get(ctx context.Context) (T, error) {
+	select {
+	case <-f.done:
+		return f.value, f.err
+	case <-ctx.Done():
+		var zero T
+		return zero, ctx.Err()
+	}
+}
+
+// Go submits fn to p like Submit does and returns the Future of its result.
+// An error of fn fails the pool, like the error of any other task.
+func Go[T any](ctx context.Context, p *Pool, fn func(ctx context.Context) (T, error)) (*Future[T], error) {
+	f, j := future(fn)
+	if err := p.submit(ctx, j); err != nil {
+		return nil, err
+	}
+	return f, nil
+}
+
+// TryGo submits fn to p like TrySubmit does and returns the Future of its result.
+func TryGo[T any](p *Pool, fn func(ctx context.Context) (T, error)) (*Future[T], error) {
+	f, j := future(fn)
+	if err := p.trySubmit(j); err != nil {
+		return nil, err
+	}
+	return f, nil
+}
+
+func future[T any](fn func(ctx context.Context) (T, error)) (*Future[T], job) {
+	f := &Future[T]{done: make(chan struct{})}
+	return f, job{
+		task: func(ctx context.Context) error {
+			defer close(f.done)
+			f.value, f.err = fn(ctx)
+			return f.err
+		},
+		skip: func(cause error) {
+			f.err = cause
+			close(f.done)
+		},
+	}
+}
@@ -0,0 +1,198 @@
+// Package pool runs tasks on a fixed number of worker goroutines, instead of a goroutine per task
+// and a time.Sleep to wait for them:
+//
+//	p := pool.New(ctx, pool.Config{Workers: 8})
+//	for _, url := range urls {
+//		p.Submit(ctx, func(ctx context.Context) error { return fetch(ctx, url) })
+//	}
+//	err := p.Close() // waits for every task, returns the first error
+//
+// Tasks wait in a bounded queue, Submit blocks while it is full and TrySubmit gives up with ErrQueueFull.
+// Like golang.org/x/sync/errgroup, the first task to fail cancels the context of the pool, so the tasks still
+// running can stop early and the queued ones are skipped. Go and TryGo submit tasks with a result, as a Future.
+package pool
+
+import (
+	"context"
+	"errors"
+	"runtime"
+	"sync"
+)
+
+var (
+	// ErrQueueFull is returned by TrySubmit and TryGo when the queue has no room for another task.
+	ErrQueueFull = errors.New("pool: queue is full")
+	// ErrClosed is returned for tasks submitted after Close.
+	ErrClosed = errors.New("pool: closed")
+)
+
+// Task is a unit of work. Its context is done once the pool failed or its parent context was canceled.
+type Task func(ctx context.Context) error
+
+// Config configures a Pool, zero fields take the defaults below.
+type Config struct {
+	Workers   int // tasks running at once; defaults to GOMAXPROCS
+	QueueSize int // tasks waiting for a worker; defaults to Workers
+}
+
+// Pool is a fixed set of workers running submitted tasks. Use New to create one and Close it once done.
+type Pool struct {
+	parent context.Context
+	ctx    context.Context
+	cancel context.CancelCauseFunc
+	queue  chan job
+	quit   chan struct{} // closed by Close once the queue is drained, so the workers exit
+	wg     sync.WaitGroup
+
+	mu      sync.Mutex
+	idle    *sync.Cond // signaled when pending drops to 0
+	pending int        // tasks submitted and not finished yet
+	closed  bool
+	err     error // of the first task that failed
+}
+
+// New starts the workers of a pool, canceled along with ctx.
+func New(ctx context.Context, cfg Config) *Pool {
+	if cfg.Workers <= 0 {
+		cfg.Workers = runtime.GOMAXPROCS(0)
+	}
+	if cfg.QueueSize <= 0 {
+		cfg.QueueSize = cfg.Workers
+	}
+	p := &Pool{parent: ctx, queue: make(chan job, cfg.QueueSize), quit: make(chan struct{})}
+	p.ctx, p.cancel = context.WithCancelCause(ctx)
+	p.idle = sync.NewCond(&p.mu)
+	p.wg.Add(cfg.Workers)
+	for i := 0; i < cfg.Workers; i++ {
+		go p.work()
+	}
+	return p
+}
+
+func (p *Pool) work() {
+	defer p.wg.Done()
+	for {
+		select {
+		case j := <-p.queue:
+			p.run(j)
+		case <-p.quit:
+			return
+		}
+	}
+}
+
+// job is a queued task, skip is called instead of the task if the pool is canceled before its turn.
+type job struct {
+	task Task
+	skip func(cause error)
+}
+
+func (p *Pool) run(j job) {
+	defer p.done()
+	if p.ctx.Err() != nil {
+		if j.skip != nil {
+			j.skip(context.Cause(p.ctx))
+		}
+		return // the pool failed or was canceled, skip what is still queued
+	}
+	if err := j.task(p.ctx); err != nil {
+		p.mu.Lock()
+		if p.err == nil && !(p.ctx.Err() != nil && errors.Is(err, p.ctx.Err())) {
+			p.err = err
+			p.cancel(err)
+		}
+		p.mu.Unlock()
+	}
+}
+
+func (p *Pool) done() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.pending--; p.pending == 0 {
+		p.idle.Broadcast()
+	}
+}
+
+// Submit queues task, waiting for room in the queue if it is full, until ctx is done.
+// It fails with ErrClosed after Close, and with the error of the pool once a task failed.
+func (p *Pool) Submit(ctx context.Context, task Task) error {
+	return p.submit(ctx, job{task: task})
+}
+
+func (p *Pool) submit(ctx context.Context, j job) error {
+	if err := p.add(); err != nil {
+		return err
+	}
+	select {
+	case p.queue <- j:
+		return nil
+	case <-ctx.Done():
+		p.done()
+		return ctx.Err()
+	case <-p.ctx.Done():
+		p.done()
+		return context.Cause(p.ctx)
+	}
+}
+
+// TrySubmit queues task if there is room in the queue, and returns ErrQueueFull otherwise.
+func (p *Pool) TrySubmit(task Task) error {
+	return p.trySubmit(job{task: task})
+}
+
+func (p *Pool) trySubmit(j job) error {
+	if err := p.add(); err != nil {
+		return err
+	}
+	select {
+	case p.queue <- j:
+		return nil
+	default:
+		p.done()
+		return ErrQueueFull
+	}
+}
+
+// add counts a task about to be queued.
+func (p *Pool) add() error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	switch {
+	case p.closed:
+		return ErrClosed
+	case p.ctx.Err() != nil:
+		return context.Cause(p.ctx)
+	}
+	p.pending++
+	return nil
+}
+
+// Wait waits for every task submitted so far to finish and returns the error of the first one that failed,
+// or the error of the parent context if it was canceled. The pool stays open, and a failed pool stays failed.
+func (p *Pool) Wait() error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	for p.pending > 0 {
+		p.idle.Wait()
+	}
+	if p.err != nil {
+		return p.err
+	}
+	return p.parent.Err()
+}
+
+// Close stops accepting tasks, waits for the submitted ones like Wait does and stops the workers.
+// It returns what Wait returns, and can be called more than once.
+func (p *Pool) Close() error {
+	p.mu.Lock()
+	closed := p.closed
+	p.closed = true
+	p.mu.Unlock()
+	err := p.Wait()
+	if !closed {
+		close(p.quit)
+		p.wg.Wait()
+		p.cancel(ErrClosed) // release the context
+	}
+	return err
+}
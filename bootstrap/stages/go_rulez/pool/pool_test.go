@@ -0,0 +1,211 @@
+package pool
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/ysyesilyurt/lets-go/bootstrap/stages/go_rulez/leakcheck"
+)
+
+// blocking returns a task that tells started it runs and then waits for release.
+func blocking(started chan<- struct{}, release <-chan struct{}, err error) Task {
+	return func(context.Context) error {
+		started <- struct{}{}
+		<-release
+		return err
+	}
+}
+
+func TestRunsEveryTask(t *testing.T) {
+	leakcheck.Check(t, leakcheck.Config{})
+	p := New(context.Background(), Config{Workers: 8})
+	var n atomic.Int32
+	var futures []*Future[int]
+	for i := range 1000 {
+		if err := p.Submit(context.Background(), func(context.Context) error {
+			n.Add(1)
+			return nil
+		}); err != nil {
+			t.Fatal(err)
+		}
+		f, err := Go(context.Background(), p, func(context.Context) (int, error) { return i * i, nil })
+		if err != nil {
+			t.Fatal(err)
+		}
+		futures = append(futures, f)
+	}
+	if err := p.Close(); err != nil || n.Load() != 1000 {
+		t.Fatalf("Close = %v with %d of 1000 tasks run", err, n.Load())
+	}
+	for i, f := range futures {
+		if v, err := f.Get(context.Background()); err != nil || v != i*i {
+			t.Fatalf("future %d = %d, %v; want %d", i, v, err, i*i)
+		}
+	}
+}
+
+func TestQueueFull(t *testing.T) {
+	leakcheck.Check(t, leakcheck.Config{})
+	p := New(context.Background(), Config{Workers: 1, QueueSize: 1})
+	defer p.Close()
+	started, release := make(chan struct{}), make(chan struct{})
+
+	/* one task runs, one waits in the queue, and that is all the room there is */
+	p.Submit(context.Background(), blocking(started, release, nil))
+	<-started
+	if err := p.Submit(context.Background(), func(context.Context) error { return nil }); err != nil {
+		t.Fatal(err)
+	}
+	if err := p.TrySubmit(func(context.Context) error { return nil }); err != ErrQueueFull {
+		t.Fatalf("TrySubmit = %v, want ErrQueueFull", err)
+	}
+	if _, err := TryGo(p, func(context.Context) (int, error) { return 1, nil }); err != ErrQueueFull {
+		t.Fatalf("TryGo = %v, want ErrQueueFull", err)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	if err := p.Submit(ctx, func(context.Context) error { return nil }); err != context.DeadlineExceeded {
+		t.Fatalf("Submit to a full queue = %v, want it to block until its context is done", err)
+	}
+
+	submitted := make(chan error)
+	go func() { submitted <- p.Submit(context.Background(), func(context.Context) error { return nil }) }()
+	select {
+	case err := <-submitted:
+		t.Fatalf("Submit to a full queue returned %v right away", err)
+	case <-time.After(20 * time.Millisecond):
+	}
+	close(release)
+	if err := <-submitted; err != nil {
+		t.Fatalf("Submit = %v once there was room, want nil", err)
+	}
+	if err := p.Wait(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestFirstErrorCancels(t *testing.T) {
+	leakcheck.Check(t, leakcheck.Config{})
+	p := New(context.Background(), Config{Workers: 2, QueueSize: 3})
+	boom := errors.New("boom")
+	started, release := make(chan struct{}), make(chan struct{})
+
+	/* one worker fails, the other runs a task that stops once the pool is canceled, with ctx.Err() which does not count */
+	p.Submit(context.Background(), blocking(started, release, boom))
+	<-started
+	p.Submit(context.Background(), func(ctx context.Context) error {
+		started <- struct{}{}
+		<-ctx.Done()
+		return ctx.Err()
+	})
+	<-started
+	var ran atomic.Bool
+	var futures []*Future[int]
+	for range 3 {
+		f, err := Go(context.Background(), p, func(context.Context) (int, error) {
+			ran.Store(true)
+			return 1, nil
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		futures = append(futures, f)
+	}
+	close(release)
+
+	if err := p.Wait(); err != boom {
+		t.Fatalf("Wait = %v, want %v", err, boom)
+	}
+	if ran.Load() {
+		t.Fatal("a task queued behind the failure ran")
+	}
+	for i, f := range futures {
+		if _, err := f.Get(context.Background()); err != boom {
+			t.Fatalf("skipped future %d got %v, want the cause %v", i, err, boom)
+		}
+	}
+	if err := p.Submit(context.Background(), func(context.Context) error { return nil }); err != boom {
+		t.Fatalf("Submit to a failed pool = %v, want %v", err, boom)
+	}
+	if err := p.Close(); err != boom {
+		t.Fatalf("Close = %v, want %v", err, boom)
+	}
+}
+
+func TestWaitAndClose(t *testing.T) {
+	leakcheck.Check(t, leakcheck.Config{})
+	p := New(context.Background(), Config{Workers: 2})
+	var n atomic.Int32
+	inc := func(context.Context) error {
+		n.Add(1)
+		return nil
+	}
+
+	/* the pool stays open after Wait, and Close can be called again */
+	for round := 1; round <= 2; round++ {
+		p.Submit(context.Background(), inc)
+		if err := p.Wait(); err != nil || n.Load() != int32(round) {
+			t.Fatalf("Wait = %v with %d tasks run, want %d", err, n.Load(), round)
+		}
+	}
+	if err := p.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if err := p.Close(); err != nil {
+		t.Fatalf("second Close = %v", err)
+	}
+
+	if err := p.Submit(context.Background(), inc); err != ErrClosed {
+		t.Errorf("Submit after Close = %v, want ErrClosed", err)
+	}
+	if err := p.TrySubmit(inc); err != ErrClosed {
+		t.Errorf("TrySubmit after Close = %v, want ErrClosed", err)
+	}
+	if _, err := Go(context.Background(), p, func(context.Context) (int, error) { return 0, nil }); err != ErrClosed {
+		t.Errorf("Go after Close = %v, want ErrClosed", err)
+	}
+	if _, err := TryGo(p, func(context.Context) (int, error) { return 0, nil }); err != ErrClosed {
+		t.Errorf("TryGo after Close = %v, want ErrClosed", err)
+	}
+}
+
+func TestParentCancel(t *testing.T) {
+	leakcheck.Check(t, leakcheck.Config{})
+	ctx, cancel := context.WithCancel(context.Background())
+	p := New(ctx, Config{Workers: 1, QueueSize: 2})
+	started := make(chan struct{})
+	p.Submit(context.Background(), func(ctx context.Context) error {
+		close(started)
+		<-ctx.Done()
+		return ctx.Err()
+	})
+	<-started
+	f, err := Go(context.Background(), p, func(context.Context) (int, error) { return 1, nil })
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	/* Get gives up with its own context before the task is done */
+	short, stop := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer stop()
+	if _, err := f.Get(short); err != context.DeadlineExceeded {
+		t.Fatalf("Get = %v, want its context's error", err)
+	}
+
+	cancel()
+	if err := p.Wait(); err != context.Canceled {
+		t.Fatalf("Wait = %v, want %v", err, context.Canceled)
+	}
+	if _, err := f.Get(context.Background()); err != context.Canceled {
+		t.Fatalf("the skipped future got %v, want %v", err, context.Canceled)
+	}
+	if err := p.Submit(context.Background(), func(context.Context) error { return nil }); err != context.Canceled {
+		t.Fatalf("Submit to a canceled pool = %v, want %v", err, context.Canceled)
+	}
+	if err := p.Close(); err != context.Canceled {
+		t.Fatalf("Close = %v, want %v", err, context.Canceled)
+	}
+}
@@ -0,0 +1,290 @@
+// Package pubsub is an in-process message broker built on channels. Publishers send messages to a topic,
+// subscribers receive the messages of every topic matching their pattern on a channel of their own:
+//
+//	b := pubsub.New[Order](pubsub.Config{})
+//	sub, err := b.Subscribe("orders.*", pubsub.SubscriberConfig{Buffer: 100, Policy: pubsub.DropOldest})
+//	go func() {
+//		for m := range sub.C() {
+//			fmt.Println(m.Topic, m.Payload)
+//		}
+//	}()
+//	err = b.Publish(ctx, "orders.created", order)
+//	...
+//	b.Close(ctx) // delivers what is in flight, then closes every subscriber channel
+//
+// Every subscriber has a buffer of its own, and its Policy decides what happens once that is full:
+// wait for room (Block), or make room by dropping a message (DropOldest, DropNewest). So a slow subscriber
+// only slows down the publishers if it asked for it, and never the other subscribers of a dropping policy.
+package pubsub
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"sync"
+	"sync/atomic"
+)
+
+var (
+	// ErrClosed is returned by Publish and Subscribe once the broker is closed.
+	ErrClosed = errors.New("pubsub: broker closed")
+	// ErrTopic is wrapped by the errors for malformed topics and patterns.
+	ErrTopic = errors.New("pubsub: invalid topic")
+)
+
+// Policy decides what delivering to a subscriber with a full buffer does.
+type Policy int
+
+const (
+	// Block waits until the subscriber has room again, holding up Publish like a send on a plain channel.
+	Block Policy = iota
+	// DropOldest drops the oldest message in the buffer to make room for the new one.
+	DropOldest
+	// DropNewest drops the new message and keeps the buffer as it is.
+	DropNewest
+)
+
+func (p Policy) String() string {
+	switch p {
+	case Block:
+		return "block"
+	case DropOldest:
+		return "drop-oldest"
+	case DropNewest:
+		return "drop-newest"
+	}
+	return "unknown"
+}
+
+// Message is a payload along with the topic it was published to.
+type Message[T any] struct {
+	Topic   string
+	Payload T
+}
+
+// Config configures a Broker. Zero fields take the defaults given below.
+type Config struct {
+	// Buffer is the buffer size of subscribers that do not set their own. Defaults to 16.
+	Buffer int
+	// Metrics is told about every message published, delivered and dropped. Optional, see NewMetrics.
+	Metrics Metrics
+}
+
+// SubscriberConfig configures a single subscription.
+type SubscriberConfig struct {
+	// Buffer is how many messages can wait for the subscriber. Defaults to the Buffer of the broker.
+	Buffer int
+	// Policy decides what happens to messages once the buffer is full. Defaults to Block.
+	Policy Policy
+}
+
+// Broker routes published messages to the matching subscribers. Use New to create one.
+type Broker[T any] struct {
+	cfg   Config
+	abort chan struct{} // closed when Close gives up on blocked publishers
+
+	mu       sync.RWMutex
+	subs     []*Subscription[T]
+	closed   bool
+	inflight sync.WaitGroup // Publish calls that are delivering
+}
+
+// New returns a broker without subscribers.
+func New[T any](cfg Config) *Broker[T] {
+	if cfg.Buffer <= 0 {
+		cfg.Buffer = 16
+	}
+	if cfg.Metrics == nil {
+		cfg.Metrics = noMetrics{}
+	}
+	return &Broker[T]{cfg: cfg, abort: make(chan struct{})}
+}
+
+// Subscribe starts receiving the messages of every topic matching pattern, see the top of topic.go for the wildcards.
+// Messages are delivered to the subscription in the order they were published by any one goroutine.
+func (b *Broker[T]) Subscribe(pattern string, cfg SubscriberConfig) (*Subscription[T], error) {
+	words, err := parsePattern(pattern)
+	if err != nil {
+		return nil, err
+	}
+	if cfg.Buffer <= 0 {
+		cfg.Buffer = b.cfg.Buffer
+	}
+	s := &Subscription[T]{
+		b:       b,
+		pattern: pattern,
+		words:   words,
+		policy:  cfg.Policy,
+		ch:      make(chan Message[T], cfg.Buffer),
+		quit:    make(chan struct{}),
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.closed {
+		return nil, ErrClosed
+	}
+	b.subs = append(b.subs, s)
+	return s, nil
+}
+
+// Publish delivers payload to every subscriber matching topic, which must not contain wildcards.
+// It only waits for subscribers with the Block policy and a full buffer. If ctx is done meanwhile,
+// Publish gives up and returns the error of ctx, the message may then have reached some of the subscribers.
+func (b *Broker[T]) Publish(ctx context.Context, topic string, payload T) error {
+	words, err := parseTopic(topic)
+	if err != nil {
+		return err
+	}
+
+	b.mu.RLock()
+	if b.closed {
+		b.mu.RUnlock()
+		return ErrClosed
+	}
+	b.inflight.Add(1) // under the lock, so Close can not be waiting already
+	var matched []*Subscription[T]
+	for _, s := range b.subs {
+		if match(s.words, words) {
+			matched = append(matched, s)
+		}
+	}
+	b.mu.RUnlock()
+	defer b.inflight.Done()
+
+	b.cfg.Metrics.Published(topic)
+	m := Message[T]{Topic: topic, Payload: payload}
+	for _, s := range matched {
+		if err := s.deliver(ctx, m); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Close stops the broker gracefully: Publish and Subscribe fail with ErrClosed from now on, the Publish calls
+// already delivering get to finish, and then every subscriber channel is closed. Messages buffered by then
+// can still be read from it.
+// If ctx is done before the publishers finished, those still blocked give up with ErrClosed and Close returns
+// the error of ctx. Closing a closed broker does nothing.
+func (b *Broker[T]) Close(ctx context.Context) error {
+	b.mu.Lock()
+	if b.closed {
+		b.mu.Unlock()
+		return nil
+	}
+	b.closed = true
+	subs := b.subs
+	b.subs = nil
+	b.mu.Unlock()
+
+	finished := make(chan struct{})
+	go func() {
+		b.inflight.Wait()
+		close(finished)
+	}()
+	var err error
+	select {
+	case <-finished:
+	case <-ctx.Done():
+		err = ctx.Err()
+		close(b.abort)
+		<-finished
+	}
+	for _, s := range subs {
+		s.close()
+	}
+	return err
+}
+
+// Subscription is a single subscriber of a Broker. Read its messages from C until it is closed.
+type Subscription[T any] struct {
+	b       *Broker[T]
+	pattern string
+	words   []string
+	policy  Policy
+	ch      chan Message[T]
+	quit    chan struct{} // closed first on Unsubscribe, to release a delivery blocked on a full buffer
+	once    sync.Once
+	dropped atomic.Int64
+
+	mu     sync.Mutex // taken by deliveries, so that close never closes ch under a send
+	closed bool
+}
+
+// C returns the channel the messages arrive on. It is closed after Unsubscribe or Close of the broker,
+// messages buffered by then can still be read from it.
+func (s *Subscription[T]) C() <-chan Message[T] { return s.ch }
+
+// Pattern returns the pattern s subscribed to.
+func (s *Subscription[T]) Pattern() string { return s.pattern }
+
+// Dropped returns how many messages s lost to its policy.
+func (s *Subscription[T]) Dropped() int64 { return s.dropped.Load() }
+
+// Unsubscribe stops the delivery of new messages and closes the channel of s. It may be called more than once.
+func (s *Subscription[T]) Unsubscribe() {
+	s.b.mu.Lock()
+	if i := slices.Index(s.b.subs, s); i >= 0 {
+		s.b.subs = slices.Delete(s.b.subs, i, i+1)
+	}
+	s.b.mu.Unlock()
+	s.close()
+}
+
+func (s *Subscription[T]) close() {
+	s.once.Do(func() {
+		close(s.quit)
+		s.mu.Lock()
+		s.closed = true
+		close(s.ch)
+		s.mu.Unlock()
+	})
+}
+
+// deliver queues m for s according to its policy. It only returns an error if ctx was done while blocked.
+func (s *Subscription[T]) deliver(ctx context.Context, m Message[T]) error {
+	hook := s.b.cfg.Metrics
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.closed {
+		return nil
+	}
+
+	switch s.policy {
+	case DropNewest:
+		select {
+		case s.ch <- m:
+			hook.Delivered(m.Topic)
+		default:
+			s.dropped.Add(1)
+			hook.Dropped(m.Topic)
+		}
+	case DropOldest:
+		for {
+			select {
+			case s.ch <- m:
+				hook.Delivered(m.Topic)
+				return nil
+			default:
+			}
+			// Full: take out the oldest message, unless the subscriber just read it, and try again.
+			select {
+			case old := <-s.ch:
+				s.dropped.Add(1)
+				hook.Dropped(old.Topic)
+			default:
+			}
+		}
+	default:
+		select {
+		case s.ch <- m:
+			hook.Delivered(m.Topic)
+		case <-s.quit:
+		case <-s.b.abort:
+			return ErrClosed
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+	return nil
+}
@@ -0,0 +1,40 @@
+package pubsub
+
+import "github.com/ysyesilyurt/lets-go/bootstrap/stages/go_rulez/metrics"
+
+// Metrics is told what happens to the messages of a Broker. It is called on the hot path of Publish,
+// Delivered and Dropped even under the lock of the subscriber, so it must be fast, safe for concurrent use
+// and must not call back into the broker.
+type Metrics interface {
+	// Published is called once for every message published to topic, whether it matched any subscriber or not.
+	Published(topic string)
+	// Delivered is called for every subscriber a message of topic was queued for.
+	Delivered(topic string)
+	// Dropped is called for every message of topic a subscriber lost to its policy.
+	Dropped(topic string)
+}
+
+type noMetrics struct{}
+
+func (noMetrics) Published(string) {}
+func (noMetrics) Delivered(string) {}
+func (noMetrics) Dropped(string)   {}
+
+// NewMetrics returns Metrics counting messages by topic in three counters of r:
+// <prefix>_published_total, <prefix>_delivered_total and <prefix>_dropped_total.
+// Every topic becomes a label value, so this suits brokers with a known, small set of topics.
+func NewMetrics(r *metrics.Registry, prefix string) Metrics {
+	return registryMetrics{
+		published: r.NewCounter(prefix+"_published_total", "Messages published, by topic.", "topic"),
+		delivered: r.NewCounter(prefix+"_delivered_total", "Messages queued for a subscriber, by topic.", "topic"),
+		dropped:   r.NewCounter(prefix+"_dropped_total", "Messages a subscriber lost to its policy, by topic.", "topic"),
+	}
+}
+
+type registryMetrics struct {
+	published, delivered, dropped *metrics.Counter
+}
+
+func (m registryMetrics) Published(topic string) { m.published.Inc(topic) }
+func (m registryMetrics) Delivered(topic string) { m.delivered.Inc(topic) }
+func (m registryMetrics) Dropped(topic string)   { m.dropped.Inc(topic) }
@@ -0,0 +1,222 @@
+package pubsub
+
+import (
+	"context"
+	"errors"
+	"net/http/httptest"
+	"slices"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/ysyesilyurt/lets-go/bootstrap/stages/go_rulez/leakcheck"
+	"github.com/ysyesilyurt/lets-go/bootstrap/stages/go_rulez/metrics"
+)
+
+// payloads reads the messages buffered for s.
+func payloads(s *Subscription[int]) []int {
+	var got []int
+	for {
+		select {
+		case m, ok := <-s.C():
+			if !ok {
+				return got
+			}
+			got = append(got, m.Payload)
+		default:
+			return got
+		}
+	}
+}
+
+// blocked publishes payload to topic in a goroutine and returns the error of Publish once it returns.
+// It fails the test if Publish does not block first.
+func blocked(t *testing.T, b *Broker[int], topic string, payload int) <-chan error {
+	t.Helper()
+	errc := make(chan error, 1)
+	go func() { errc <- b.Publish(context.Background(), topic, payload) }()
+	select {
+	case err := <-errc:
+		t.Fatalf("Publish returned %v, want it to block on the full buffer", err)
+	case <-time.After(20 * time.Millisecond):
+	}
+	return errc
+}
+
+func TestPolicies(t *testing.T) {
+	/* a buffer of 2 and 4 messages: the two that do not fit are dropped, or Publish has to wait */
+	for _, tt := range []struct {
+		policy  Policy
+		want    []int
+		dropped int64
+	}{
+		{DropNewest, []int{1, 2}, 2},
+		{DropOldest, []int{3, 4}, 2},
+		{Block, []int{1, 2}, 0},
+	} {
+		b := New[int](Config{})
+		s, _ := b.Subscribe("t", SubscriberConfig{Buffer: 2, Policy: tt.policy})
+		for i := 1; i <= 4; i++ {
+			ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+			err := b.Publish(ctx, "t", i)
+			cancel()
+			if tt.policy == Block && i > 2 {
+				if err != context.DeadlineExceeded {
+					t.Errorf("%v: Publish to a full buffer = %v, want it to wait until its context is done", tt.policy, err)
+				}
+			} else if err != nil {
+				t.Errorf("%v: Publish = %v", tt.policy, err)
+			}
+		}
+		if got := payloads(s); !slices.Equal(got, tt.want) || s.Dropped() != tt.dropped {
+			t.Errorf("%v: got %v with %d dropped, want %v with %d dropped", tt.policy, got, s.Dropped(), tt.want, tt.dropped)
+		}
+	}
+}
+
+func TestSlowSubscriberOnlyHoldsUpItself(t *testing.T) {
+	b := New[int](Config{Buffer: 1})
+	slow, _ := b.Subscribe("#", SubscriberConfig{Policy: DropNewest})
+	fast, _ := b.Subscribe("t.*", SubscriberConfig{Buffer: 10})
+	other, _ := b.Subscribe("u", SubscriberConfig{})
+	for i := range 5 {
+		if err := b.Publish(context.Background(), "t.x", i); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if got := payloads(fast); !slices.Equal(got, []int{0, 1, 2, 3, 4}) {
+		t.Fatalf("the fast subscriber got %v", got)
+	}
+	if got := payloads(slow); !slices.Equal(got, []int{0}) || slow.Dropped() != 4 {
+		t.Fatalf("the slow subscriber got %v with %d dropped", got, slow.Dropped())
+	}
+	if got := payloads(other); len(got) != 0 {
+		t.Fatalf("a subscriber of another topic got %v", got)
+	}
+}
+
+func TestUnsubscribeReleasesBlockedPublisher(t *testing.T) {
+	leakcheck.Check(t, leakcheck.Config{})
+	b := New[int](Config{})
+	s, _ := b.Subscribe("t", SubscriberConfig{Buffer: 1, Policy: Block})
+	b.Publish(context.Background(), "t", 1)
+	errc := blocked(t, b, "t", 2)
+
+	s.Unsubscribe()
+	if err := <-errc; err != nil {
+		t.Fatalf("the released Publish returned %v, want nil", err)
+	}
+	s.Unsubscribe()
+	if got := payloads(s); !slices.Equal(got, []int{1}) {
+		t.Fatalf("got %v from the closed subscription, want what was buffered, [1]", got)
+	}
+	if _, ok := <-s.C(); ok {
+		t.Fatal("the channel of an unsubscribed subscription is still open")
+	}
+	if err := b.Publish(context.Background(), "t", 3); err != nil {
+		t.Fatalf("Publish without subscribers = %v", err)
+	}
+}
+
+func TestClose(t *testing.T) {
+	leakcheck.Check(t, leakcheck.Config{})
+
+	/* a graceful Close keeps what is buffered readable */
+	b := New[int](Config{})
+	s, _ := b.Subscribe("#", SubscriberConfig{})
+	b.Publish(context.Background(), "t", 1)
+	b.Publish(context.Background(), "u", 2)
+	if err := b.Close(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if got := payloads(s); !slices.Equal(got, []int{1, 2}) {
+		t.Fatalf("got %v after Close, want [1 2]", got)
+	}
+	if _, ok := <-s.C(); ok {
+		t.Fatal("Close left the subscriber channel open")
+	}
+	if err := b.Publish(context.Background(), "t", 3); err != ErrClosed {
+		t.Fatalf("Publish after Close = %v, want ErrClosed", err)
+	}
+	if _, err := b.Subscribe("t", SubscriberConfig{}); err != ErrClosed {
+		t.Fatalf("Subscribe after Close = %v, want ErrClosed", err)
+	}
+	if err := b.Close(context.Background()); err != nil {
+		t.Fatalf("closing twice = %v", err)
+	}
+
+	/* Close gives up on a publisher blocked for longer than its context, which then fails with ErrClosed */
+	b = New[int](Config{})
+	s, _ = b.Subscribe("t", SubscriberConfig{Buffer: 1, Policy: Block})
+	b.Publish(context.Background(), "t", 1)
+	errc := blocked(t, b, "t", 2)
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	if err := b.Close(ctx); err != context.DeadlineExceeded {
+		t.Fatalf("Close = %v, want %v", err, context.DeadlineExceeded)
+	}
+	if err := <-errc; err != ErrClosed {
+		t.Fatalf("the blocked Publish returned %v, want ErrClosed", err)
+	}
+	if got := payloads(s); !slices.Equal(got, []int{1}) {
+		t.Fatalf("got %v, want [1]", got)
+	}
+}
+
+// counts is Metrics counting every call by topic.
+type counts struct {
+	mu                            sync.Mutex
+	published, delivered, dropped map[string]int
+}
+
+func (c *counts) inc(m *map[string]int, topic string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if *m == nil {
+		*m = make(map[string]int)
+	}
+	(*m)[topic]++
+}
+
+func (c *counts) Published(topic string) { c.inc(&c.published, topic) }
+func (c *counts) Delivered(topic string) { c.inc(&c.delivered, topic) }
+func (c *counts) Dropped(topic string)   { c.inc(&c.dropped, topic) }
+
+func TestMetrics(t *testing.T) {
+	c := &counts{}
+	b := New[int](Config{Buffer: 1, Metrics: c})
+	b.Subscribe("t", SubscriberConfig{Policy: DropNewest})
+	b.Subscribe("#", SubscriberConfig{Policy: DropOldest})
+	for i := range 3 {
+		b.Publish(context.Background(), "t", i)
+	}
+	b.Publish(context.Background(), "u", 3)
+
+	/* t: 3 published, 1 + 3 delivered, 2 + 2 dropped; u: only the # subscriber gets it, dropping the last t */
+	if c.published["t"] != 3 || c.published["u"] != 1 || c.delivered["t"] != 4 || c.delivered["u"] != 1 || c.dropped["t"] != 5 || c.dropped["u"] != 0 {
+		t.Fatalf("published %v, delivered %v, dropped %v", c.published, c.delivered, c.dropped)
+	}
+
+	r := metrics.NewRegistry()
+	b = New[int](Config{Metrics: NewMetrics(r, "broker")})
+	b.Subscribe("t", SubscriberConfig{})
+	b.Publish(context.Background(), "t", 1)
+	rec := httptest.NewRecorder()
+	r.ServeHTTP(rec, httptest.NewRequest("GET", "/metrics", nil))
+	for _, want := range []string{`broker_published_total{topic="t"} 1`, `broker_delivered_total{topic="t"} 1`} {
+		if !strings.Contains(rec.Body.String(), want) {
+			t.Fatalf("exposition\n%s\nlacks %s", rec.Body, want)
+		}
+	}
+}
+
+func TestBadTopics(t *testing.T) {
+	b := New[int](Config{})
+	if err := b.Publish(context.Background(), "orders.*", 1); !errors.Is(err, ErrTopic) {
+		t.Fatalf("Publish to a pattern = %v, want ErrTopic", err)
+	}
+	if _, err := b.Subscribe("orders.#x", SubscriberConfig{}); !errors.Is(err, ErrTopic) {
+		t.Fatalf("Subscribe to a malformed pattern = %v, want ErrTopic", err)
+	}
+}
@@ -0,0 +1,70 @@
+package pubsub
+
+import (
+	"fmt"
+	"strings"
+)
+
+/*
+	Topics and patterns.
+
+	A topic is a dot separated list of words, such as "orders.created" or "payments.eu.refunded".
+	Subscriptions name a pattern instead, a topic that may use two wildcards in place of whole words:
+		* '*' matches exactly one word:     "orders.*" matches "orders.created", but not "orders" or "orders.eu.created"
+		* '#' matches zero or more words:   "orders.#" matches "orders", "orders.created" and "orders.eu.created"
+	so "#" alone matches every topic. These are the rules of AMQP topic exchanges, which many of us know from RabbitMQ.
+*/
+
+// parseTopic splits topic into its words and checks that it can be published to.
+func parseTopic(topic string) ([]string, error) {
+	words := strings.Split(topic, ".")
+	for _, w := range words {
+		switch {
+		case w == "":
+			return nil, fmt.Errorf("%w %q: empty word", ErrTopic, topic)
+		case strings.ContainsAny(w, "*#"):
+			return nil, fmt.Errorf("%w %q: wildcards are only allowed in subscriptions", ErrTopic, topic)
+		}
+	}
+	return words, nil
+}
+
+// parsePattern splits pattern into its words and checks that wildcards stand for whole words.
+func parsePattern(pattern string) ([]string, error) {
+	words := strings.Split(pattern, ".")
+	for _, w := range words {
+		switch {
+		case w == "":
+			return nil, fmt.Errorf("%w %q: empty word", ErrTopic, pattern)
+		case w != "*" && w != "#" && strings.ContainsAny(w, "*#"):
+			return nil, fmt.Errorf("%w %q: a wildcard must be a word of its own", ErrTopic, pattern)
+		}
+	}
+	return words, nil
+}
+
+// match reports whether the words of a topic match the words of a pattern.
+func match(pattern, topic []string) bool {
+	for len(pattern) > 0 {
+		switch pattern[0] {
+		case "#":
+			// Let # take no word, one word, two words... and see whether the rest of the pattern matches what is left.
+			for i := 0; i <= len(topic); i++ {
+				if match(pattern[1:], topic[i:]) {
+					return true
+				}
+			}
+			return false
+		case "*":
+			if len(topic) == 0 {
+				return false
+			}
+		default:
+			if len(topic) == 0 || topic[0] != pattern[0] {
+				return false
+			}
+		}
+		pattern, topic = pattern[1:], topic[1:]
+	}
+	return len(topic) == 0
+}
@@ -0,0 +1,59 @@
+package pubsub
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestMatch(t *testing.T) {
+	tests := []struct {
+		pattern, topic string
+		want           bool
+	}{
+		{"orders.created", "orders.created", true},
+		{"orders.created", "orders.refunded", false},
+		{"orders.created", "orders", false},
+		{"*", "a", true},
+		{"*", "a.b", false},
+		{"orders.*", "orders.created", true},
+		{"orders.*", "orders", false},
+		{"orders.*", "orders.eu.created", false},
+		{"*.*", "a", false},
+		{"*.created", "orders.created", true},
+		{"#", "a", true},
+		{"#", "a.b.c", true},
+		{"#.#", "a", true},
+		{"orders.#", "orders", true},
+		{"orders.#", "orders.created", true},
+		{"orders.#", "orders.eu.created", true},
+		{"orders.#", "payments.created", false},
+		{"#.refunded", "refunded", true},
+		{"#.refunded", "payments.eu.refunded", true},
+		{"#.refunded", "payments.refunded.late", false},
+		{"a.#.b", "a.b", true},
+		{"a.#.b", "a.x.y.b", true},
+		{"a.#.b", "a.x.b.c", false},
+		{"a.*.#", "a", false},
+		{"a.*.#", "a.b", true},
+		{"#.*", "a.b.c", true},
+	}
+	for _, tt := range tests {
+		if got := match(strings.Split(tt.pattern, "."), strings.Split(tt.topic, ".")); got != tt.want {
+			t.Errorf("match(%q, %q) = %v, want %v", tt.pattern, tt.topic, got, tt.want)
+		}
+	}
+}
+
+func TestMalformedTopics(t *testing.T) {
+	for _, topic := range []string{"", "a..b", "a.", "a.*", "#", "a.b#"} {
+		if _, err := parseTopic(topic); !errors.Is(err, ErrTopic) {
+			t.Errorf("topic %q: %v, want ErrTopic", topic, err)
+		}
+	}
+	for _, pattern := range []string{"", ".a", "a.b*", "#x", "a.**"} {
+		if _, err := parsePattern(pattern); !errors.Is(err, ErrTopic) {
+			t.Errorf("pattern %q: %v, want ErrTopic", pattern, err)
+		}
+	}
+}
@@ -0,0 +1,91 @@
+package main
+
+/* Publish/subscribe
+Instead of handing channels around by hand as in channels() of go_rulez.go, the parts of a service can talk through
+a pubsub.Broker: publishers name a topic such as "orders.eu.created", subscribers a pattern such as "orders.*.created"
+or "orders.#" and get the matching messages on a channel of their own.
+Each subscriber picks what happens when it falls behind and its buffer fills up:
+	* Block -> Publish waits for it, nothing is lost
+	* DropOldest -> the oldest buffered message makes room, good for "latest state" consumers
+	* DropNewest -> the new message is dropped, the buffered ones are kept
+The broker reports what it published, delivered and dropped per topic to our metrics registry.
+*/
+
+import (
+	"context"
+	"fmt"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/ysyesilyurt/lets-go/bootstrap/stages/go_rulez/metrics"
+	"github.com/ysyesilyurt/lets-go/bootstrap/stages/go_rulez/pubsub"
+)
+
+type order struct {
+	ID     int
+	Amount int
+}
+
+func main() {
+	registry := metrics.NewRegistry()
+	b := pubsub.New[order](pubsub.Config{Buffer: 4, Metrics: pubsub.NewMetrics(registry, "events")})
+
+	var wg sync.WaitGroup
+	subscribe := func(name, pattern string, policy pubsub.Policy, perMessage time.Duration) {
+		sub, err := b.Subscribe(pattern, pubsub.SubscriberConfig{Policy: policy})
+		if err != nil {
+			fmt.Println(name, "could not subscribe:", err) // don't ignore errors
+			return
+		}
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			var got []int
+			for m := range sub.C() {
+				time.Sleep(perMessage) // a consumer that does some work per message
+				got = append(got, m.Payload.ID)
+			}
+			fmt.Printf("%-9s %-18s %-11v got %2d, dropped %2d: %v\n", name, pattern, policy, len(got), sub.Dropped(), got)
+		}()
+	}
+	subscribe("billing", "orders.*.created", pubsub.Block, time.Millisecond)
+	subscribe("dashboard", "orders.#", pubsub.DropOldest, 5*time.Millisecond)
+	subscribe("mailer", "orders.eu.*", pubsub.DropNewest, 5*time.Millisecond)
+	subscribe("audit", "#", pubsub.Block, 0)
+
+	ctx := context.Background()
+	regions := []string{"eu", "us"}
+	for id := 1; id <= 20; id++ {
+		topic := fmt.Sprintf("orders.%s.created", regions[id%2])
+		if id%5 == 0 {
+			topic = fmt.Sprintf("orders.%s.refunded", regions[id%2])
+		}
+		if err := b.Publish(ctx, topic, order{ID: id, Amount: 10 * id}); err != nil {
+			fmt.Println("could not publish:", err)
+		}
+	}
+	if err := b.Publish(ctx, "orders.*", order{}); err != nil {
+		fmt.Println("publishing to a pattern:", err)
+	}
+
+	/* Close lets the subscribers read what is buffered, then their channels are closed and their loops end */
+	closeCtx, cancel := context.WithTimeout(ctx, time.Second)
+	defer cancel()
+	if err := b.Close(closeCtx); err != nil {
+		fmt.Println("could not close the broker in time:", err)
+	}
+	wg.Wait()
+	fmt.Println("publishing after Close:", b.Publish(ctx, "orders.eu.created", order{}))
+
+	fmt.Println()
+	rec := httptest.NewRecorder()
+	registry.ServeHTTP(rec, httptest.NewRequest("GET", "/metrics", nil))
+	for _, line := range strings.SplitAfter(rec.Body.String(), "\n") {
+		if !strings.HasPrefix(line, "#") {
+			os.Stdout.WriteString(line)
+		}
+	}
+}
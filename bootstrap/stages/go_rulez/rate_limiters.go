@@ -0,0 +1,99 @@
+package main
+
+/* Rate limiting
+Three ways to say "at most 5 requests a second" (see ratelimit/):
+	* a token bucket lets a burst of 5 through at once and then one every 200ms
+	* a leaky bucket lets them through one every 200ms, never two at once, and queues a few of the rest
+	* a sliding log lets any 5 through as long as fewer than 5 went in the last second
+All of them run on a clock.Fake here, so the seconds below pass instantly and the output is always the same.
+*/
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/ysyesilyurt/lets-go/bootstrap/stages/go_rulez/clock"
+	"github.com/ysyesilyurt/lets-go/bootstrap/stages/go_rulez/ratelimit"
+)
+
+// timeline asks lim.Allow 10 times at once, and then once every 100ms for 1.5 seconds,
+// and draws the answers: # allowed, . rejected, | every full second.
+func timeline(clk *clock.Fake, lim ratelimit.Limiter) string {
+	var b strings.Builder
+	for i := 0; i < 10; i++ {
+		b.WriteString(map[bool]string{true: "#", false: "."}[lim.Allow()])
+	}
+	b.WriteString(" ")
+	for i := 1; i <= 15; i++ {
+		clk.Advance(100 * time.Millisecond)
+		b.WriteString(map[bool]string{true: "#", false: "."}[lim.Allow()])
+		if i%10 == 0 {
+			b.WriteString("|")
+		}
+	}
+	return b.String()
+}
+
+func main() {
+	start := time.Date(2021, 1, 1, 12, 0, 0, 0, time.UTC)
+	limiters := []struct {
+		name string
+		new  func(clk clock.Clock) ratelimit.Limiter
+	}{
+		{"token bucket", func(clk clock.Clock) ratelimit.Limiter {
+			return ratelimit.NewTokenBucket(ratelimit.TokenBucketConfig{Rate: 5, Burst: 5, Clock: clk})
+		}},
+		{"leaky bucket", func(clk clock.Clock) ratelimit.Limiter {
+			return ratelimit.NewLeakyBucket(ratelimit.LeakyBucketConfig{Rate: 5, Queue: 3, Clock: clk})
+		}},
+		{"sliding log", func(clk clock.Clock) ratelimit.Limiter {
+			return ratelimit.NewSlidingLog(ratelimit.SlidingLogConfig{Limit: 5, Window: time.Second, Clock: clk})
+		}},
+	}
+
+	/* Allow: now or never */
+	fmt.Println("Allow, 10 at once and then one every 100ms:")
+	for _, l := range limiters {
+		clk := clock.NewFake(start)
+		fmt.Printf("  %-12s %s\n", l.name, timeline(clk, l.new(clk)))
+	}
+
+	/* Reserve: everyone gets a turn, some of them later. The leaky bucket turns away those that do not fit its queue */
+	fmt.Println("\nReserve, 8 at once:")
+	for _, l := range limiters {
+		lim := l.new(clock.NewFake(start))
+		var turns []string
+		for i := 0; i < 8; i++ {
+			if r := lim.Reserve(); r.OK() {
+				turns = append(turns, fmt.Sprint(r.Delay()))
+			} else {
+				turns = append(turns, "full")
+			}
+		}
+		fmt.Printf("  %-12s %s\n", l.name, strings.Join(turns, " "))
+	}
+
+	/* Wait: a goroutine blocks until its turn, or until its context is canceled, giving the turn back */
+	fmt.Println("\nWait:")
+	clk := clock.NewFake(start)
+	lim := ratelimit.NewTokenBucket(ratelimit.TokenBucketConfig{Rate: 5, Clock: clk})
+	lim.Allow() // take the only token
+
+	waited := make(chan time.Time)
+	go func() {
+		lim.Wait(context.Background())
+		waited <- clk.Now()
+	}()
+	clk.BlockUntil(1) // the goroutine is waiting on the clock now
+	clk.Advance(200 * time.Millisecond)
+	fmt.Println("  got through after", (<-waited).Sub(start))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	errs := make(chan error)
+	go func() { errs <- lim.Wait(ctx) }()
+	clk.BlockUntil(1)
+	cancel()
+	fmt.Println("  gave up:", <-errs, "- tokens left afterwards:", lim.Tokens())
+}
@@ -0,0 +1,44 @@
+package main
+
+/* Rate tracking
+counter.Window answers "how many in the last minute" with a ring of time buckets per key,
+counter.Expiring is a SafeCounter that forgets keys nobody touched for a while.
+Both drop idle keys with a janitor goroutine and take their time from a clock.Clock,
+so with a clock.Fake we can fast forward through minutes instantly.
+*/
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/ysyesilyurt/lets-go/bootstrap/stages/go_rulez/clock"
+	"github.com/ysyesilyurt/lets-go/bootstrap/stages/go_rulez/counter"
+)
+
+func main() {
+	clk := clock.NewFake(time.Date(2021, 1, 1, 12, 0, 0, 0, time.UTC))
+	requests := counter.NewWindow(counter.WindowConfig{Window: time.Minute, Buckets: 6, Clock: clk})
+	defer requests.Close()
+	logins := counter.NewExpiring(5*time.Minute, clk)
+	defer logins.Close()
+
+	/* one request every 5 seconds for two minutes, and one login a minute */
+	for i := 0; i < 24; i++ {
+		requests.Inc("/pairs")
+		if i%12 == 0 {
+			logins.Inc("yavuz")
+		}
+		clk.Advance(5 * time.Second)
+	}
+	fmt.Println("requests in the last minute:", requests.Value("/pairs"))
+	fmt.Println("logins so far:", logins.Value("yavuz"))
+
+	clk.Advance(30 * time.Second)
+	fmt.Println("30 seconds of silence later:", requests.Value("/pairs"))
+	clk.Advance(time.Minute)
+	fmt.Println("another minute later:", requests.Value("/pairs"))
+
+	clk.Advance(5 * time.Minute)
+	logins.Sweep() // the janitor would get to it too, this just does not wait for it
+	fmt.Println("logins after 5 idle minutes:", logins.Value("yavuz"), "keys left:", logins.Len())
+}
@@ -0,0 +1,81 @@
+package ratelimit
+
+import (
+	"time"
+
+	"github.com/ysyesilyurt/lets-go/bootstrap/stages/go_rulez/clock"
+)
+
+/*
+	Leaky bucket.
+
+	Requests drip into a bucket with room for Queue of them, and leak out of a hole in its bottom at Rate per second.
+	Unlike a token bucket there are no bursts: however many arrive at once, they leave one every 1/Rate seconds,
+	and whatever does not fit into the bucket anymore is turned away. It shapes traffic into an even flow,
+	for a downstream that can not take bursts at all.
+
+	We do not need an actual queue for that, next is the earliest turn nobody took yet.
+	Reserve hands it out and moves it 1/Rate on, unless it is more than Queue turns away.
+*/
+
+// LeakyBucketConfig configures a LeakyBucket, only Rate is required.
+type LeakyBucketConfig struct {
+	Rate  float64     // requests let through per second
+	Queue int         // how many requests may wait for their turn, 0 for none
+	Clock clock.Clock // defaults to clock.Real()
+}
+
+// LeakyBucket is a Limiter letting requests through at an even pace. Use NewLeakyBucket to create one.
+type LeakyBucket struct {
+	core
+	per   time.Duration // between two requests
+	queue int
+	next  time.Time
+}
+
+// NewLeakyBucket returns an empty leaky bucket. It panics if Rate is not positive.
+func NewLeakyBucket(cfg LeakyBucketConfig) *LeakyBucket {
+	b := &LeakyBucket{per: interval(cfg.Rate), queue: max(cfg.Queue, 0)}
+	b.core = core{clock: defaultClock(cfg.Clock), policy: b}
+	return b
+}
+
+// Queued returns how many requests are waiting for their turn right now.
+func (b *LeakyBucket) Queued() int {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	// the turns handed out are next-per, next-2*per..., the waiting ones are those still after now
+	ahead := b.next.Sub(b.clock.Now())
+	if ahead <= 0 {
+		return 0
+	}
+	return int((ahead+b.per-1)/b.per) - 1
+}
+
+func (b *LeakyBucket) allow(now time.Time) bool {
+	if b.next.After(now) {
+		return false
+	}
+	b.next = now.Add(b.per)
+	return true
+}
+
+func (b *LeakyBucket) reserve(now time.Time) (time.Time, bool) {
+	at := b.next
+	if at.Before(now) {
+		at = now
+	}
+	if at.Sub(now) > time.Duration(b.queue)*b.per {
+		return time.Time{}, false
+	}
+	b.next = at.Add(b.per)
+	return at, true
+}
+
+// unreserve only takes back the last turn handed out. An earlier one is lost, handing it out again would let
+// two requests through at once.
+func (b *LeakyBucket) unreserve(now, at time.Time) {
+	if b.next.Equal(at.Add(b.per)) {
+		b.next = at
+	}
+}
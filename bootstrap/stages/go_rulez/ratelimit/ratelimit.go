@@ -0,0 +1,146 @@
+// Package ratelimit holds three rate limiters, all taking their time from a clock.Clock:
+//
+//   - TokenBucket allows bursts of up to Burst requests and Rate requests per second on average
+//   - LeakyBucket lets requests through at exactly Rate per second, queueing up to Queue of them
+//   - SlidingLog allows at most Limit requests in any Window of time, without the edges of fixed windows
+//
+// Each of them can be asked in three ways:
+//
+//	if lim.Allow() { ... }               // now or never, for requests that are dropped when over the limit
+//	err := lim.Wait(ctx)                 // block until it is our turn, for work that must be done anyway
+//	r := lim.Reserve()                   // take a turn now and learn how long to wait for it
+//	if r.OK() { time.Sleep(r.Delay()) }
+//
+// A Wait or a Reservation that is given up on returns its turn, so the next request gets it.
+// With a clock.Fake in place of the real clock a test can run through hours of traffic instantly.
+package ratelimit
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/ysyesilyurt/lets-go/bootstrap/stages/go_rulez/clock"
+)
+
+// ErrQueueFull is returned by Wait when the limiter can not even queue the request, see LeakyBucket.
+var ErrQueueFull = errors.New("ratelimit: queue is full")
+
+// Limiter is implemented by TokenBucket, LeakyBucket and SlidingLog. They are safe for concurrent use.
+type Limiter interface {
+	// Allow reports whether a request may go right now, and if so takes its turn.
+	Allow() bool
+	// Wait blocks until a request may go, or until ctx is done and returns its error.
+	Wait(ctx context.Context) error
+	// Reserve takes the next turn of a request, which may be in the future.
+	Reserve() *Reservation
+}
+
+// policy is what sets the limiters apart, core calls it with its lock held.
+type policy interface {
+	// allow takes a turn at now if there is one.
+	allow(now time.Time) bool
+	// reserve takes the next turn, at now or later, unless there is none to be had.
+	reserve(now time.Time) (at time.Time, ok bool)
+	// unreserve gives back a turn at that was reserved but not used.
+	unreserve(now, at time.Time)
+}
+
+// core implements Limiter on top of a policy, every limiter embeds one.
+type core struct {
+	mu     sync.Mutex
+	clock  clock.Clock
+	policy policy
+}
+
+// Allow reports whether a request may go right now, and if so takes its turn.
+func (c *core) Allow() bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.policy.allow(c.clock.Now())
+}
+
+// Reserve takes the next turn of a request. Check OK before anything else, then wait Delay before going ahead,
+// or Cancel the reservation to hand the turn to the next request.
+func (c *core) Reserve() *Reservation {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	at, ok := c.policy.reserve(c.clock.Now())
+	return &Reservation{c: c, ok: ok, at: at}
+}
+
+// Wait blocks until a request may go. If ctx is done first, the turn is returned and Wait returns the error of ctx.
+// It returns ErrQueueFull right away if there is no turn to wait for.
+func (c *core) Wait(ctx context.Context) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	r := c.Reserve()
+	if !r.OK() {
+		return ErrQueueFull
+	}
+	d := r.Delay()
+	if d == 0 {
+		return nil
+	}
+	t := c.clock.NewTimer(d)
+	defer t.Stop() // or a Wait given up on leaves its timer pending on a clock.Fake
+	select {
+	case <-t.C():
+		return nil
+	case <-ctx.Done():
+		r.Cancel()
+		return ctx.Err()
+	}
+}
+
+// Reservation is a turn taken by Reserve.
+type Reservation struct {
+	c        *core
+	ok       bool
+	at       time.Time
+	canceled bool // guarded by c.mu
+}
+
+// OK reports whether the limiter could give a turn at all. If not, Delay and Cancel are meaningless.
+func (r *Reservation) OK() bool { return r.ok }
+
+// Time returns when the turn is.
+func (r *Reservation) Time() time.Time { return r.at }
+
+// Delay returns how long to wait until the turn, 0 once it has come.
+func (r *Reservation) Delay() time.Duration {
+	return max(r.at.Sub(r.c.clock.Now()), 0)
+}
+
+// Cancel gives the turn back to the limiter, so that the next request may go sooner. A turn that has come already
+// counts as used, cancelling it does nothing, and so does cancelling twice.
+func (r *Reservation) Cancel() {
+	if !r.ok {
+		return
+	}
+	r.c.mu.Lock()
+	defer r.c.mu.Unlock()
+	now := r.c.clock.Now()
+	if r.canceled || !r.at.After(now) {
+		return
+	}
+	r.canceled = true
+	r.c.policy.unreserve(now, r.at)
+}
+
+func defaultClock(c clock.Clock) clock.Clock {
+	if c == nil {
+		return clock.Real()
+	}
+	return c
+}
+
+// interval returns the time between two requests at rate requests per second.
+func interval(rate float64) time.Duration {
+	if rate <= 0 {
+		panic("ratelimit: Rate must be positive")
+	}
+	return max(time.Duration(float64(time.Second)/rate), 1)
+}
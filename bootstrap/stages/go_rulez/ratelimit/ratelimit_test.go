@@ -0,0 +1,202 @@
+package ratelimit
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/ysyesilyurt/lets-go/bootstrap/stages/go_rulez/clock"
+)
+
+var start = time.Date(2021, 1, 1, 0, 0, 0, 0, time.UTC)
+
+// allowed returns how many requests lim allows right now.
+func allowed(lim Limiter) int {
+	n := 0
+	for lim.Allow() {
+		n++
+	}
+	return n
+}
+
+func TestTokenBucket(t *testing.T) {
+	clk := clock.NewFake(start)
+	b := NewTokenBucket(TokenBucketConfig{Rate: 3, Burst: 5, Clock: clk})
+	if n := allowed(b); n != 5 {
+		t.Fatalf("a full bucket allowed %d, want the burst of 5", n)
+	}
+	clk.Advance(time.Second)
+	if n := allowed(b); n != 3 {
+		t.Fatalf("allowed %d a second later, want 3", n)
+	}
+
+	/* a token takes 333333333ns at 3 per second, counting in floats would lose one every now and then */
+	for i := range 3000 {
+		clk.Advance(time.Second / 3)
+		if !b.Allow() {
+			t.Fatalf("no token after %d thirds of a second", i+1)
+		}
+		clk.Advance(time.Nanosecond)
+	}
+	clk.Advance(time.Hour)
+	if b.Tokens() != 5 {
+		t.Fatalf("Tokens after an hour = %v, want no more than the burst", b.Tokens())
+	}
+
+	/* reservations take tokens that are not there yet, a canceled one gives its token back */
+	allowed(b)
+	r1, r2 := b.Reserve(), b.Reserve()
+	if r1.Delay() != time.Second/3 || r2.Delay() != 2*time.Second/3 || b.Tokens() != -2 {
+		t.Fatalf("reservations wait %v and %v with %v tokens, want 1/3s and 2/3s with -2", r1.Delay(), r2.Delay(), b.Tokens())
+	}
+	r2.Cancel()
+	r2.Cancel()
+	if r := b.Reserve(); r.Delay() != 2*time.Second/3 {
+		t.Fatalf("the next reservation waits %v, want the canceled turn in 2/3s", r.Delay())
+	}
+}
+
+func TestLeakyBucket(t *testing.T) {
+	clk := clock.NewFake(start)
+	b := NewLeakyBucket(LeakyBucketConfig{Rate: 10, Queue: 3, Clock: clk})
+	if n := allowed(b); n != 1 {
+		t.Fatalf("allowed %d at once, want 1", n)
+	}
+	var rs []*Reservation
+	for range 4 {
+		rs = append(rs, b.Reserve())
+	}
+	if !rs[2].OK() || rs[3].OK() || rs[2].Delay() != 300*time.Millisecond || b.Queued() != 3 {
+		t.Fatalf("third reservation waits %v, fourth OK %v, %d queued; want 300ms, false, 3", rs[2].Delay(), rs[3].OK(), b.Queued())
+	}
+
+	/* only the last turn can be given back, the gap of an earlier one is lost */
+	rs[0].Cancel()
+	if r := b.Reserve(); r.OK() {
+		t.Fatalf("got a turn in %v after canceling one in the middle of the queue", r.Delay())
+	}
+	rs[2].Cancel()
+	if r := b.Reserve(); r.Delay() != 300*time.Millisecond {
+		t.Fatalf("got a turn in %v, want the canceled last one in 300ms", r.Delay())
+	}
+
+	clk.Advance(time.Second)
+	if b.Queued() != 0 || !b.Allow() || b.Allow() {
+		t.Fatal("the bucket did not drain in a second")
+	}
+}
+
+func TestSlidingLog(t *testing.T) {
+	clk := clock.NewFake(start)
+	l := NewSlidingLog(SlidingLogConfig{Limit: 3, Window: time.Minute, Clock: clk})
+	l.Allow()
+	clk.Advance(20 * time.Second)
+	l.Allow()
+	l.Allow()
+	if l.Allow() {
+		t.Fatal("allowed a fourth request within the minute")
+	}
+	clk.Advance(40 * time.Second)
+	if !l.Allow() || l.Allow() {
+		t.Fatal("want exactly one more request once the first one slid out of the window")
+	}
+
+	/* the next turns come when the requests of 20s ago slide out; a reservation never goes before an earlier one */
+	r := l.Reserve()
+	r2 := l.Reserve()
+	if r.Delay() != 20*time.Second || r2.Delay() != 20*time.Second || l.Len() != 5 {
+		t.Fatalf("reservations wait %v and %v with %d logged, want 20s and 20s with 5", r.Delay(), r2.Delay(), l.Len())
+	}
+	r.Cancel()
+	if l.Len() != 4 {
+		t.Fatalf("%d logged after a cancel, want 4", l.Len())
+	}
+}
+
+// limiters of one request a second, or with a queue of one for the leaky bucket.
+var limiters = []struct {
+	name string
+	new  func(clock.Clock) Limiter
+}{
+	{"token bucket", func(clk clock.Clock) Limiter {
+		return NewTokenBucket(TokenBucketConfig{Rate: 1, Clock: clk})
+	}},
+	{"leaky bucket", func(clk clock.Clock) Limiter {
+		return NewLeakyBucket(LeakyBucketConfig{Rate: 1, Queue: 1, Clock: clk})
+	}},
+	{"sliding log", func(clk clock.Clock) Limiter {
+		return NewSlidingLog(SlidingLogConfig{Limit: 1, Window: time.Second, Clock: clk})
+	}},
+}
+
+func TestWait(t *testing.T) {
+	for _, l := range limiters {
+		clk := clock.NewFake(start)
+		lim := l.new(clk)
+		if err := lim.Wait(context.Background()); err != nil {
+			t.Fatalf("%s: first Wait = %v", l.name, err)
+		}
+
+		done := make(chan error)
+		go func() { done <- lim.Wait(context.Background()) }()
+		clk.BlockUntil(1)
+		clk.Advance(time.Second)
+		if err := <-done; err != nil {
+			t.Fatalf("%s: Wait = %v", l.name, err)
+		}
+
+		/* a canceled Wait hands its turn back: the next one is 1s away, not 2s */
+		ctx, cancel := context.WithCancel(context.Background())
+		go func() { done <- lim.Wait(ctx) }()
+		clk.BlockUntil(1)
+		cancel()
+		if err := <-done; err != context.Canceled {
+			t.Fatalf("%s: canceled Wait = %v", l.name, err)
+		}
+		if r := lim.Reserve(); r.Delay() != time.Second {
+			t.Fatalf("%s: the next turn is %v away, want 1s", l.name, r.Delay())
+		}
+	}
+
+	lb := NewLeakyBucket(LeakyBucketConfig{Rate: 1, Clock: clock.NewFake(start)})
+	lb.Allow()
+	if err := lb.Wait(context.Background()); err != ErrQueueFull {
+		t.Fatalf("Wait without room in the queue = %v, want %v", err, ErrQueueFull)
+	}
+}
+
+func TestCanceledWaitLeavesNoTimer(t *testing.T) {
+	clk := clock.NewFake(start)
+	lim := NewLeakyBucket(LeakyBucketConfig{Rate: 1, Queue: 10, Clock: clk})
+	lim.Allow()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error)
+	go func() { done <- lim.Wait(ctx) }()
+	clk.BlockUntil(1)
+	cancel()
+	<-done
+
+	/* BlockUntil counts the timers still pending: with the one of the canceled Wait among them,
+	it would return while only one goroutine waits */
+	go func() { done <- lim.Wait(context.Background()) }()
+	blocked := make(chan struct{})
+	go func() {
+		clk.BlockUntil(2)
+		close(blocked)
+	}()
+	select {
+	case <-blocked:
+		t.Fatal("BlockUntil(2) returned with one goroutine waiting, the canceled Wait left its timer behind")
+	case <-time.After(50 * time.Millisecond):
+	}
+	go func() { done <- lim.Wait(context.Background()) }()
+	<-blocked
+
+	clk.Advance(2 * time.Second)
+	for range 2 {
+		if err := <-done; err != nil {
+			t.Fatal(err)
+		}
+	}
+}
@@ -0,0 +1,92 @@
+package ratelimit
+
+import (
+	"slices"
+	"time"
+
+	"github.com/ysyesilyurt/lets-go/bootstrap/stages/go_rulez/clock"
+)
+
+/*
+	Sliding log.
+
+	"At most 100 requests a minute" counted per calendar minute lets 200 through within two seconds:
+	100 at 12:00:59 and 100 more at 12:01:00. counter.Window narrows that edge down to a bucket, the sliding log
+	gets rid of it: it remembers the time of every turn in the last Window, and a request may go if fewer than
+	Limit of them are left. Exact, for the price of Limit times per limiter.
+
+	Reservations log their turn too, which may be in the future: once Limit turns are logged, the next one is
+	Window after the Limit-th newest, when that one slides out of the window.
+*/
+
+// SlidingLogConfig configures a SlidingLog, both Limit and Window are required.
+type SlidingLogConfig struct {
+	Limit  int           // requests allowed in any Window
+	Window time.Duration // e.g. time.Minute
+	Clock  clock.Clock   // defaults to clock.Real()
+}
+
+// SlidingLog is a Limiter counting requests over a sliding window of time. Use NewSlidingLog to create one.
+type SlidingLog struct {
+	core
+	limit  int
+	window time.Duration
+	log    []time.Time // turns in the window or after it, oldest first
+}
+
+// NewSlidingLog returns an empty sliding log. It panics if Limit or Window is not positive.
+func NewSlidingLog(cfg SlidingLogConfig) *SlidingLog {
+	if cfg.Limit <= 0 || cfg.Window <= 0 {
+		panic("ratelimit: Limit and Window must be positive")
+	}
+	l := &SlidingLog{limit: cfg.Limit, window: cfg.Window, log: make([]time.Time, 0, cfg.Limit)}
+	l.core = core{clock: defaultClock(cfg.Clock), policy: l}
+	return l
+}
+
+// Len returns how many turns are logged in the current window, including those reserved for later.
+func (l *SlidingLog) Len() int {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.prune(l.clock.Now())
+	return len(l.log)
+}
+
+// prune drops the turns that slid out of the window.
+func (l *SlidingLog) prune(now time.Time) {
+	start := now.Add(-l.window)
+	i := 0
+	for i < len(l.log) && !l.log[i].After(start) {
+		i++
+	}
+	l.log = slices.Delete(l.log, 0, i)
+}
+
+func (l *SlidingLog) allow(now time.Time) bool {
+	l.prune(now)
+	// with turns reserved for later, a request now would jump the queue
+	if len(l.log) >= l.limit || len(l.log) > 0 && l.log[len(l.log)-1].After(now) {
+		return false
+	}
+	l.log = append(l.log, now)
+	return true
+}
+
+func (l *SlidingLog) reserve(now time.Time) (time.Time, bool) {
+	l.prune(now)
+	at := now
+	if n := len(l.log); n >= l.limit {
+		at = l.log[n-l.limit].Add(l.window)
+	}
+	if n := len(l.log); n > 0 && l.log[n-1].After(at) {
+		at = l.log[n-1] // never ahead of an earlier reservation
+	}
+	l.log = append(l.log, at)
+	return at, true
+}
+
+func (l *SlidingLog) unreserve(now, at time.Time) {
+	if i := slices.IndexFunc(l.log, at.Equal); i >= 0 {
+		l.log = slices.Delete(l.log, i, i+1)
+	}
+}
@@ -0,0 +1,82 @@
+package ratelimit
+
+import (
+	"time"
+
+	"github.com/ysyesilyurt/lets-go/bootstrap/stages/go_rulez/clock"
+)
+
+/*
+	Token bucket.
+
+	The bucket holds up to Burst tokens and gains Rate of them per second, every request takes one.
+	A full bucket lets a burst of Burst requests through at once, after that they go at Rate per second.
+
+	Counting tokens in floats drifts: at 3 per second a token takes 333333333ns, and 0.333333333 * 3 < 1.
+	So we keep a time instead, full, the moment the bucket will be full again if nobody takes another token
+	(the "theoretical arrival time" of GCRA, https://en.wikipedia.org/wiki/Generic_cell_rate_algorithm):
+
+		tokens at now = Burst - (full - now) / per        if full is after now, Burst otherwise
+		taking one    = full moves per later
+
+	A request may go at now if at least one token is left, that is if full - (Burst-1)*per is not after now.
+	Reservations may take tokens that are not there yet, they move full further into the future, and their turn
+	is when their token will have been added.
+*/
+
+// TokenBucketConfig configures a TokenBucket, only Rate is required.
+type TokenBucketConfig struct {
+	Rate  float64     // tokens added per second
+	Burst int         // most tokens the bucket holds, defaults to 1
+	Clock clock.Clock // defaults to clock.Real()
+}
+
+// TokenBucket is a Limiter allowing bursts. Use NewTokenBucket to create one.
+type TokenBucket struct {
+	core
+	per   time.Duration // to add a token
+	burst int
+	full  time.Time
+}
+
+// NewTokenBucket returns a full token bucket. It panics if Rate is not positive.
+func NewTokenBucket(cfg TokenBucketConfig) *TokenBucket {
+	b := &TokenBucket{per: interval(cfg.Rate), burst: max(cfg.Burst, 1)}
+	b.core = core{clock: defaultClock(cfg.Clock), policy: b}
+	return b
+}
+
+// Tokens returns how many tokens are in the bucket right now. It is below zero while reservations wait for tokens.
+func (b *TokenBucket) Tokens() float64 {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return float64(b.burst) - float64(max(b.full.Sub(b.clock.Now()), 0))/float64(b.per)
+}
+
+func (b *TokenBucket) allow(now time.Time) bool {
+	if b.full.Add(-time.Duration(b.burst-1) * b.per).After(now) {
+		return false
+	}
+	b.take(now)
+	return true
+}
+
+func (b *TokenBucket) reserve(now time.Time) (time.Time, bool) {
+	b.take(now)
+	at := b.full.Add(-time.Duration(b.burst) * b.per)
+	if at.Before(now) {
+		at = now
+	}
+	return at, true
+}
+
+func (b *TokenBucket) unreserve(now, at time.Time) {
+	b.full = b.full.Add(-b.per)
+}
+
+func (b *TokenBucket) take(now time.Time) {
+	if b.full.Before(now) {
+		b.full = now
+	}
+	b.full = b.full.Add(b.per)
+}
@@ -0,0 +1,80 @@
+package main
+
+/* Resilient client
+A flaky server fails some requests and is very slow on others. client.Transport (see client/) retries the failures
+with backoff, hedges the slow ones, and a circuit breaker stops the calls once the server is down for good.
+loadgen (see load_generator.go) measures what each of them buys us.
+*/
+
+import (
+	"context"
+	"fmt"
+	"math/rand"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"sync/atomic"
+	"time"
+
+	"github.com/ysyesilyurt/lets-go/bootstrap/stages/go_rulez/client"
+	"github.com/ysyesilyurt/lets-go/bootstrap/stages/go_rulez/loadgen"
+)
+
+func main() {
+	var down atomic.Bool
+	flaky := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch n := rand.Intn(100); {
+		case down.Load() || n < 10:
+			http.Error(w, "try again later", http.StatusServiceUnavailable)
+		case n < 13:
+			time.Sleep(300 * time.Millisecond) // stuck behind a slow disk, say
+		default:
+			time.Sleep(5 * time.Millisecond)
+		}
+	}))
+	defer flaky.Close()
+
+	clients := []struct {
+		name string
+		c    *http.Client
+	}{
+		{"plain", &http.Client{Timeout: 5 * time.Second}},
+		{"retries", client.New(client.Config{MaxAttempts: 3, BaseDelay: 10 * time.Millisecond})},
+		{"retries + hedging", client.New(client.Config{MaxAttempts: 3, BaseDelay: 10 * time.Millisecond, HedgeAfter: 20 * time.Millisecond})},
+	}
+	for _, c := range clients {
+		report, err := loadgen.Run(context.Background(), loadgen.Config{URL: flaky.URL, Concurrency: 10, Requests: 300, Client: c.c})
+		if err != nil {
+			fmt.Println(err)
+			return
+		}
+		fmt.Printf("\n%s client\n", c.name)
+		report.WriteText(os.Stdout)
+	}
+
+	/* Once the server is down for good, retrying only adds to its load, the breaker fails fast instead */
+	fmt.Println("\nserver goes down")
+	down.Store(true)
+	breaker := client.NewBreaker(client.BreakerConfig{
+		FailureThreshold: 5,
+		OpenTimeout:      100 * time.Millisecond,
+		OnStateChange: func(from, to client.State) {
+			fmt.Printf("  breaker %s -> %s\n", from, to)
+		},
+	})
+	c := client.New(client.Config{MaxAttempts: 1, Breaker: breaker})
+	for i := 0; i < 30; i++ {
+		if i == 20 {
+			fmt.Println("server is back")
+			down.Store(false)
+		}
+		resp, err := c.Get(flaky.URL)
+		if err != nil {
+			fmt.Printf("  request %2d: %v\n", i, err)
+		} else {
+			fmt.Printf("  request %2d: %s\n", i, resp.Status)
+			resp.Body.Close()
+		}
+		time.Sleep(20 * time.Millisecond)
+	}
+}
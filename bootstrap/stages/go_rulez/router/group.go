@@ -0,0 +1,35 @@
+package router
+
+import "net/http"
+
+// Group registers routes under a common path prefix that share middleware:
+//
+//	api := r.Group("/api", requireAuth)
+//	api.HandleFunc("GET", "/pairs/{x}/{y}", getPair) // GET /api/pairs/{x}/{y}, behind requireAuth
+//
+// Unlike Router.Use, group middleware only runs for requests that matched one of its routes.
+type Group struct {
+	router *Router
+	prefix string
+	mw     []Middleware
+}
+
+// Use adds middleware to the routes registered on g from now on.
+func (g *Group) Use(mw ...Middleware) {
+	g.mw = append(g.mw, mw...)
+}
+
+// Handle registers h for method and the prefix of g followed by pattern.
+func (g *Group) Handle(method, pattern string, h http.Handler) {
+	g.router.Handle(method, g.prefix+pattern, chain(h, g.mw))
+}
+
+// HandleFunc registers f for method and the prefix of g followed by pattern.
+func (g *Group) HandleFunc(method, pattern string, f func(http.ResponseWriter, *http.Request)) {
+	g.Handle(method, pattern, http.HandlerFunc(f))
+}
+
+// Group returns a nested group, its routes run the middleware of g first and then mw.
+func (g *Group) Group(prefix string, mw ...Middleware) *Group {
+	return &Group{router: g.router, prefix: g.prefix + prefix, mw: append(append([]Middleware(nil), g.mw...), mw...)}
+}
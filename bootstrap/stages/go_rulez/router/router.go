@@ -0,0 +1,258 @@
+// Package router is a small HTTP router for our servers. On top of what a plain http.Handler gives us it matches
+//   - the method of the request, answering 405 Method Not Allowed with an Allow header when only the path matches
+//   - path parameters, /pairs/{x}/{y}, read back with r.PathValue("x")
+//   - wildcards at the end of a pattern, /static/{path...} matches the rest of the path, slashes included
+//   - route groups sharing a path prefix and middleware
+//
+// Literal segments win over parameters, parameters over wildcards, no matter the registration order.
+package router
+
+import (
+	"fmt"
+	"net/http"
+	"slices"
+	"strings"
+)
+
+// Middleware wraps a handler with some extra behavior, such as logging or authentication.
+type Middleware func(http.Handler) http.Handler
+
+// Router dispatches requests to the handler registered for their method and path. Use New to create one.
+// Routes and middleware must be set up before serving, the router is not safe to modify while it serves.
+type Router struct {
+	root    *segment
+	mw      []Middleware
+	handler http.Handler // dispatch wrapped in mw
+
+	// NotFound handles requests no route matches, http.NotFound by default.
+	NotFound http.Handler
+	// MethodNotAllowed handles requests whose path matches but method does not, after the Allow header is set.
+	// By default it replies with a plain 405.
+	MethodNotAllowed http.Handler
+}
+
+// segment is a node of the routing tree, one per path segment of the registered patterns.
+type segment struct {
+	literals map[string]*segment
+	param    *segment // matches any single segment
+	wildcard *segment // matches the rest of the path
+	name     string   // of the param or wildcard this segment stands for
+	routes   map[string]route
+}
+
+type route struct {
+	pattern string
+	handler http.Handler
+}
+
+// New returns a router without any routes.
+func New() *Router {
+	r := &Router{root: &segment{}}
+	r.handler = http.HandlerFunc(r.dispatch)
+	return r
+}
+
+// Use adds middleware that runs for every request, including the ones that end in 404 or 405.
+// Middleware added first runs first.
+func (r *Router) Use(mw ...Middleware) {
+	r.mw = append(r.mw, mw...)
+	r.handler = chain(http.HandlerFunc(r.dispatch), r.mw)
+}
+
+// Handle registers h for method and pattern. It panics if the pattern is malformed or already taken,
+// like http.Handle does, since that is a programming error.
+func (r *Router) Handle(method, pattern string, h http.Handler) {
+	if method == "" || !strings.HasPrefix(pattern, "/") {
+		panic(fmt.Sprintf("router: invalid route %q %q", method, pattern))
+	}
+	s := r.root
+	parts := split(pattern)
+	for i, part := range parts {
+		name, isParam := strings.CutPrefix(part, "{")
+		if !isParam {
+			if s.literals == nil {
+				s.literals = make(map[string]*segment)
+			}
+			if s.literals[part] == nil {
+				s.literals[part] = &segment{}
+			}
+			s = s.literals[part]
+			continue
+		}
+		name, ok := strings.CutSuffix(name, "}")
+		if !ok || name == "" {
+			panic(fmt.Sprintf("router: malformed segment %q in %q", part, pattern))
+		}
+		if name, isWildcard := strings.CutSuffix(name, "..."); isWildcard {
+			if i != len(parts)-1 {
+				panic(fmt.Sprintf("router: wildcard %q must be the last segment of %q", part, pattern))
+			}
+			s.wildcard = child(s.wildcard, name, pattern)
+			s = s.wildcard
+		} else {
+			s.param = child(s.param, name, pattern)
+			s = s.param
+		}
+	}
+	if s.routes == nil {
+		s.routes = make(map[string]route)
+	}
+	if _, taken := s.routes[method]; taken {
+		panic(fmt.Sprintf("router: %s %s registered twice", method, pattern))
+	}
+	s.routes[method] = route{pattern: method + " " + pattern, handler: h}
+}
+
+// child returns the param or wildcard segment c, creating it if needed. Two patterns can not
+// give the same position different names, /pairs/{x} and /pairs/{id}/more would be ambiguous.
+func child(c *segment, name, pattern string) *segment {
+	if c == nil {
+		return &segment{name: name}
+	}
+	if c.name != name {
+		panic(fmt.Sprintf("router: {%s} in %q conflicts with {%s} registered before", name, pattern, c.name))
+	}
+	return c
+}
+
+// HandleFunc registers f for method and pattern.
+func (r *Router) HandleFunc(method, pattern string, f func(http.ResponseWriter, *http.Request)) {
+	r.Handle(method, pattern, http.HandlerFunc(f))
+}
+
+// Group returns a group of routes under prefix that all run mw, see Group.
+func (r *Router) Group(prefix string, mw ...Middleware) *Group {
+	return &Group{router: r, prefix: prefix, mw: mw}
+}
+
+// ServeHTTP dispatches the request through the middleware to the matching route.
+func (r *Router) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	r.handler.ServeHTTP(w, req)
+}
+
+func (r *Router) dispatch(w http.ResponseWriter, req *http.Request) {
+	parts := split(req.URL.Path)
+	var params []string // name, value pairs
+	rt, ok := r.root.match(parts, req.Method, &params)
+	if !ok {
+		/* no route for the method, if there are some for others it is a 405 that lists them */
+		methods := make(map[string]bool)
+		r.root.methods(parts, methods)
+		if len(methods) == 0 {
+			r.notFound(w, req)
+			return
+		}
+		w.Header().Set("Allow", allow(methods))
+		if req.Method == http.MethodOptions {
+			w.WriteHeader(http.StatusNoContent)
+		} else if r.MethodNotAllowed != nil {
+			r.MethodNotAllowed.ServeHTTP(w, req)
+		} else {
+			http.Error(w, http.StatusText(http.StatusMethodNotAllowed), http.StatusMethodNotAllowed)
+		}
+		return
+	}
+
+	for i := 0; i < len(params); i += 2 {
+		req.SetPathValue(params[i], params[i+1])
+	}
+	req.Pattern = rt.pattern
+	rt.handler.ServeHTTP(w, req)
+}
+
+func (r *Router) notFound(w http.ResponseWriter, req *http.Request) {
+	if r.NotFound != nil {
+		r.NotFound.ServeHTTP(w, req)
+		return
+	}
+	http.NotFound(w, req)
+}
+
+// match finds the route for method and parts, trying literals first, then the param and the wildcard last.
+// It backtracks, so /pairs/new/edit still reaches /pairs/{x}/edit when /pairs/new has no /edit below it,
+// and GET /pairs/new reaches GET /pairs/{x} when /pairs/new only has a POST route.
+func (s *segment) match(parts []string, method string, params *[]string) (route, bool) {
+	if len(parts) == 0 {
+		return s.route(method)
+	}
+	if next, ok := s.literals[parts[0]]; ok {
+		if rt, ok := next.match(parts[1:], method, params); ok {
+			return rt, true
+		}
+	}
+	if s.param != nil && parts[0] != "" {
+		n := len(*params)
+		*params = append(*params, s.param.name, parts[0])
+		if rt, ok := s.param.match(parts[1:], method, params); ok {
+			return rt, true
+		}
+		*params = (*params)[:n]
+	}
+	if s.wildcard != nil {
+		if rt, ok := s.wildcard.route(method); ok {
+			*params = append(*params, s.wildcard.name, strings.Join(parts, "/"))
+			return rt, true
+		}
+	}
+	return route{}, false
+}
+
+// route returns the route of the segment for method, a HEAD request falls back to the GET route.
+func (s *segment) route(method string) (route, bool) {
+	rt, ok := s.routes[method]
+	if !ok && method == http.MethodHead {
+		rt, ok = s.routes[http.MethodGet] // net/http drops the body of a HEAD response for us
+	}
+	return rt, ok
+}
+
+// methods adds the methods of every segment parts matches to set, whichever of them match would pick.
+func (s *segment) methods(parts []string, set map[string]bool) {
+	if len(parts) == 0 {
+		for m := range s.routes {
+			set[m] = true
+		}
+		return
+	}
+	if next, ok := s.literals[parts[0]]; ok {
+		next.methods(parts[1:], set)
+	}
+	if s.param != nil && parts[0] != "" {
+		s.param.methods(parts[1:], set)
+	}
+	if s.wildcard != nil {
+		s.wildcard.methods(nil, set)
+	}
+}
+
+// allow lists methods the way the Allow header wants them.
+func allow(methods map[string]bool) string {
+	list := []string{http.MethodOptions}
+	for m := range methods {
+		if m != http.MethodOptions {
+			list = append(list, m)
+		}
+	}
+	if methods[http.MethodGet] && !methods[http.MethodHead] {
+		list = append(list, http.MethodHead)
+	}
+	slices.Sort(list)
+	return strings.Join(list, ", ")
+}
+
+// split turns /pairs/1/2 into [pairs 1 2], and / into no segments at all.
+func split(path string) []string {
+	path = strings.TrimPrefix(path, "/")
+	if path == "" {
+		return nil
+	}
+	return strings.Split(path, "/")
+}
+
+// chain wraps h in mw, the first middleware being the outermost one.
+func chain(h http.Handler, mw []Middleware) http.Handler {
+	for i := len(mw) - 1; i >= 0; i-- {
+		h = mw[i](h)
+	}
+	return h
+}
@@ -0,0 +1,128 @@
+package router
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"testing"
+)
+
+// echo answers with its name, the path values it got and the pattern that matched.
+func echo(name string) http.HandlerFunc {
+	return func(w http.ResponseWriter, req *http.Request) {
+		fmt.Fprintf(w, "%s x=%s y=%s rest=%s pattern=%s", name, req.PathValue("x"), req.PathValue("y"), req.PathValue("rest"), req.Pattern)
+	}
+}
+
+func TestRouter(t *testing.T) {
+	r := New()
+	r.Handle("GET", "/", echo("root"))
+	r.Handle("GET", "/pairs/{x}/{y}", echo("pair"))
+	r.Handle("DELETE", "/pairs/{x}/{y}", echo("delete"))
+	r.Handle("GET", "/pairs/new/edit", echo("new-edit"))
+	r.Handle("GET", "/pairs/{x}/edit", echo("edit"))
+	r.Handle("GET", "/items/{x}", echo("item"))
+	r.Handle("POST", "/items/new", echo("create"))
+	r.Handle("GET", "/static/{rest...}", echo("static"))
+	r.Handle("PUT", "/static/uploads/{x}", echo("upload"))
+
+	tests := []struct {
+		method, path string
+		code         int
+		body         string
+		allow        string
+	}{
+		{"GET", "/", 200, "root x= y= rest= pattern=GET /", ""},
+		{"GET", "/pairs/1/2", 200, "pair x=1 y=2 rest= pattern=GET /pairs/{x}/{y}", ""},
+		{"HEAD", "/pairs/1/2", 200, "pair x=1 y=2 rest= pattern=GET /pairs/{x}/{y}", ""}, // the server drops the body
+		{"DELETE", "/pairs/1/2", 200, "delete x=1 y=2 rest= pattern=DELETE /pairs/{x}/{y}", ""},
+		{"POST", "/pairs/1/2", 405, "Method Not Allowed\n", "DELETE, GET, HEAD, OPTIONS"},
+		{"OPTIONS", "/pairs/1/2", 204, "", "DELETE, GET, HEAD, OPTIONS"},
+
+		/* literals win, but a literal that leads nowhere backtracks to the param */
+		{"GET", "/pairs/new/edit", 200, "new-edit x= y= rest= pattern=GET /pairs/new/edit", ""},
+		{"GET", "/pairs/old/edit", 200, "edit x=old y= rest= pattern=GET /pairs/{x}/edit", ""},
+		{"GET", "/pairs/new/3", 200, "pair x=new y=3 rest= pattern=GET /pairs/{x}/{y}", ""},
+
+		/* a literal with routes for other methods only backtracks as well, it is no 405 while the param has the method */
+		{"POST", "/items/new", 200, "create x= y= rest= pattern=POST /items/new", ""},
+		{"GET", "/items/new", 200, "item x=new y= rest= pattern=GET /items/{x}", ""},
+		{"GET", "/items/7", 200, "item x=7 y= rest= pattern=GET /items/{x}", ""},
+		{"DELETE", "/items/new", 405, "Method Not Allowed\n", "GET, HEAD, OPTIONS, POST"},
+		{"POST", "/items/7", 405, "Method Not Allowed\n", "GET, HEAD, OPTIONS"},
+
+		/* and back to the wildcard */
+		{"GET", "/static/css/site.css", 200, "static x= y= rest=css/site.css pattern=GET /static/{rest...}", ""},
+		{"GET", "/static/uploads/a.png", 200, "static x= y= rest=uploads/a.png pattern=GET /static/{rest...}", ""},
+		{"PUT", "/static/uploads/a.png", 200, "upload x=a.png y= rest= pattern=PUT /static/uploads/{x}", ""},
+		{"POST", "/static/uploads/a.png", 405, "Method Not Allowed\n", "GET, HEAD, OPTIONS, PUT"},
+
+		{"GET", "/nope", 404, "404 page not found\n", ""},
+		{"GET", "/pairs/1", 404, "404 page not found\n", ""},
+		{"GET", "/pairs//2", 404, "404 page not found\n", ""},
+	}
+	for _, tt := range tests {
+		rec := httptest.NewRecorder()
+		r.ServeHTTP(rec, httptest.NewRequest(tt.method, tt.path, nil))
+		if rec.Code != tt.code || rec.Body.String() != tt.body || rec.Header().Get("Allow") != tt.allow {
+			t.Errorf("%s %s: %d %q, Allow %q\nwant %d %q, Allow %q",
+				tt.method, tt.path, rec.Code, rec.Body.String(), rec.Header().Get("Allow"), tt.code, tt.body, tt.allow)
+		}
+	}
+}
+
+func TestMiddlewareOrder(t *testing.T) {
+	var order []string
+	mw := func(name string) Middleware {
+		return func(h http.Handler) http.Handler {
+			return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+				order = append(order, name)
+				h.ServeHTTP(w, req)
+			})
+		}
+	}
+	r := New()
+	r.Use(mw("global"))
+	api := r.Group("/api", mw("api"))
+	api.Group("/v1", mw("v1")).Handle("GET", "/files/{rest...}", echo("files"))
+
+	rec := httptest.NewRecorder()
+	r.ServeHTTP(rec, httptest.NewRequest("GET", "/api/v1/files/a/b", nil))
+	if want := "files x= y= rest=a/b pattern=GET /api/v1/files/{rest...}"; rec.Body.String() != want {
+		t.Fatalf("got %q, want %q", rec.Body.String(), want)
+	}
+	if want := []string{"global", "api", "v1"}; !slices.Equal(order, want) {
+		t.Fatalf("middleware ran in order %v, want %v", order, want)
+	}
+
+	/* global middleware runs for a 404 as well, group middleware does not */
+	order = nil
+	r.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/api/nope", nil))
+	if want := []string{"global"}; !slices.Equal(order, want) {
+		t.Fatalf("middleware ran in order %v for a 404, want %v", order, want)
+	}
+}
+
+func TestHandlePanicsOnConflicts(t *testing.T) {
+	for _, routes := range [][2]string{
+		{"/a/{x}", "/a/{x}"},
+		{"/a/{x}", "/a/{y}/b"},
+		{"/a/{rest...}/b", ""},
+		{"a", ""},
+	} {
+		func() {
+			defer func() {
+				if recover() == nil {
+					t.Errorf("registering %q did not panic", routes)
+				}
+			}()
+			r := New()
+			for _, p := range routes {
+				if p != "" {
+					r.Handle("GET", p, echo(p))
+				}
+			}
+		}()
+	}
+}
@@ -0,0 +1,141 @@
+// Package server wraps http.Server with what http.ListenAndServe leaves out: a configurable address,
+// timeouts, and a graceful shutdown on SIGINT/SIGTERM or context cancellation that lets in-flight
+// requests finish before returning.
+package server
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+)
+
+// Config configures a Server, zero fields take the defaults below.
+type Config struct {
+	Addr            string        // TCP address to listen on, ":0" picks a free port; defaults to ":8080"
+	ReadTimeout     time.Duration // for reading a whole request, body included; defaults to 5s
+	WriteTimeout    time.Duration // from the end of reading the request headers to the end of the response; defaults to 10s
+	IdleTimeout     time.Duration // how long a keep-alive connection may wait for the next request; defaults to 60s
+	ShutdownTimeout time.Duration // how long in-flight requests get to finish on shutdown; defaults to 15s
+}
+
+func (c Config) withDefaults() Config {
+	if c.Addr == "" {
+		c.Addr = ":8080"
+	}
+	if c.ReadTimeout == 0 {
+		c.ReadTimeout = 5 * time.Second
+	}
+	if c.WriteTimeout == 0 {
+		c.WriteTimeout = 10 * time.Second
+	}
+	if c.IdleTimeout == 0 {
+		c.IdleTimeout = 60 * time.Second
+	}
+	if c.ShutdownTimeout == 0 {
+		c.ShutdownTimeout = 15 * time.Second
+	}
+	return c
+}
+
+// Server is an HTTP server that can be started and stopped. Use New to create one.
+type Server struct {
+	cfg      Config
+	http     *http.Server
+	ln       net.Listener
+	served   chan struct{} // closed once Serve returned
+	serveErr error         // what Serve returned, only read after served is closed
+}
+
+// New returns a server that will serve h according to cfg.
+func New(cfg Config, h http.Handler) *Server {
+	cfg = cfg.withDefaults()
+	return &Server{
+		cfg: cfg,
+		http: &http.Server{
+			Handler:      h,
+			ReadTimeout:  cfg.ReadTimeout,
+			WriteTimeout: cfg.WriteTimeout,
+			IdleTimeout:  cfg.IdleTimeout,
+		},
+		served: make(chan struct{}),
+	}
+}
+
+// Start starts listening and serving in the background. Once it returns, the server accepts connections on Addr.
+// Start must be called only once.
+func (s *Server) Start() error {
+	ln, err := net.Listen("tcp", s.cfg.Addr)
+	if err != nil {
+		return fmt.Errorf("server: listen on %s: %w", s.cfg.Addr, err)
+	}
+	s.ln = ln
+	go func() {
+		s.serveErr = s.http.Serve(ln)
+		close(s.served)
+	}()
+	return nil
+}
+
+// Addr returns the address the server listens on, with the actual port when Config.Addr asked for ":0".
+// It is only valid after Start.
+func (s *Server) Addr() string { return s.ln.Addr().String() }
+
+// URL returns the base URL of the server, e.g. http://127.0.0.1:8080. It is only valid after Start.
+func (s *Server) URL() string {
+	addr := s.ln.Addr().(*net.TCPAddr)
+	if addr.IP.IsUnspecified() {
+		return fmt.Sprintf("http://localhost:%d", addr.Port)
+	}
+	return "http://" + addr.String()
+}
+
+// Shutdown stops accepting connections and waits for in-flight requests to finish, or for ctx to be done,
+// whichever comes first. Requests still running then are cut off: their connections are closed, which cancels
+// their contexts, and ctx.Err() is returned. Handlers that ignore their request context may run on after that,
+// but whatever they write goes nowhere.
+func (s *Server) Shutdown(ctx context.Context) error {
+	if s.ln == nil { // never started
+		return nil
+	}
+	err := s.http.Shutdown(ctx)
+	if err != nil {
+		s.http.Close() // http.Server.Shutdown gives up waiting, but leaves the connections open
+	}
+	<-s.served
+	if !errors.Is(s.serveErr, http.ErrServerClosed) {
+		return s.serveErr
+	}
+	return err
+}
+
+// Run starts the server (unless Start was called already) and blocks until ctx is canceled or the process
+// gets SIGINT or SIGTERM, then shuts down gracefully, giving in-flight requests Config.ShutdownTimeout to finish.
+// It returns nil after a clean shutdown.
+//
+// To learn the port picked for ":0" before serving, call Start and Addr first and then Run.
+func (s *Server) Run(ctx context.Context) error {
+	ctx, stop := signal.NotifyContext(ctx, os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	if s.ln == nil {
+		if err := s.Start(); err != nil {
+			return err
+		}
+	}
+
+	select {
+	case <-s.served: // Serve gave up on its own, there is nothing to shut down
+		return s.serveErr
+	case <-ctx.Done():
+	}
+	stop() // a second Ctrl-C kills the process as usual instead of waiting for the drain
+
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), s.cfg.ShutdownTimeout)
+	defer cancel()
+	return s.Shutdown(shutdownCtx)
+}
@@ -0,0 +1,111 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"testing"
+	"time"
+)
+
+// slowServer starts a server on a free port whose handler takes d to answer "ok", unless its request
+// is canceled first. canceled is closed if that happens.
+func slowServer(t *testing.T, cfg Config, d time.Duration) (s *Server, started, canceled chan struct{}) {
+	t.Helper()
+	started, canceled = make(chan struct{}), make(chan struct{})
+	cfg.Addr = "127.0.0.1:0"
+	s = New(cfg, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		close(started)
+		select {
+		case <-time.After(d):
+			io.WriteString(w, "ok")
+		case <-r.Context().Done():
+			close(canceled)
+		}
+	}))
+	if err := s.Start(); err != nil {
+		t.Fatal(err)
+	}
+	return s, started, canceled
+}
+
+// get requests url in the background and sends the body, or the error, once it is done.
+func get(url string) <-chan string {
+	res := make(chan string, 1)
+	go func() {
+		resp, err := http.Get(url)
+		if err != nil {
+			res <- err.Error()
+			return
+		}
+		defer resp.Body.Close()
+		b, err := io.ReadAll(resp.Body)
+		if err != nil {
+			res <- err.Error()
+			return
+		}
+		res <- string(b)
+	}()
+	return res
+}
+
+func TestShutdownLetsRequestsFinish(t *testing.T) {
+	s, started, canceled := slowServer(t, Config{}, 200*time.Millisecond)
+	res := get(s.URL())
+	<-started
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := s.Shutdown(ctx); err != nil {
+		t.Fatalf("Shutdown = %v, want nil", err)
+	}
+	if got := <-res; got != "ok" {
+		t.Fatalf("in-flight request got %q, want ok", got)
+	}
+	select {
+	case <-canceled:
+		t.Fatal("the in-flight request was canceled")
+	default:
+	}
+}
+
+func TestShutdownCutsOffRequestsOnTimeout(t *testing.T) {
+	s, started, canceled := slowServer(t, Config{}, time.Minute)
+	res := get(s.URL())
+	<-started
+
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+	if err := s.Shutdown(ctx); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("Shutdown = %v, want %v", err, context.DeadlineExceeded)
+	}
+	select {
+	case <-canceled:
+	case <-time.After(5 * time.Second):
+		t.Fatal("the handler is still running after Shutdown gave up")
+	}
+	if got := <-res; got == "ok" {
+		t.Fatal("the request cut off by Shutdown got an answer")
+	}
+}
+
+func TestRunShutsDownOnCancel(t *testing.T) {
+	s, started, _ := slowServer(t, Config{ShutdownTimeout: 100 * time.Millisecond}, 20*time.Millisecond)
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error)
+	go func() { done <- s.Run(ctx) }()
+
+	res := get(s.URL())
+	<-started
+	cancel()
+	if err := <-done; err != nil {
+		t.Fatalf("Run = %v, want nil", err)
+	}
+	if got := <-res; got != "ok" {
+		t.Fatalf("in-flight request got %q, want ok", got)
+	}
+	if _, err := http.Get(s.URL()); err == nil {
+		t.Fatal("the server still accepts requests after Run returned")
+	}
+}
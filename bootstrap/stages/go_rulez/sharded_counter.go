@@ -0,0 +1,35 @@
+package main
+
+/* Sharded counters
+counter.SafeCounter guards its whole map with one sync.Mutex, every Inc and Value waits for every other one.
+counter.Sharded hashes the keys over independently locked shards and increments existing keys atomically
+under a read lock, so goroutines hardly ever wait for each other (see counter/sharded.go).
+
+How the two compare under contention shows in the benchmarks of the counter package:
+	go test -run XXX -bench Counters ./counter
+*/
+
+import (
+	"fmt"
+	"sync"
+
+	"github.com/ysyesilyurt/lets-go/bootstrap/stages/go_rulez/counter"
+)
+
+func main() {
+	/* Correctness first: concurrent increments must not get lost */
+	sharded := counter.NewSharded(0)
+	var wg sync.WaitGroup
+	for i := 0; i < 1000; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			sharded.Inc("somekey")
+			sharded.Add("other", 2)
+		}()
+	}
+	wg.Wait()
+	fmt.Println(sharded.Value("somekey"), sharded.Snapshot(), sharded.Keys())
+	sharded.Reset()
+	fmt.Println("after Reset:", sharded.Snapshot())
+}
@@ -0,0 +1,60 @@
+package stream
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+)
+
+// endEvent tells the client the generator is done.
+const endEvent = "event: end\ndata:\n\n"
+
+// SSE streams the values of gen as Server-Sent Events, one JSON encoded value per event:
+//
+//	id: 1
+//	data: 0
+//
+//	id: 2
+//	data: 1
+//
+// When gen closes its channel the stream ends with an "end" event, so EventSource clients know not to reconnect.
+// The handler needs a ResponseWriter that can flush, which the middleware package passes through.
+func SSE[T any](gen Generator[T]) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		rc := http.NewResponseController(w)
+		w.Header().Set("Content-Type", "text/event-stream")
+		w.Header().Set("Cache-Control", "no-cache")
+		w.WriteHeader(http.StatusOK)
+		if err := rc.Flush(); err != nil {
+			return // no streaming through this writer
+		}
+
+		out, stop := start(gen)
+		defer stop()
+		for id := 1; ; id++ {
+			var event string
+			select {
+			case v, ok := <-out:
+				if !ok {
+					event = endEvent
+					break
+				}
+				data, err := json.Marshal(v)
+				if err != nil {
+					return
+				}
+				event = fmt.Sprintf("id: %d\ndata: %s\n\n", id, data)
+			case <-r.Context().Done():
+				return // the client went away
+			}
+			rc.SetWriteDeadline(time.Now().Add(WriteTimeout))
+			if _, err := fmt.Fprint(w, event); err != nil {
+				return
+			}
+			if err := rc.Flush(); err != nil || event == endEvent {
+				return
+			}
+		}
+	})
+}
@@ -0,0 +1,59 @@
+package stream
+
+import (
+	"bufio"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestSSE(t *testing.T) {
+	srv := httptest.NewServer(SSE(func(out chan<- string, quit <-chan struct{}) {
+		defer close(out)
+		for _, s := range []string{"a", "two\nlines"} {
+			select {
+			case out <- s:
+			case <-quit:
+				return
+			}
+		}
+	}))
+	defer srv.Close()
+
+	resp, err := http.Get(srv.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	if ct, cc := resp.Header.Get("Content-Type"), resp.Header.Get("Cache-Control"); ct != "text/event-stream" || cc != "no-cache" {
+		t.Fatalf("Content-Type %q, Cache-Control %q", ct, cc)
+	}
+	body, _ := io.ReadAll(resp.Body)
+
+	/* JSON keeps every value on a single data line, and the end event follows the last one */
+	want := "id: 1\ndata: \"a\"\n\nid: 2\ndata: \"two\\nlines\"\n\nevent: end\ndata:\n\n"
+	if string(body) != want {
+		t.Fatalf("got\n%q\nwant\n%q", body, want)
+	}
+}
+
+func TestSSEClientGoesAway(t *testing.T) {
+	stopped := make(chan struct{})
+	srv := httptest.NewServer(SSE(counting(stopped)))
+	defer srv.Close()
+
+	resp, err := http.Get(srv.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	sc := bufio.NewScanner(resp.Body)
+	for events := 0; events < 3 && sc.Scan(); {
+		if strings.HasPrefix(sc.Text(), "data: ") {
+			events++
+		}
+	}
+	resp.Body.Close()
+	waitStopped(t, stopped)
+}
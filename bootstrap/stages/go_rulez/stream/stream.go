@@ -0,0 +1,47 @@
+// Package stream serves the values of a generator goroutine to HTTP clients as they are produced,
+// as Server-Sent Events (SSE) or over a WebSocket (see websocket.go for our minimal RFC 6455 implementation).
+//
+// The generator runs once per client and only as fast as the client reads: the channel between them is unbuffered,
+// so the generator blocks on its next send until the previous value is written out. A client that stops reading
+// for WriteTimeout, disconnects, or (over a WebSocket) asks to quit gets its generator stopped through quit.
+package stream
+
+import (
+	"sync"
+	"time"
+)
+
+// WriteTimeout is how long a client may leave a value unread before it is dropped.
+const WriteTimeout = 10 * time.Second
+
+// Generator sends values on out, like the fibonacci generators of go_rulez.go. It may close out once it has
+// nothing more to send, and must return when quit is closed, so every send has to select on quit as well:
+//
+//	func fibonacci(out chan<- int, quit <-chan struct{}) {
+//		x, y := 0, 1
+//		for {
+//			select {
+//			case out <- x:
+//				x, y = y, x+y
+//			case <-quit:
+//				return
+//			}
+//		}
+//	}
+type Generator[T any] func(out chan<- T, quit <-chan struct{})
+
+// start runs gen in a goroutine. stop closes its quit channel and waits for it to return.
+func start[T any](gen Generator[T]) (out <-chan T, stop func()) {
+	ch := make(chan T)
+	quit := make(chan struct{})
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		gen(ch, quit)
+	}()
+	var once sync.Once
+	return ch, func() {
+		once.Do(func() { close(quit) })
+		<-done
+	}
+}
@@ -0,0 +1,390 @@
+package stream
+
+import (
+	"bufio"
+	"context"
+	"crypto/rand"
+	"crypto/sha1"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+	"unicode/utf8"
+)
+
+/*
+	WebSocket, the minimal RFC 6455.
+
+	A WebSocket starts as a plain HTTP request asking to upgrade, the server answers 101 Switching Protocols
+	and from then on both sides send each other frames over the TCP connection:
+
+		 0               1               2               3
+		+-+-+-+-+-------+-+-------------+-------------------------------+
+		|F|R|R|R| opcode|M| payload len |  extended payload length      |
+		|I|S|S|S|  (4)  |A|     (7)     |  (16 bits if len == 126,      |
+		|N|V|V|V|       |S|             |   64 bits if len == 127)      |
+		+-+-+-+-+-------+-+-------------+-------------------------------+
+		|  masking key (32 bits, if MASK is set)  |  payload ...        |
+		+-----------------------------------------+---------------------+
+
+	Clients mask every frame they send, servers never do. A message may be split over several frames,
+	the last one has FIN set. Ping, pong and close frames are control frames, they may come in between.
+
+	We leave out extensions (no compression), subprotocols and TLS (ws:// only).
+*/
+
+// Message types, the opcodes of data frames.
+const (
+	TextMessage   = 1
+	BinaryMessage = 2
+)
+
+const (
+	continuationFrame = 0
+	closeFrame        = 8
+	pingFrame         = 9
+	pongFrame         = 10
+
+	maxMessageSize = 1 << 20
+)
+
+// close codes of RFC 6455 section 7.4.1
+const (
+	closeNormal          = 1000
+	closeProtocolError   = 1002
+	closeInvalidPayload  = 1007
+	closeMessageTooLarge = 1009
+)
+
+var errProtocol = errors.New("stream: websocket protocol error")
+
+// Conn is a WebSocket connection. ReadMessage must be called from one goroutine at a time,
+// WriteMessage and Close may be called concurrently with it and with each other.
+type Conn struct {
+	conn   net.Conn
+	br     *bufio.Reader
+	client bool // clients mask their frames
+
+	wmu       sync.Mutex // serializes frames, the reader answers pings while others write
+	closeSent bool
+}
+
+// Upgrade turns the request into a WebSocket connection. If the request is no valid WebSocket handshake
+// it answers with an error status and returns an error.
+func Upgrade(w http.ResponseWriter, r *http.Request) (*Conn, error) {
+	key := r.Header.Get("Sec-WebSocket-Key")
+	if k, err := base64.StdEncoding.DecodeString(key); r.Method != http.MethodGet || err != nil || len(k) != 16 ||
+		!headerHas(r.Header, "Connection", "upgrade") || !headerHas(r.Header, "Upgrade", "websocket") {
+		http.Error(w, "not a WebSocket handshake", http.StatusBadRequest)
+		return nil, errors.New("stream: not a WebSocket handshake")
+	}
+	if r.Header.Get("Sec-WebSocket-Version") != "13" {
+		w.Header().Set("Sec-WebSocket-Version", "13")
+		http.Error(w, "unsupported WebSocket version", http.StatusUpgradeRequired)
+		return nil, errors.New("stream: unsupported WebSocket version")
+	}
+	conn, brw, err := http.NewResponseController(w).Hijack()
+	if err != nil {
+		http.Error(w, "can not upgrade this connection", http.StatusInternalServerError)
+		return nil, fmt.Errorf("stream: %w", err)
+	}
+	conn.SetDeadline(time.Time{}) // the timeouts of the http.Server were meant for HTTP requests
+	fmt.Fprintf(brw, "HTTP/1.1 101 Switching Protocols\r\nUpgrade: websocket\r\nConnection: Upgrade\r\nSec-WebSocket-Accept: %s\r\n\r\n", acceptKey(key))
+	if err := brw.Flush(); err != nil {
+		conn.Close()
+		return nil, fmt.Errorf("stream: %w", err)
+	}
+	// the reader of brw may already hold the first frames the client sent right after its request
+	return &Conn{conn: conn, br: brw.Reader}, nil
+}
+
+// Dial opens a WebSocket connection to a ws:// URL.
+func Dial(ctx context.Context, rawURL string) (*Conn, error) {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return nil, fmt.Errorf("stream: %w", err)
+	}
+	if u.Scheme != "ws" {
+		return nil, fmt.Errorf("stream: only ws:// URLs are supported, got %q", rawURL)
+	}
+	host := u.Host
+	if u.Port() == "" {
+		host = net.JoinHostPort(u.Hostname(), "80")
+	}
+	conn, err := new(net.Dialer).DialContext(ctx, "tcp", host)
+	if err != nil {
+		return nil, fmt.Errorf("stream: %w", err)
+	}
+	if deadline, ok := ctx.Deadline(); ok {
+		conn.SetDeadline(deadline)
+		defer conn.SetDeadline(time.Time{})
+	}
+
+	var k [16]byte
+	rand.Read(k[:])
+	key := base64.StdEncoding.EncodeToString(k[:])
+	u.Scheme = "http"
+	req := &http.Request{Method: http.MethodGet, URL: u, Host: u.Host, Header: http.Header{
+		"Upgrade":               {"websocket"},
+		"Connection":            {"Upgrade"},
+		"Sec-WebSocket-Key":     {key},
+		"Sec-WebSocket-Version": {"13"},
+	}}
+	br := bufio.NewReader(conn)
+	if err := req.Write(conn); err != nil {
+		conn.Close()
+		return nil, fmt.Errorf("stream: %w", err)
+	}
+	resp, err := http.ReadResponse(br, req)
+	if err != nil {
+		conn.Close()
+		return nil, fmt.Errorf("stream: %w", err)
+	}
+	if resp.StatusCode != http.StatusSwitchingProtocols || resp.Header.Get("Sec-WebSocket-Accept") != acceptKey(key) {
+		conn.Close()
+		return nil, fmt.Errorf("stream: handshake failed with %s", resp.Status)
+	}
+	return &Conn{conn: conn, br: br, client: true}, nil
+}
+
+// ReadMessage returns the next data message, TextMessage or BinaryMessage, and answers the pings that come before it.
+// Once the peer closes the connection it returns io.EOF.
+func (c *Conn) ReadMessage() (typ int, data []byte, err error) {
+	typ = -1
+	for {
+		fin, op, payload, err := c.readFrame()
+		if err != nil {
+			if errors.Is(err, errProtocol) {
+				c.closeWith(closeProtocolError)
+			}
+			return 0, nil, err
+		}
+		switch op {
+		case pingFrame:
+			c.writeFrame(pongFrame, payload)
+			continue
+		case pongFrame:
+			continue
+		case closeFrame:
+			code := closeNormal
+			if len(payload) >= 2 {
+				code = int(binary.BigEndian.Uint16(payload))
+			}
+			c.closeWith(code) // echo the close, completing the closing handshake
+			return 0, nil, io.EOF
+		case continuationFrame:
+			if typ == -1 {
+				return 0, nil, c.fail(closeProtocolError, "continuation without a message")
+			}
+		default:
+			if typ != -1 {
+				return 0, nil, c.fail(closeProtocolError, "new message before the last one ended")
+			}
+			typ = op
+		}
+		if len(data)+len(payload) > maxMessageSize {
+			return 0, nil, c.fail(closeMessageTooLarge, "message too large")
+		}
+		data = append(data, payload...)
+		if fin {
+			if typ == TextMessage && !utf8.Valid(data) {
+				return 0, nil, c.fail(closeInvalidPayload, "text message is not UTF-8")
+			}
+			return typ, data, nil
+		}
+	}
+}
+
+// WriteMessage sends data as a single frame of type typ, TextMessage or BinaryMessage.
+func (c *Conn) WriteMessage(typ int, data []byte) error {
+	if typ != TextMessage && typ != BinaryMessage {
+		return fmt.Errorf("stream: invalid message type %d", typ)
+	}
+	return c.writeFrame(typ, data)
+}
+
+// SetWriteDeadline sets the deadline for writing the next frames, see net.Conn.
+func (c *Conn) SetWriteDeadline(t time.Time) error { return c.conn.SetWriteDeadline(t) }
+
+// Close sends a normal close frame, unless one was sent already, and closes the connection.
+func (c *Conn) Close() error {
+	c.closeWith(closeNormal)
+	return c.conn.Close()
+}
+
+// closeWith sends a close frame with code, once.
+func (c *Conn) closeWith(code int) {
+	c.wmu.Lock()
+	sent := c.closeSent
+	c.closeSent = true
+	c.wmu.Unlock()
+	if !sent {
+		c.conn.SetWriteDeadline(time.Now().Add(time.Second)) // not worth waiting long for, we are closing anyway
+		c.writeFrame(closeFrame, binary.BigEndian.AppendUint16(nil, uint16(code)))
+	}
+}
+
+// fail closes the connection with code after a misbehaving peer.
+func (c *Conn) fail(code int, reason string) error {
+	c.closeWith(code)
+	c.conn.Close()
+	return fmt.Errorf("%w: %s", errProtocol, reason)
+}
+
+func (c *Conn) readFrame() (fin bool, op int, payload []byte, err error) {
+	var head [2]byte
+	if _, err := io.ReadFull(c.br, head[:]); err != nil {
+		return false, 0, nil, err
+	}
+	fin, op = head[0]&0x80 != 0, int(head[0]&0x0f)
+	masked, n := head[1]&0x80 != 0, uint64(head[1]&0x7f)
+	switch {
+	case head[0]&0x70 != 0:
+		return false, 0, nil, fmt.Errorf("%w: reserved bits set without an extension", errProtocol)
+	case op > BinaryMessage && op < closeFrame || op > pongFrame:
+		return false, 0, nil, fmt.Errorf("%w: unknown opcode %d", errProtocol, op)
+	case op >= closeFrame && (!fin || n > 125):
+		return false, 0, nil, fmt.Errorf("%w: fragmented or long control frame", errProtocol)
+	case masked == c.client:
+		return false, 0, nil, fmt.Errorf("%w: clients must mask their frames, servers must not", errProtocol)
+	}
+
+	switch n {
+	case 126:
+		var ext [2]byte
+		if _, err := io.ReadFull(c.br, ext[:]); err != nil {
+			return false, 0, nil, err
+		}
+		n = uint64(binary.BigEndian.Uint16(ext[:]))
+	case 127:
+		var ext [8]byte
+		if _, err := io.ReadFull(c.br, ext[:]); err != nil {
+			return false, 0, nil, err
+		}
+		n = binary.BigEndian.Uint64(ext[:])
+	}
+	if n > maxMessageSize {
+		return false, 0, nil, c.fail(closeMessageTooLarge, "frame too large")
+	}
+	var mask [4]byte
+	if masked {
+		if _, err := io.ReadFull(c.br, mask[:]); err != nil {
+			return false, 0, nil, err
+		}
+	}
+	payload = make([]byte, n)
+	if _, err := io.ReadFull(c.br, payload); err != nil {
+		return false, 0, nil, err
+	}
+	if masked {
+		for i := range payload {
+			payload[i] ^= mask[i%4]
+		}
+	}
+	return fin, op, payload, nil
+}
+
+// writeFrame sends payload as a single, final frame.
+func (c *Conn) writeFrame(op int, payload []byte) error {
+	frame := []byte{0x80 | byte(op)}
+	var maskBit byte
+	if c.client {
+		maskBit = 0x80
+	}
+	switch n := len(payload); {
+	case n <= 125:
+		frame = append(frame, maskBit|byte(n))
+	case n <= 0xffff:
+		frame = binary.BigEndian.AppendUint16(append(frame, maskBit|126), uint16(n))
+	default:
+		frame = binary.BigEndian.AppendUint64(append(frame, maskBit|127), uint64(n))
+	}
+	if c.client {
+		var mask [4]byte
+		rand.Read(mask[:])
+		frame = append(frame, mask[:]...)
+		for i, b := range payload {
+			frame = append(frame, b^mask[i%4])
+		}
+	} else {
+		frame = append(frame, payload...)
+	}
+
+	c.wmu.Lock()
+	defer c.wmu.Unlock()
+	if c.closeSent && op != closeFrame {
+		return net.ErrClosed
+	}
+	_, err := c.conn.Write(frame)
+	return err
+}
+
+// acceptKey proves to the client that the server understood its handshake, see RFC 6455 section 4.2.2.
+func acceptKey(key string) string {
+	h := sha1.Sum([]byte(key + "258EAFA5-E914-47DA-95CA-C5AB0DC85B11"))
+	return base64.StdEncoding.EncodeToString(h[:])
+}
+
+// headerHas reports whether the comma separated values of header name contain token, ignoring case.
+func headerHas(h http.Header, name, token string) bool {
+	for _, v := range h.Values(name) {
+		for _, t := range strings.Split(v, ",") {
+			if strings.EqualFold(strings.TrimSpace(t), token) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// WebSocket streams the values of gen to WebSocket clients, one JSON encoded text message per value,
+// and listens to them: a "quit" text message stops gen, like quit_chan <- 0 does in select_channels.
+// The connection is closed once gen is done or stopped.
+func WebSocket[T any](gen Generator[T]) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		c, err := Upgrade(w, r)
+		if err != nil {
+			return // Upgrade answered already
+		}
+		defer c.Close()
+		out, stop := start(gen)
+		defer stop()
+
+		quit := make(chan struct{}) // closed once the client asks to quit or goes away
+		go func() {
+			defer close(quit)
+			for {
+				typ, msg, err := c.ReadMessage()
+				if err != nil || typ == TextMessage && strings.TrimSpace(string(msg)) == "quit" {
+					return
+				}
+			}
+		}()
+		for {
+			select {
+			case v, ok := <-out:
+				if !ok {
+					return
+				}
+				data, err := json.Marshal(v)
+				if err != nil {
+					return
+				}
+				c.SetWriteDeadline(time.Now().Add(WriteTimeout))
+				if err := c.WriteMessage(TextMessage, data); err != nil {
+					return
+				}
+			case <-quit:
+				return
+			}
+		}
+	})
+}
@@ -0,0 +1,377 @@
+package stream
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/binary"
+	"errors"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+)
+
+// the example handshake of RFC 6455 section 1.3
+const (
+	rfcKey    = "dGhlIHNhbXBsZSBub25jZQ=="
+	rfcAccept = "s3pPLMBiTxaQ9kYGzzhZRbK+xOo="
+)
+
+// echo sends every message it gets back.
+func echo(w http.ResponseWriter, r *http.Request) {
+	c, err := Upgrade(w, r)
+	if err != nil {
+		return
+	}
+	defer c.Close()
+	for {
+		typ, msg, err := c.ReadMessage()
+		if err != nil {
+			return
+		}
+		c.WriteMessage(typ, msg)
+	}
+}
+
+// counting returns a generator of 0, 1, 2, ... that closes stopped once it returns.
+func counting(stopped chan<- struct{}) Generator[int] {
+	return func(out chan<- int, quit <-chan struct{}) {
+		defer close(stopped)
+		for i := 0; ; i++ {
+			select {
+			case out <- i:
+			case <-quit:
+				return
+			}
+		}
+	}
+}
+
+// waitStopped fails the test unless stopped is closed soon.
+func waitStopped(t *testing.T, stopped <-chan struct{}) {
+	t.Helper()
+	select {
+	case <-stopped:
+	case <-time.After(5 * time.Second):
+		t.Fatal("the generator was not stopped")
+	}
+}
+
+func wsURL(srv *httptest.Server, path string) string {
+	return "ws" + strings.TrimPrefix(srv.URL, "http") + path
+}
+
+// pipe returns the server side of a WebSocket connection and the raw connection of its client.
+func pipe() (client net.Conn, server *Conn) {
+	a, b := net.Pipe()
+	return a, &Conn{conn: b, br: bufio.NewReader(b)}
+}
+
+// clientFrame builds a frame the way clients send them, masked, with first as its first byte
+// and a payload of at most 125 bytes.
+func clientFrame(first byte, payload string) []byte {
+	mask := []byte{1, 2, 3, 4}
+	frame := append([]byte{first, 0x80 | byte(len(payload))}, mask...)
+	for i := range len(payload) {
+		frame = append(frame, payload[i]^mask[i%4])
+	}
+	return frame
+}
+
+// readClose reads a close frame from a raw client connection and returns its code.
+func readClose(t *testing.T, client net.Conn) int {
+	t.Helper()
+	var frame [4]byte
+	if _, err := io.ReadFull(client, frame[:]); err != nil {
+		t.Fatal(err)
+	}
+	if frame[0] != 0x80|closeFrame || frame[1] != 2 {
+		t.Fatalf("got frame % x, want an unmasked close frame with a code", frame)
+	}
+	return int(binary.BigEndian.Uint16(frame[2:]))
+}
+
+func TestHandshake(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(echo))
+	defer srv.Close()
+	addr := srv.Listener.Addr().String()
+
+	for _, tt := range []struct {
+		name   string
+		method string
+		change func(http.Header)
+		status int
+	}{
+		{"valid", "GET", func(http.Header) {}, http.StatusSwitchingProtocols},
+		{"missing Upgrade", "GET", func(h http.Header) { h.Del("Upgrade") }, http.StatusBadRequest},
+		{"missing Connection", "GET", func(h http.Header) { h.Del("Connection") }, http.StatusBadRequest},
+		{"short key", "GET", func(h http.Header) { h.Set("Sec-WebSocket-Key", "c2hvcnQ=") }, http.StatusBadRequest},
+		{"POST", "POST", func(http.Header) {}, http.StatusBadRequest},
+		{"version 8", "GET", func(h http.Header) { h.Set("Sec-WebSocket-Version", "8") }, http.StatusUpgradeRequired},
+	} {
+		conn, err := net.Dial("tcp", addr)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer conn.Close()
+		/* tokens are case insensitive and Connection may list more than one */
+		h := make(http.Header)
+		h.Set("Upgrade", "WebSocket")
+		h.Set("Connection", "keep-alive, Upgrade")
+		h.Set("Sec-WebSocket-Key", rfcKey)
+		h.Set("Sec-WebSocket-Version", "13")
+		tt.change(h)
+		req := &http.Request{Method: tt.method, URL: &url.URL{Scheme: "http", Host: addr, Path: "/"}, Host: addr, Header: h}
+		req.Write(conn)
+		resp, err := http.ReadResponse(bufio.NewReader(conn), req)
+		if err != nil {
+			t.Fatalf("%s: %v", tt.name, err)
+		}
+		if resp.StatusCode != tt.status {
+			t.Errorf("%s: %s, want %d", tt.name, resp.Status, tt.status)
+		}
+		switch tt.status {
+		case http.StatusSwitchingProtocols:
+			if got := resp.Header.Get("Sec-WebSocket-Accept"); got != rfcAccept || !headerHas(resp.Header, "Upgrade", "websocket") {
+				t.Errorf("%s: Sec-WebSocket-Accept %q, Upgrade %q; want %q, websocket", tt.name, got, resp.Header.Get("Upgrade"), rfcAccept)
+			}
+		case http.StatusUpgradeRequired:
+			if v := resp.Header.Get("Sec-WebSocket-Version"); v != "13" {
+				t.Errorf("%s: Sec-WebSocket-Version %q, want 13", tt.name, v)
+			}
+		}
+	}
+
+	if _, err := Dial(context.Background(), srv.URL); err == nil {
+		t.Error("dialed an http:// URL")
+	}
+	notWS := httptest.NewServer(http.NotFoundHandler())
+	defer notWS.Close()
+	if _, err := Dial(context.Background(), wsURL(notWS, "/")); err == nil {
+		t.Error("dialed a server that does not upgrade")
+	}
+}
+
+// recorder is a net.Conn that keeps what is written to it.
+type recorder struct {
+	net.Conn
+	buf bytes.Buffer
+}
+
+func (r *recorder) Write(p []byte) (int, error) { return r.buf.Write(p) }
+
+func TestFrames(t *testing.T) {
+	/* payload lengths up to 125 fit the 7 bits of the header, up to 0xffff the 16 bit extension, beyond the 64 bit one */
+	for _, n := range []int{0, 1, 125, 126, 0xffff, 0x10000, 70000} {
+		for _, client := range []bool{false, true} {
+			payload := make([]byte, n)
+			for i := range payload {
+				payload[i] = byte(i)
+			}
+			rec := &recorder{}
+			if err := (&Conn{conn: rec, client: client}).WriteMessage(BinaryMessage, payload); err != nil {
+				t.Fatal(err)
+			}
+			frame := rec.buf.Bytes()
+
+			length, ext := n, 0
+			switch {
+			case n > 0xffff:
+				length, ext = 127, 8
+			case n > 125:
+				length, ext = 126, 2
+			}
+			head := 2 + ext
+			if client {
+				head += 4
+			}
+			if frame[0] != 0x80|BinaryMessage || int(frame[1]&0x7f) != length || frame[1]&0x80 != 0 != client || len(frame) != head+n {
+				t.Fatalf("%d bytes, client %v: frame starts % x and is %d bytes long", n, client, frame[:head], len(frame))
+			}
+			var extended uint64
+			for _, b := range frame[2 : 2+ext] {
+				extended = extended<<8 | uint64(b)
+			}
+			if ext > 0 && extended != uint64(n) {
+				t.Fatalf("%d bytes: the extended length says %d", n, extended)
+			}
+			if !client && !bytes.Equal(frame[head:], payload) {
+				t.Fatalf("%d bytes: the server masked its payload", n)
+			}
+
+			peer := &Conn{conn: rec, br: bufio.NewReader(bytes.NewReader(frame)), client: !client}
+			typ, data, err := peer.ReadMessage()
+			if err != nil || typ != BinaryMessage || !bytes.Equal(data, payload) {
+				t.Fatalf("%d bytes, client %v: read back %d %d bytes, %v", n, client, typ, len(data), err)
+			}
+		}
+	}
+}
+
+func TestPingPongAndClose(t *testing.T) {
+	client, server := pipe()
+	defer client.Close()
+	type message struct {
+		typ  int
+		data string
+		err  error
+	}
+	read := func() <-chan message {
+		ch := make(chan message, 1)
+		go func() {
+			typ, data, err := server.ReadMessage()
+			ch <- message{typ, string(data), err}
+		}()
+		return ch
+	}
+
+	/* a ping between the fragments of a message gets its pong right away */
+	got := read()
+	client.Write(clientFrame(0x00|TextMessage, "hel"))
+	client.Write(clientFrame(0x80|pingFrame, "pp"))
+	var pong [4]byte
+	if _, err := io.ReadFull(client, pong[:]); err != nil || string(pong[:]) != "\x8a\x02pp" {
+		t.Fatalf("got % x, %v; want the pong % x", pong, err, "\x8a\x02pp")
+	}
+	client.Write(clientFrame(0x80|continuationFrame, "lo"))
+	if m := <-got; m.typ != TextMessage || m.data != "hello" || m.err != nil {
+		t.Fatalf("got %+v, want the text message hello", m)
+	}
+
+	/* unsolicited pongs are skipped, and a close frame is answered with one */
+	got = read()
+	client.Write(clientFrame(0x80|pongFrame, ""))
+	client.Write(clientFrame(0x80|closeFrame, "\x03\xe8"))
+	if code := readClose(t, client); code != closeNormal {
+		t.Fatalf("the close was answered with code %d, want %d", code, closeNormal)
+	}
+	if m := <-got; m.err != io.EOF {
+		t.Fatalf("got %+v after the close, want io.EOF", m)
+	}
+	if err := server.WriteMessage(TextMessage, []byte("late")); !errors.Is(err, net.ErrClosed) {
+		t.Fatalf("writing after the close: %v, want net.ErrClosed", err)
+	}
+}
+
+func TestProtocolErrors(t *testing.T) {
+	tooLong := binary.BigEndian.AppendUint64([]byte{0x80 | BinaryMessage, 0x80 | 127}, maxMessageSize+1)
+	for _, tt := range []struct {
+		name  string
+		frame []byte
+		code  int
+	}{
+		{"unmasked client frame", []byte{0x80 | TextMessage, 1, 'a'}, closeProtocolError},
+		{"reserved bit", clientFrame(0xc0|TextMessage, "a"), closeProtocolError},
+		{"unknown opcode", clientFrame(0x83, "a"), closeProtocolError},
+		{"fragmented ping", clientFrame(pingFrame, ""), closeProtocolError},
+		{"long ping", []byte{0x80 | pingFrame, 0x80 | 126}, closeProtocolError},
+		{"continuation without a message", clientFrame(0x80|continuationFrame, "a"), closeProtocolError},
+		{"message within a message", append(clientFrame(TextMessage, "a"), clientFrame(0x80|TextMessage, "b")...), closeProtocolError},
+		{"text that is not UTF-8", clientFrame(0x80|TextMessage, "\xff"), closeInvalidPayload},
+		{"oversized frame", tooLong, closeMessageTooLarge},
+	} {
+		client, server := pipe()
+		errc := make(chan error, 1)
+		go func() {
+			_, _, err := server.ReadMessage()
+			errc <- err
+		}()
+		go client.Write(tt.frame)
+		if code := readClose(t, client); code != tt.code {
+			t.Errorf("%s: closed with code %d, want %d", tt.name, code, tt.code)
+		}
+		if err := <-errc; !errors.Is(err, errProtocol) {
+			t.Errorf("%s: ReadMessage returned %v, want a protocol error", tt.name, err)
+		}
+		client.Close()
+	}
+}
+
+func TestWebSocket(t *testing.T) {
+	stopped := make(chan struct{})
+	mux := http.NewServeMux()
+	mux.Handle("/count", WebSocket(counting(stopped)))
+	mux.Handle("/three", WebSocket(func(out chan<- string, quit <-chan struct{}) {
+		defer close(out)
+		for _, s := range []string{"a", "b", "c"} {
+			select {
+			case out <- s:
+			case <-quit:
+				return
+			}
+		}
+	}))
+	mux.HandleFunc("/echo", echo)
+	srv := httptest.NewServer(mux)
+	defer srv.Close()
+	ctx := context.Background()
+
+	/* a generator that is done closes the connection, the client answers its close frame */
+	c, err := Dial(ctx, wsURL(srv, "/three"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{`"a"`, `"b"`, `"c"`} {
+		if typ, msg, err := c.ReadMessage(); err != nil || typ != TextMessage || string(msg) != want {
+			t.Fatalf("got %d %s, %v; want the text message %s", typ, msg, err, want)
+		}
+	}
+	if _, _, err := c.ReadMessage(); err != io.EOF {
+		t.Fatalf("after the last value: %v, want io.EOF", err)
+	}
+	c.Close()
+
+	/* a quit message stops the generator and closes the connection */
+	c, err = Dial(ctx, wsURL(srv, "/count"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer c.Close()
+	for i := range 3 {
+		if _, msg, err := c.ReadMessage(); err != nil || string(msg) != string(rune('0'+i)) {
+			t.Fatalf("got %s, %v; want %d", msg, err, i)
+		}
+	}
+	c.WriteMessage(TextMessage, []byte("quit\n"))
+	waitStopped(t, stopped)
+	for err == nil {
+		_, _, err = c.ReadMessage() // values sent before the quit arrived
+	}
+	if err != io.EOF {
+		t.Fatalf("after quit: %v, want io.EOF", err)
+	}
+
+	/* messages of any size make it through the echo */
+	e, err := Dial(ctx, wsURL(srv, "/echo"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer e.Close()
+	for _, n := range []int{0, 125, 126, 70000} {
+		msg := bytes.Repeat([]byte("x"), n)
+		e.WriteMessage(BinaryMessage, msg)
+		if typ, got, err := e.ReadMessage(); err != nil || typ != BinaryMessage || !bytes.Equal(got, msg) {
+			t.Fatalf("echo of %d bytes: %d %d bytes, %v", n, typ, len(got), err)
+		}
+	}
+}
+
+func TestWebSocketClientGoesAway(t *testing.T) {
+	stopped := make(chan struct{})
+	srv := httptest.NewServer(WebSocket(counting(stopped)))
+	defer srv.Close()
+	c, err := Dial(context.Background(), wsURL(srv, "/"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, _, err := c.ReadMessage(); err != nil {
+		t.Fatal(err)
+	}
+	c.conn.Close() // without a close frame
+	waitStopped(t, stopped)
+}
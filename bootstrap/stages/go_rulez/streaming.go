@@ -0,0 +1,129 @@
+package main
+
+/* Streaming channels over HTTP
+The fibonacci generators of close_and_loop_channels() and select_channels() in go_rulez.go only print to stdout.
+Package stream serves any generator to HTTP clients instead, each client gets its own generator goroutine:
+	* as Server-Sent Events, which browsers read with new EventSource("/fib/sse")
+	* over a WebSocket, where the client can also talk back: sending "quit" is our quit_chan <- 0
+The generator only runs as far ahead as the client reads, and it is stopped when the client goes away.
+*/
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"math/big"
+	"net/http"
+	"strings"
+
+	"github.com/ysyesilyurt/lets-go/bootstrap/stages/go_rulez/router"
+	"github.com/ysyesilyurt/lets-go/bootstrap/stages/go_rulez/server"
+	"github.com/ysyesilyurt/lets-go/bootstrap/stages/go_rulez/stream"
+)
+
+// fibonacci is fib_2 of select_channels(): it sends fibonacci numbers until told to quit.
+// They outgrow an int after 92 numbers, so it sends big.Ints, which marshal to JSON numbers just the same.
+func fibonacci(c chan<- *big.Int, quit <-chan struct{}) {
+	x, y := big.NewInt(0), big.NewInt(1)
+	for {
+		select {
+		case c <- x:
+			x, y = y, new(big.Int).Add(x, y)
+		case <-quit:
+			fmt.Println("SERVER: fibonacci quit") // if we receive a quit msg from this chan then return
+			return
+		}
+	}
+}
+
+// firstTen is fib of close_and_loop_channels(): it sends the first ten fibonacci numbers and closes the channel.
+func firstTen(c chan<- int, quit <-chan struct{}) {
+	x, y := 0, 1
+	for i := 0; i < 10; i++ {
+		select {
+		case c <- x:
+			x, y = y, x+y
+		case <-quit:
+			return
+		}
+	}
+	close(c)
+}
+
+func main() {
+	rt := router.New()
+	rt.Handle("GET", "/fib/sse", stream.SSE(fibonacci))
+	rt.Handle("GET", "/fib/ten/sse", stream.SSE(firstTen))
+	rt.Handle("GET", "/fib/ws", stream.WebSocket(fibonacci))
+
+	srv := server.New(server.Config{Addr: "localhost:0"}, rt)
+	if err := srv.Start(); err != nil {
+		fmt.Println("SERVER: Could not listen", err)
+		return
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		srv.Run(ctx)
+	}()
+
+	/* An SSE client reads 10 events and hangs up, which stops its generator on the server */
+	resp, err := http.Get(srv.URL() + "/fib/sse")
+	if err != nil {
+		fmt.Println("CLIENT: Could not connect", err)
+		return
+	}
+	events := bufio.NewScanner(resp.Body)
+	for i := 0; i < 10 && events.Scan(); {
+		if data, ok := strings.CutPrefix(events.Text(), "data: "); ok {
+			fmt.Print(data, " ")
+			i++
+		}
+	}
+	fmt.Println("\nCLIENT: Hanging up")
+	resp.Body.Close()
+
+	/* A generator that closes its channel ends the stream with an end event */
+	resp, err = http.Get(srv.URL() + "/fib/ten/sse")
+	if err != nil {
+		fmt.Println("CLIENT: Could not connect", err)
+		return
+	}
+	events = bufio.NewScanner(resp.Body)
+	for events.Scan() {
+		if events.Text() != "" {
+			fmt.Printf("%q ", events.Text())
+		}
+	}
+	fmt.Println("\nCLIENT: The stream ended")
+	resp.Body.Close()
+
+	/* Over a WebSocket the client tells the generator to quit, like select_channels() does with quit_chan */
+	ws, err := stream.Dial(ctx, "ws://"+srv.Addr()+"/fib/ws")
+	if err != nil {
+		fmt.Println("CLIENT: Could not connect", err)
+		return
+	}
+	for i := 0; i < 100; i++ {
+		_, msg, err := ws.ReadMessage()
+		if err != nil {
+			fmt.Println("CLIENT: Could not read", err)
+			return
+		}
+		if i%20 == 0 || i == 99 {
+			fmt.Printf("fib(%d) = %s\n", i, msg)
+		}
+	}
+	ws.WriteMessage(stream.TextMessage, []byte("quit"))
+	for {
+		if _, _, err := ws.ReadMessage(); err != nil {
+			fmt.Println("CLIENT: Server closed the WebSocket:", err)
+			break
+		}
+	}
+	ws.Close()
+
+	cancel()
+	<-done
+}
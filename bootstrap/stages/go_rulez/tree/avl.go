@@ -0,0 +1,120 @@
+package tree
+
+import (
+	"cmp"
+	"fmt"
+)
+
+// AVL is a binary search tree that keeps the heights of the two subtrees of every node within one of each other,
+// so its height stays below 1.44*log2(n) no matter the insertion order. The zero value is an empty tree ready to use.
+type AVL[K cmp.Ordered] struct {
+	base[K]
+}
+
+// NewAVL returns an AVL tree holding the given keys.
+func NewAVL[K cmp.Ordered](keys ...K) *AVL[K] {
+	t := &AVL[K]{}
+	for _, k := range keys {
+		t.Insert(k)
+	}
+	return t
+}
+
+// Insert adds key to t and reports whether it was not already there.
+func (t *AVL[K]) Insert(key K) bool {
+	var added bool
+	t.root, added = avlInsert(t.root, key)
+	if added {
+		t.len++
+	}
+	return added
+}
+
+// Delete removes key from t and reports whether it was there.
+func (t *AVL[K]) Delete(key K) bool {
+	var removed bool
+	t.root, removed = avlRemove(t.root, key)
+	if removed {
+		t.len--
+	}
+	return removed
+}
+
+// Verify checks the BST invariants of t and that every node is balanced.
+func (t *AVL[K]) Verify() error {
+	if err := t.verify(); err != nil {
+		return err
+	}
+	return verifyAVL(t.root)
+}
+
+func avlInsert[K cmp.Ordered](n *Node[K], key K) (*Node[K], bool) {
+	if n == nil {
+		return &Node[K]{key: key, height: 1, size: 1}, true
+	}
+	var added bool
+	switch c := cmp.Compare(key, n.key); {
+	case c < 0:
+		n.left, added = avlInsert(n.left, key)
+	case c > 0:
+		n.right, added = avlInsert(n.right, key)
+	default:
+		return n, false
+	}
+	return avlBalance(n), added
+}
+
+func avlRemove[K cmp.Ordered](n *Node[K], key K) (*Node[K], bool) {
+	if n == nil {
+		return nil, false
+	}
+	var removed bool
+	switch c := cmp.Compare(key, n.key); {
+	case c < 0:
+		n.left, removed = avlRemove(n.left, key)
+	case c > 0:
+		n.right, removed = avlRemove(n.right, key)
+	default:
+		if n.left == nil {
+			return n.right, true
+		} else if n.right == nil {
+			return n.left, true
+		}
+		succ := n.right.min()
+		n.key = succ.key
+		n.right, _ = avlRemove(n.right, succ.key)
+		removed = true
+	}
+	return avlBalance(n), removed
+}
+
+// avlBalance fixes n after one of its subtrees grew or shrank by one level and returns the new subtree root.
+func avlBalance[K cmp.Ordered](n *Node[K]) *Node[K] {
+	n.fix()
+	switch bf := height(n.left) - height(n.right); {
+	case bf > 1:
+		if height(n.left.left) < height(n.left.right) { // left-right case
+			n.left = rotateLeft(n.left)
+		}
+		return rotateRight(n)
+	case bf < -1:
+		if height(n.right.right) < height(n.right.left) { // right-left case
+			n.right = rotateRight(n.right)
+		}
+		return rotateLeft(n)
+	}
+	return n
+}
+
+func verifyAVL[K cmp.Ordered](n *Node[K]) error {
+	if n == nil {
+		return nil
+	}
+	if bf := height(n.left) - height(n.right); bf < -1 || bf > 1 {
+		return fmt.Errorf("tree: AVL node %v has balance factor %d", n.key, bf)
+	}
+	if err := verifyAVL(n.left); err != nil {
+		return err
+	}
+	return verifyAVL(n.right)
+}
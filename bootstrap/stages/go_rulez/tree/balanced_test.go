@@ -0,0 +1,125 @@
+package tree
+
+import (
+	"math"
+	"math/rand"
+	"testing"
+)
+
+var variants = []struct {
+	name string
+	new  func() OrderedSet[int]
+}{
+	{"bst", func() OrderedSet[int] { return New[int]() }},
+	{"avl", func() OrderedSet[int] { return NewAVL[int]() }},
+	{"red-black", func() OrderedSet[int] { return NewRedBlack[int]() }},
+}
+
+func TestInvariantsAfterEveryMutation(t *testing.T) {
+	for _, v := range variants {
+		t.Run(v.name, func(t *testing.T) {
+			s, want := v.new(), make(map[int]bool)
+			for i := 0; i < 10000; i++ {
+				k := rand.Intn(500)
+				if rand.Intn(3) == 0 {
+					if s.Delete(k) != want[k] {
+						t.Fatalf("Delete(%d) = %v, but the key was there: %v", k, !want[k], want[k])
+					}
+					delete(want, k)
+				} else {
+					if s.Insert(k) == want[k] {
+						t.Fatalf("Insert(%d) = %v, but the key was there: %v", k, want[k], want[k])
+					}
+					want[k] = true
+				}
+				if err := s.Verify(); err != nil {
+					t.Fatalf("after %d mutations: %v", i+1, err)
+				}
+				if s.Len() != len(want) {
+					t.Fatalf("Len = %d, want %d", s.Len(), len(want))
+				}
+			}
+		})
+	}
+}
+
+func TestCheckedPanicsOnBrokenInvariants(t *testing.T) {
+	s := Checked(OrderedSet[int](NewAVL(1, 2, 3)))
+	s.Root().left.key = 5 // out of order behind the tree's back
+	defer func() {
+		if recover() == nil {
+			t.Fatal("Checked did not panic on a broken tree")
+		}
+	}()
+	s.Insert(4)
+}
+
+func TestBalancedHeight(t *testing.T) {
+	const n = 1 << 12
+	/* AVL trees are at most ~1.44 log2(n) high, red-black trees at most 2 log2(n+1) */
+	bounds := map[string]float64{
+		"avl":       1.4405 * math.Log2(n+2),
+		"red-black": 2 * math.Log2(n+1),
+	}
+	for _, v := range variants {
+		bound, ok := bounds[v.name]
+		if !ok {
+			continue
+		}
+		for _, keys := range [][]int{sortedKeys(n), rand.Perm(n)} {
+			s := v.new()
+			for _, k := range keys {
+				s.Insert(k)
+			}
+			if h := s.Height(); float64(h) > bound {
+				t.Fatalf("%s: height %d after %d inserts, want at most %.1f", v.name, h, n, bound)
+			}
+		}
+	}
+}
+
+func sortedKeys(n int) []int {
+	keys := make([]int, n)
+	for i := range keys {
+		keys[i] = i
+	}
+	return keys
+}
+
+func BenchmarkInsert(b *testing.B) {
+	const n = 2000
+	for _, w := range []struct {
+		name string
+		keys []int
+	}{{"sorted", sortedKeys(n)}, {"random", rand.Perm(n)}} {
+		for _, v := range variants {
+			b.Run(w.name+"/"+v.name, func(b *testing.B) {
+				for i := 0; i < b.N; i++ {
+					s := v.new()
+					for _, k := range w.keys {
+						s.Insert(k)
+					}
+				}
+			})
+		}
+	}
+}
+
+/* Lookups are where the height pays off, a plain BST filled with sorted keys is a linked list */
+func BenchmarkContainsAfterSortedInserts(b *testing.B) {
+	const n = 2000
+	probes := rand.Perm(n)
+	for _, v := range variants {
+		s := v.new()
+		for _, k := range sortedKeys(n) {
+			s.Insert(k)
+		}
+		b.Run(v.name, func(b *testing.B) {
+			for i := 0; i < b.N; i++ {
+				for _, k := range probes {
+					s.Contains(k)
+				}
+			}
+		})
+	}
+}
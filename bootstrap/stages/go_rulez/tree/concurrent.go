@@ -0,0 +1,108 @@
+package tree
+
+import (
+	"cmp"
+	"iter"
+	"sync"
+)
+
+/*
+	Concurrent ordered set.
+
+	Walk in walk.go reads a Tree from another goroutine, so nobody may touch the tree
+	while it runs. Concurrent gets around that with copy-on-write nodes (see cow.go): a published node is never
+	changed again, every write builds a new root instead. The sync.RWMutex only guards which root is current:
+		* readers take the read lock just long enough to grab the root, so any number of them run in parallel
+		* writers take the write lock for the whole mutation, so they are serialized
+	Grabbing the root gives a Snapshot, a consistent read-only view that later writes do not affect,
+	so it can be iterated for as long as we like without holding any lock.
+*/
+
+// Concurrent is an AVL balanced ordered set that is safe for concurrent use by multiple goroutines.
+// The zero value is an empty set ready to use.
+type Concurrent[K cmp.Ordered] struct {
+	mu   sync.RWMutex
+	root *Node[K] // copy-on-write, never mutated once stored here
+	len  int
+}
+
+// NewConcurrent returns a concurrent set holding the given keys.
+func NewConcurrent[K cmp.Ordered](keys ...K) *Concurrent[K] {
+	c := &Concurrent[K]{}
+	for _, k := range keys {
+		c.Insert(k)
+	}
+	return c
+}
+
+// Snapshot is a read-only view of a Concurrent set at one point in time.
+// It is safe to use from any number of goroutines and never changes.
+type Snapshot[K cmp.Ordered] struct {
+	base[K]
+}
+
+// Verify checks the BST and AVL invariants of the snapshot.
+func (s *Snapshot[K]) Verify() error {
+	if err := s.verify(); err != nil {
+		return err
+	}
+	return verifyAVL(s.root)
+}
+
+// Snapshot returns a consistent read-only view of the current contents of c.
+func (c *Concurrent[K]) Snapshot() *Snapshot[K] {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return &Snapshot[K]{base[K]{root: c.root, len: c.len}}
+}
+
+// Insert adds key to c and reports whether it was not already there.
+func (c *Concurrent[K]) Insert(key K) bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	root, added := cowInsert(c.root, key)
+	if added {
+		c.root = root
+		c.len++
+	}
+	return added
+}
+
+// Delete removes key from c and reports whether it was there.
+func (c *Concurrent[K]) Delete(key K) bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	root, removed := cowRemove(c.root, key)
+	if removed {
+		c.root = root
+		c.len--
+	}
+	return removed
+}
+
+/* Reads answer from a fresh snapshot, so every single one of them is consistent on its own */
+
+func (c *Concurrent[K]) Contains(key K) bool          { return c.Snapshot().Contains(key) }
+func (c *Concurrent[K]) Min() (K, bool)               { return c.Snapshot().Min() }
+func (c *Concurrent[K]) Max() (K, bool)               { return c.Snapshot().Max() }
+func (c *Concurrent[K]) Floor(key K) (K, bool)        { return c.Snapshot().Floor(key) }
+func (c *Concurrent[K]) Ceiling(key K) (K, bool)      { return c.Snapshot().Ceiling(key) }
+func (c *Concurrent[K]) Len() int                     { return c.Snapshot().Len() }
+func (c *Concurrent[K]) Height() int                  { return c.Snapshot().Height() }
+func (c *Concurrent[K]) Root() *Node[K]               { return c.Snapshot().Root() }
+func (c *Concurrent[K]) Select(i int) (K, bool)       { return c.Snapshot().Select(i) }
+func (c *Concurrent[K]) Rank(key K) int               { return c.Snapshot().Rank(key) }
+func (c *Concurrent[K]) CountRange(lo, hi K) int      { return c.Snapshot().CountRange(lo, hi) }
+func (c *Concurrent[K]) Verify() error                { return c.Snapshot().Verify() }
+func (c *Concurrent[K]) String() string               { return c.Snapshot().String() }
+func (c *Concurrent[K]) MarshalJSON() ([]byte, error) { return c.Snapshot().MarshalJSON() }
+
+// All iterates over a snapshot of c taken when the iteration starts, writes made meanwhile are not seen.
+func (c *Concurrent[K]) All() iter.Seq[K] {
+	return func(yield func(K) bool) { c.Snapshot().All()(yield) }
+}
+
+// Range iterates over the keys k of a snapshot of c with lo <= k < hi, taken when the iteration starts.
+func (c *Concurrent[K]) Range(lo, hi K) iter.Seq[K] {
+	return func(yield func(K) bool) { c.Snapshot().Range(lo, hi)(yield) }
+}
@@ -0,0 +1,96 @@
+package tree
+
+import (
+	"math/rand"
+	"slices"
+	"sync"
+	"sync/atomic"
+	"testing"
+)
+
+// TestConcurrentStress is meant for the race detector: go test -race -run Concurrent ./tree
+func TestConcurrentStress(t *testing.T) {
+	const (
+		writers = 8
+		readers = 8
+		perKeys = 500
+	)
+	set := NewConcurrent[int]()
+	var wg, readersWg sync.WaitGroup
+	var done atomic.Bool
+
+	/* Every writer owns its own keys, so we know what must be left: it inserts all of them and deletes the even ones */
+	for w := 0; w < writers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			keys := rand.Perm(perKeys)
+			for _, k := range keys {
+				if !set.Insert(w*perKeys + k) {
+					t.Errorf("Insert(%d) found the key of writer %d there already", w*perKeys+k, w)
+				}
+			}
+			for _, k := range keys {
+				if k%2 == 0 && !set.Delete(w*perKeys+k) {
+					t.Errorf("Delete(%d) did not find the key writer %d inserted", w*perKeys+k, w)
+				}
+			}
+		}()
+	}
+
+	for r := 0; r < readers; r++ {
+		readersWg.Add(1)
+		go func() {
+			defer readersWg.Done()
+			for !done.Load() {
+				/* a snapshot never changes under us: its keys are sorted, match its Len and its invariants hold */
+				snap := set.Snapshot()
+				keys := slices.Collect(snap.All())
+				if err := snap.Verify(); err != nil || !slices.IsSorted(keys) || len(keys) != snap.Len() {
+					t.Errorf("inconsistent snapshot of %d keys, Len %d: %v", len(keys), snap.Len(), err)
+					return
+				}
+				set.Contains(rand.Intn(writers * perKeys))
+			}
+		}()
+	}
+
+	wg.Wait()
+	done.Store(true)
+	readersWg.Wait()
+
+	if err := set.Verify(); err != nil {
+		t.Fatal(err)
+	}
+	if set.Len() != writers*perKeys/2 {
+		t.Fatalf("Len = %d, want %d", set.Len(), writers*perKeys/2)
+	}
+	for k := range set.All() {
+		if k%2 == 0 {
+			t.Fatalf("key %d survived its Delete", k)
+		}
+	}
+}
+
+func TestSnapshotsDoNotChange(t *testing.T) {
+	c := NewConcurrent[int]()
+	var snaps []*Snapshot[int]
+	var shapes []string
+	for i := 0; i < 3000; i++ {
+		if k := rand.Intn(300); rand.Intn(2) == 0 {
+			c.Insert(k)
+		} else {
+			c.Delete(k)
+		}
+		if i%100 == 0 {
+			s := c.Snapshot()
+			snaps = append(snaps, s)
+			shapes = append(shapes, s.String())
+		}
+	}
+	for i, s := range snaps {
+		if err := s.Verify(); err != nil || s.String() != shapes[i] {
+			t.Fatalf("snapshot %d changed after later writes: %v\n%s\nwas\n%s", i, err, s, shapes[i])
+		}
+	}
+}
@@ -0,0 +1,99 @@
+package tree
+
+import "cmp"
+
+/*
+	Copy-on-write (path copying) AVL operations.
+
+	cowInsert and cowRemove never touch a node that is already part of a tree: they copy the nodes on
+	the path from the root down to the change (and the ones a rotation moves) and return a new root
+	that shares every other node with the old one. So whoever still holds the old root keeps a valid,
+	unchanging tree, at the cost of O(log n) new nodes per mutation.
+*/
+
+func clone[K cmp.Ordered](n *Node[K]) *Node[K] {
+	c := *n
+	return &c
+}
+
+func cowInsert[K cmp.Ordered](n *Node[K], key K) (*Node[K], bool) {
+	if n == nil {
+		return &Node[K]{key: key, height: 1, size: 1}, true
+	}
+	var child *Node[K]
+	var added bool
+	switch c := cmp.Compare(key, n.key); {
+	case c < 0:
+		if child, added = cowInsert(n.left, key); added {
+			n = clone(n)
+			n.left = child
+		}
+	case c > 0:
+		if child, added = cowInsert(n.right, key); added {
+			n = clone(n)
+			n.right = child
+		}
+	}
+	if !added {
+		return n, false
+	}
+	return cowBalance(n), true
+}
+
+func cowRemove[K cmp.Ordered](n *Node[K], key K) (*Node[K], bool) {
+	if n == nil {
+		return nil, false
+	}
+	var child *Node[K]
+	var removed bool
+	switch c := cmp.Compare(key, n.key); {
+	case c < 0:
+		if child, removed = cowRemove(n.left, key); removed {
+			n = clone(n)
+			n.left = child
+		}
+	case c > 0:
+		if child, removed = cowRemove(n.right, key); removed {
+			n = clone(n)
+			n.right = child
+		}
+	default:
+		if n.left == nil {
+			return n.right, true
+		} else if n.right == nil {
+			return n.left, true
+		}
+		succ := n.right.min()
+		child, _ = cowRemove(n.right, succ.key)
+		n = clone(n)
+		n.key, n.right = succ.key, child
+		removed = true
+	}
+	if !removed {
+		return n, false
+	}
+	return cowBalance(n), true
+}
+
+// cowBalance is avlBalance for a node that was just copied: n itself may be changed, but
+// the children a rotation moves are still shared, so they get copied before rotating.
+func cowBalance[K cmp.Ordered](n *Node[K]) *Node[K] {
+	n.fix()
+	switch bf := height(n.left) - height(n.right); {
+	case bf > 1:
+		n.left = clone(n.left)
+		if height(n.left.left) < height(n.left.right) {
+			n.left.right = clone(n.left.right)
+			n.left = rotateLeft(n.left)
+		}
+		return rotateRight(n)
+	case bf < -1:
+		n.right = clone(n.right)
+		if height(n.right.right) < height(n.right.left) {
+			n.right.left = clone(n.right.left)
+			n.right = rotateRight(n.right)
+		}
+		return rotateLeft(n)
+	}
+	return n
+}
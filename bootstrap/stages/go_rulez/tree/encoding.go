@@ -0,0 +1,212 @@
+package tree
+
+import (
+	"cmp"
+	"encoding/json"
+	"fmt"
+	"strings"
+)
+
+/*
+	Serialization.
+
+	JSON keeps the exact shape of the tree as nested objects, missing children are left out:
+
+		{"value":4,"left":{"value":2},"right":{"value":6,"left":{"value":5}}}
+
+	The compact format is the preorder walk with # marking every missing child, values are JSON encoded
+	so strings come out quoted:
+
+		4 2 # # 6 5 # # #
+
+	A plain Tree decodes back into exactly the same shape. AVL and RedBlack decode by inserting the keys
+	in preorder, so they keep their contents but rebalance as they see fit (red-black colors are not stored).
+*/
+
+// nullMarker stands for a missing child in the compact preorder format.
+const nullMarker = "#"
+
+type jsonNode[K cmp.Ordered] struct {
+	Value K            `json:"value"`
+	Left  *jsonNode[K] `json:"left,omitempty"`
+	Right *jsonNode[K] `json:"right,omitempty"`
+}
+
+// MarshalJSON encodes the subtree rooted at n as nested JSON objects.
+func (n *Node[K]) MarshalJSON() ([]byte, error) {
+	return json.Marshal(struct {
+		Value K        `json:"value"`
+		Left  *Node[K] `json:"left,omitempty"`
+		Right *Node[K] `json:"right,omitempty"`
+	}{n.key, n.left, n.right})
+}
+
+// MarshalJSON encodes the shape of the set as nested JSON objects, null if it is empty.
+func (t *base[K]) MarshalJSON() ([]byte, error) { return json.Marshal(t.root) }
+
+// UnmarshalJSON restores t to the exact shape found in data, which must be a valid binary search tree.
+func (t *Tree[K]) UnmarshalJSON(data []byte) error {
+	root, err := decodeJSON[K](data)
+	if err != nil {
+		return err
+	}
+	return t.load(root)
+}
+
+// UnmarshalJSON replaces the contents of t with the keys found in data.
+func (t *AVL[K]) UnmarshalJSON(data []byte) error {
+	root, err := decodeJSON[K](data)
+	if err != nil {
+		return err
+	}
+	var fresh AVL[K] // t stays as it was if data is no search tree
+	if err := reinsert(&fresh, root); err != nil {
+		return err
+	}
+	*t = fresh
+	return nil
+}
+
+// UnmarshalJSON replaces the contents of t with the keys found in data.
+func (t *RedBlack[K]) UnmarshalJSON(data []byte) error {
+	root, err := decodeJSON[K](data)
+	if err != nil {
+		return err
+	}
+	var fresh RedBlack[K] // t stays as it was if data is no search tree
+	if err := reinsert(&fresh, root); err != nil {
+		return err
+	}
+	*t = fresh
+	return nil
+}
+
+// EncodePreorder encodes the subtree rooted at n in the compact preorder format.
+func EncodePreorder[K cmp.Ordered](n *Node[K]) (string, error) {
+	var b strings.Builder
+	var encode func(n *Node[K]) error
+	encode = func(n *Node[K]) error {
+		if b.Len() > 0 {
+			b.WriteByte(' ')
+		}
+		if n == nil {
+			b.WriteString(nullMarker)
+			return nil
+		}
+		v, err := json.Marshal(n.key)
+		if err != nil {
+			return err
+		}
+		b.Write(v)
+		if err := encode(n.left); err != nil {
+			return err
+		}
+		return encode(n.right)
+	}
+	if err := encode(n); err != nil {
+		return "", err
+	}
+	return b.String(), nil
+}
+
+// DecodePreorder builds a Tree with exactly the shape encoded in s by EncodePreorder.
+func DecodePreorder[K cmp.Ordered](s string) (*Tree[K], error) {
+	p := &preorderParser{s: s}
+	var decode func() (*Node[K], error)
+	decode = func() (*Node[K], error) {
+		tok, ok := p.next()
+		if !ok {
+			return nil, fmt.Errorf("tree: preorder input ends early")
+		}
+		if tok == nullMarker {
+			return nil, nil
+		}
+		n := &Node[K]{}
+		if err := json.Unmarshal([]byte(tok), &n.key); err != nil {
+			return nil, fmt.Errorf("tree: bad preorder value %s: %w", tok, err)
+		}
+		var err error
+		if n.left, err = decode(); err != nil {
+			return nil, err
+		}
+		if n.right, err = decode(); err != nil {
+			return nil, err
+		}
+		n.fix()
+		return n, nil
+	}
+	root, err := decode()
+	if err != nil {
+		return nil, err
+	}
+	if tok, ok := p.next(); ok {
+		return nil, fmt.Errorf("tree: unexpected %s after the preorder walk", tok)
+	}
+	t := &Tree[K]{}
+	return t, t.load(root)
+}
+
+// preorderParser splits the compact format into tokens, quoted strings may contain spaces.
+type preorderParser struct {
+	s string
+}
+
+func (p *preorderParser) next() (string, bool) {
+	p.s = strings.TrimLeft(p.s, " \t\r\n")
+	if p.s == "" {
+		return "", false
+	}
+	end := strings.IndexAny(p.s, " \t\r\n")
+	if p.s[0] == '"' {
+		end = 1
+		for end < len(p.s) && p.s[end] != '"' {
+			if p.s[end] == '\\' {
+				end++
+			}
+			end++
+		}
+		end = min(end+1, len(p.s)) // an unterminated string is left for json.Unmarshal to complain about
+	} else if end < 0 {
+		end = len(p.s)
+	}
+	tok := p.s[:end]
+	p.s = p.s[end:]
+	return tok, true
+}
+
+func decodeJSON[K cmp.Ordered](data []byte) (*Node[K], error) {
+	var j *jsonNode[K]
+	if err := json.Unmarshal(data, &j); err != nil {
+		return nil, err
+	}
+	var build func(j *jsonNode[K]) *Node[K]
+	build = func(j *jsonNode[K]) *Node[K] {
+		if j == nil {
+			return nil
+		}
+		n := &Node[K]{key: j.Value, left: build(j.Left), right: build(j.Right)}
+		n.fix()
+		return n
+	}
+	return build(j), nil
+}
+
+// load makes root the root of t after checking that it is a valid binary search tree.
+func (t *Tree[K]) load(root *Node[K]) error {
+	n, err := verify(root, nil, nil)
+	if err != nil {
+		return fmt.Errorf("tree: decoded input is not a binary search tree: %w", err)
+	}
+	t.root, t.len = root, n
+	return nil
+}
+
+func reinsert[K cmp.Ordered](s OrderedSet[K], root *Node[K]) error {
+	if _, err := verify(root, nil, nil); err != nil {
+		return fmt.Errorf("tree: decoded input is not a binary search tree: %w", err)
+	}
+	for k := range preOrder(root) {
+		s.Insert(k)
+	}
+	return nil
+}
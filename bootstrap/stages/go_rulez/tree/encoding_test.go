@@ -0,0 +1,112 @@
+package tree
+
+import (
+	"encoding/json"
+	"math/rand"
+	"slices"
+	"testing"
+)
+
+func TestPreorderRoundTrip(t *testing.T) {
+	for n := range 50 {
+		tr := New(rand.Perm(n)...)
+		enc, err := EncodePreorder(tr.Root())
+		if err != nil {
+			t.Fatal(err)
+		}
+		got, err := DecodePreorder[int](enc)
+		if err != nil {
+			t.Fatalf("DecodePreorder(%q): %v", enc, err)
+		}
+		if got.String() != tr.String() || got.Len() != tr.Len() {
+			t.Fatalf("%q decoded into\n%swant\n%s", enc, got, tr)
+		}
+	}
+
+	/* strings are quoted, so spaces, quotes and a key that is the null marker itself survive */
+	tr := New("a b", `q"x`, "#", "z")
+	enc, err := EncodePreorder(tr.Root())
+	if want := `"a b" "#" # # "q\"x" # "z" # #`; err != nil || enc != want {
+		t.Fatalf("EncodePreorder = %q, %v, want %q", enc, err, want)
+	}
+	got, err := DecodePreorder[string](enc)
+	if err != nil || got.String() != tr.String() {
+		t.Fatalf("%q decoded into\n%s%v", enc, got, err)
+	}
+}
+
+func TestDecodePreorderRejectsMalformedInput(t *testing.T) {
+	for _, s := range []string{
+		"",              // not even an empty tree, that is #
+		"2 #",           // ends early
+		"2 # # 5",       // more after the walk
+		"2 3 # # #",     // 3 on the left of 2
+		"x # #",         // not an int
+		`"a # #`,        // unterminated string
+		"1 # 1 # #",     // duplicate key
+		"2 1 # 3 # # #", // 3 below 1 but right of 2
+	} {
+		if got, err := DecodePreorder[int](s); err == nil {
+			t.Errorf("DecodePreorder(%q) = %v, want an error", s, slices.Collect(got.All()))
+		}
+	}
+	if got, err := DecodePreorder[int]("#"); err != nil || got.Len() != 0 {
+		t.Fatalf(`DecodePreorder("#") = %v, %v, want an empty tree`, got, err)
+	}
+}
+
+func TestJSONRoundTrip(t *testing.T) {
+	data, err := json.Marshal(New(4, 2, 6, 5))
+	if want := `{"value":4,"left":{"value":2},"right":{"value":6,"left":{"value":5}}}`; err != nil || string(data) != want {
+		t.Fatalf("Marshal = %s, %v, want %s", data, err, want)
+	}
+	if data, _ := json.Marshal(New[int]()); string(data) != "null" {
+		t.Fatalf("Marshal of an empty tree = %s, want null", data)
+	}
+
+	for n := range 50 {
+		tr := New(rand.Perm(n)...)
+		data, _ := json.Marshal(tr)
+
+		/* a Tree keeps the exact shape, the balanced variants the keys */
+		var plain Tree[int]
+		if err := json.Unmarshal(data, &plain); err != nil || plain.String() != tr.String() || plain.Len() != n {
+			t.Fatalf("%s decoded into\n%s%v", data, plain.String(), err)
+		}
+		for _, s := range []interface {
+			OrderedSet[int]
+			json.Unmarshaler
+		}{NewAVL(100), NewRedBlack(100)} {
+			if err := json.Unmarshal(data, s); err != nil || s.Verify() != nil || !slices.Equal(slices.Collect(s.All()), slices.Collect(tr.All())) {
+				t.Fatalf("%s decoded into %T %v, %v", data, s, slices.Collect(s.All()), err)
+			}
+		}
+	}
+
+	strs := New("a b", `q"x`, "#")
+	data, _ = json.Marshal(strs)
+	var got RedBlack[string]
+	if err := json.Unmarshal(data, &got); err != nil || !slices.Equal(slices.Collect(got.All()), slices.Collect(strs.All())) {
+		t.Fatalf("%s decoded into %v, %v", data, slices.Collect(got.All()), err)
+	}
+}
+
+func TestUnmarshalJSONKeepsReceiverOnError(t *testing.T) {
+	for _, data := range []string{
+		`{"value":1,"left":{"value":5}}`, // 5 on the left of 1
+		`{"value":`,
+		`{"value":"a"}`,
+	} {
+		for _, s := range []interface {
+			OrderedSet[int]
+			json.Unmarshaler
+		}{New(1, 2, 3), NewAVL(1, 2, 3), NewRedBlack(1, 2, 3)} {
+			if err := json.Unmarshal([]byte(data), s); err == nil {
+				t.Errorf("%T: Unmarshal(%s) did not fail", s, data)
+			}
+			if got := slices.Collect(s.All()); !slices.Equal(got, []int{1, 2, 3}) || s.Len() != 3 {
+				t.Errorf("%T: failed Unmarshal(%s) left %v, want [1 2 3] untouched", s, data, got)
+			}
+		}
+	}
+}
@@ -0,0 +1,125 @@
+package tree
+
+import (
+	"cmp"
+	"iter"
+)
+
+/*
+	Range-over-func iterators.
+
+	Walk in walk.go pushes every value through a channel, which costs a goroutine
+	and a channel handoff per element, and has to be told through a quit channel when the receiver stops early (as Same does).
+	These iterators run in the caller's goroutine with an explicit stack (so no recursion either)
+	and simply stop when the loop body breaks:
+
+		for k := range t.InOrder() {
+			if k > 5 {
+				break
+			}
+		}
+
+	The set must not be mutated while one of its iterators is running.
+*/
+
+// All returns an iterator over the keys of the set in ascending order, same as InOrder.
+func (t *base[K]) All() iter.Seq[K] { return inOrder(t.root) }
+
+// InOrder returns an iterator over the keys of the set in ascending order: left subtree, node, right subtree.
+func (t *base[K]) InOrder() iter.Seq[K] { return inOrder(t.root) }
+
+// PreOrder returns an iterator over the keys of the set visiting each node before its subtrees.
+func (t *base[K]) PreOrder() iter.Seq[K] { return preOrder(t.root) }
+
+// PostOrder returns an iterator over the keys of the set visiting each node after its subtrees.
+func (t *base[K]) PostOrder() iter.Seq[K] { return postOrder(t.root) }
+
+// LevelOrder returns an iterator over the keys of the set level by level, from the root down and left to right.
+func (t *base[K]) LevelOrder() iter.Seq[K] { return levelOrder(t.root) }
+
+func inOrder[K cmp.Ordered](root *Node[K]) iter.Seq[K] {
+	return func(yield func(K) bool) {
+		stack := make([]*Node[K], 0, height(root))
+		for n := root; n != nil || len(stack) > 0; n = n.right {
+			for ; n != nil; n = n.left {
+				stack = append(stack, n)
+			}
+			n = stack[len(stack)-1]
+			stack = stack[:len(stack)-1]
+			if !yield(n.key) {
+				return
+			}
+		}
+	}
+}
+
+func preOrder[K cmp.Ordered](root *Node[K]) iter.Seq[K] {
+	return func(yield func(K) bool) {
+		if root == nil {
+			return
+		}
+		stack := make([]*Node[K], 1, height(root)+1)
+		stack[0] = root
+		for len(stack) > 0 {
+			n := stack[len(stack)-1]
+			stack = stack[:len(stack)-1]
+			if !yield(n.key) {
+				return
+			}
+			// push right first so that the left subtree pops first
+			if n.right != nil {
+				stack = append(stack, n.right)
+			}
+			if n.left != nil {
+				stack = append(stack, n.left)
+			}
+		}
+	}
+}
+
+func postOrder[K cmp.Ordered](root *Node[K]) iter.Seq[K] {
+	return func(yield func(K) bool) {
+		stack := make([]*Node[K], 0, height(root))
+		var last *Node[K] // the node yielded last, tells us whether we are coming back up from a right subtree
+		for n := root; n != nil || len(stack) > 0; {
+			if n != nil {
+				stack = append(stack, n)
+				n = n.left
+				continue
+			}
+			top := stack[len(stack)-1]
+			if top.right != nil && top.right != last {
+				n = top.right
+				continue
+			}
+			stack = stack[:len(stack)-1]
+			if !yield(top.key) {
+				return
+			}
+			last = top
+		}
+	}
+}
+
+func levelOrder[K cmp.Ordered](root *Node[K]) iter.Seq[K] {
+	return func(yield func(K) bool) {
+		if root == nil {
+			return
+		}
+		for level := []*Node[K]{root}; len(level) > 0; {
+			var next []*Node[K]
+			for _, n := range level {
+				if !yield(n.key) {
+					return
+				}
+				if n.left != nil {
+					next = append(next, n.left)
+				}
+				if n.right != nil {
+					next = append(next, n.right)
+				}
+			}
+			level = next
+		}
+	}
+}
@@ -0,0 +1,98 @@
+package tree
+
+import (
+	"iter"
+	"math/rand"
+	"slices"
+	"testing"
+
+	"github.com/ysyesilyurt/lets-go/bootstrap/stages/go_rulez/leakcheck"
+)
+
+// recursive is the textbook recursive traversal the iterators must agree with, pre, in or post order.
+func recursive(n *Node[int], order string, out *[]int) {
+	if n == nil {
+		return
+	}
+	if order == "pre" {
+		*out = append(*out, n.key)
+	}
+	recursive(n.left, order, out)
+	if order == "in" {
+		*out = append(*out, n.key)
+	}
+	recursive(n.right, order, out)
+	if order == "post" {
+		*out = append(*out, n.key)
+	}
+}
+
+func TestTraversalOrders(t *testing.T) {
+	for n := 0; n < 100; n++ {
+		tr := New(rand.Perm(n)...)
+		for order, seq := range map[string]iter.Seq[int]{"pre": tr.PreOrder(), "in": tr.InOrder(), "post": tr.PostOrder()} {
+			var want []int
+			recursive(tr.root, order, &want)
+			if got := slices.Collect(seq); !slices.Equal(got, want) {
+				t.Fatalf("%sorder of %v:\ngot  %v\nwant %v", order, tr.root, got, want)
+			}
+		}
+	}
+}
+
+func TestLevelOrder(t *testing.T) {
+	//       4
+	//    2     6
+	//   1 3   5
+	tr := New(4, 2, 6, 1, 3, 5)
+	if got, want := slices.Collect(tr.LevelOrder()), []int{4, 2, 6, 1, 3, 5}; !slices.Equal(got, want) {
+		t.Fatalf("LevelOrder = %v, want %v", got, want)
+	}
+	if got := slices.Collect(New[int]().LevelOrder()); len(got) != 0 {
+		t.Fatalf("LevelOrder of an empty tree = %v", got)
+	}
+}
+
+func TestIteratorsStopOnBreak(t *testing.T) {
+	leakcheck.Check(t, leakcheck.Config{})
+	tr := New(rand.Perm(100)...)
+	for name, seq := range map[string]func() iter.Seq[int]{
+		"InOrder": tr.InOrder, "PreOrder": tr.PreOrder, "PostOrder": tr.PostOrder, "LevelOrder": tr.LevelOrder,
+	} {
+		n := 0
+		for range seq() {
+			if n++; n == 5 {
+				break
+			}
+		}
+		if n != 5 {
+			t.Fatalf("%s went on for %d keys", name, n)
+		}
+	}
+}
+
+/* Walk pays for a goroutine and a channel handoff per key, the iterators for neither */
+func BenchmarkTraversal(b *testing.B) {
+	big := New(rand.Perm(10000)...)
+	sum := 0
+	b.Run("Walk", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			ch := make(chan int)
+			go Walk(big, ch)
+			for k := range ch {
+				sum += k
+			}
+		}
+	})
+	for name, seq := range map[string]func() iter.Seq[int]{
+		"InOrder": big.InOrder, "PreOrder": big.PreOrder, "PostOrder": big.PostOrder, "LevelOrder": big.LevelOrder,
+	} {
+		b.Run(name, func(b *testing.B) {
+			for i := 0; i < b.N; i++ {
+				for k := range seq() {
+					sum += k
+				}
+			}
+		})
+	}
+}
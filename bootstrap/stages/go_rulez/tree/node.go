@@ -0,0 +1,75 @@
+package tree
+
+import "cmp"
+
+// Node is a single node of a Tree. Unlike golang.org/x/tour/tree the fields are not exported,
+// so walkers can read the shape of a tree through Left, Right and Value but cannot break its ordering.
+type Node[K cmp.Ordered] struct {
+	left, right *Node[K]
+	key         K
+	height      int  // levels in the subtree rooted here, a leaf has height 1
+	size        int  // nodes in the subtree rooted here, for the order statistics
+	red         bool // color of the link from the parent, only used by RedBlack
+}
+
+// Left returns the left child of n, nil if there is none.
+func (n *Node[K]) Left() *Node[K] { return n.left }
+
+// Right returns the right child of n, nil if there is none.
+func (n *Node[K]) Right() *Node[K] { return n.right }
+
+// Value returns the key stored in n.
+func (n *Node[K]) Value() K { return n.key }
+
+// height is nil-safe so that callers do not need to check children before asking.
+func height[K cmp.Ordered](n *Node[K]) int {
+	if n == nil {
+		return 0
+	}
+	return n.height
+}
+
+func size[K cmp.Ordered](n *Node[K]) int {
+	if n == nil {
+		return 0
+	}
+	return n.size
+}
+
+// fix recomputes the cached fields of n from its children, call it whenever a child changes.
+func (n *Node[K]) fix() {
+	n.height = 1 + max(height(n.left), height(n.right))
+	n.size = 1 + size(n.left) + size(n.right)
+}
+
+func (n *Node[K]) min() *Node[K] {
+	for n.left != nil {
+		n = n.left
+	}
+	return n
+}
+
+func (n *Node[K]) max() *Node[K] {
+	for n.right != nil {
+		n = n.right
+	}
+	return n
+}
+
+// rotateLeft lifts the right child of n in its place and returns it.
+func rotateLeft[K cmp.Ordered](n *Node[K]) *Node[K] {
+	r := n.right
+	n.right, r.left = r.left, n
+	n.fix()
+	r.fix()
+	return r
+}
+
+// rotateRight lifts the left child of n in its place and returns it.
+func rotateRight[K cmp.Ordered](n *Node[K]) *Node[K] {
+	l := n.left
+	n.left, l.right = l.right, n
+	n.fix()
+	l.fix()
+	return l
+}
@@ -0,0 +1,86 @@
+package tree
+
+import (
+	"cmp"
+	"iter"
+)
+
+/*
+	Order statistics.
+
+	Every node caches the size of its subtree, so we can tell how many keys are smaller than a node
+	without visiting them: that turns "k-th smallest" and "rank of key" into a single walk down the tree,
+	O(height), which is O(log n) for AVL and RedBlack.
+*/
+
+// Select returns the i-th smallest key of the set counting from 0, ok is false if i is out of range.
+func (t *base[K]) Select(i int) (key K, ok bool) {
+	if i < 0 || i >= size(t.root) {
+		return key, false
+	}
+	for n := t.root; ; {
+		switch l := size(n.left); {
+		case i < l:
+			n = n.left
+		case i > l:
+			i -= l + 1
+			n = n.right
+		default:
+			return n.key, true
+		}
+	}
+}
+
+// Rank returns the number of keys in the set that are smaller than key, which is also
+// the index Select would find key at if it was in the set.
+func (t *base[K]) Rank(key K) int {
+	rank := 0
+	for n := t.root; n != nil; {
+		switch c := cmp.Compare(key, n.key); {
+		case c < 0:
+			n = n.left
+		case c > 0:
+			rank += size(n.left) + 1
+			n = n.right
+		default:
+			return rank + size(n.left)
+		}
+	}
+	return rank
+}
+
+// CountRange returns the number of keys k in the set with lo <= k < hi.
+func (t *base[K]) CountRange(lo, hi K) int {
+	if cmp.Compare(lo, hi) >= 0 {
+		return 0
+	}
+	return t.Rank(hi) - t.Rank(lo)
+}
+
+// Range returns an iterator over the keys k in the set with lo <= k < hi, in ascending order.
+// It costs O(log n) to find lo and then O(1) amortized per key.
+func (t *base[K]) Range(lo, hi K) iter.Seq[K] {
+	return func(yield func(K) bool) {
+		// the stack holds the path to lo, keeping only the nodes that are not smaller than lo
+		stack := make([]*Node[K], 0, height(t.root))
+		for n := t.root; n != nil; {
+			if cmp.Less(n.key, lo) {
+				n = n.right
+			} else {
+				stack = append(stack, n)
+				n = n.left
+			}
+		}
+		// from there on it is a plain in-order walk, cut short at hi
+		for len(stack) > 0 {
+			n := stack[len(stack)-1]
+			stack = stack[:len(stack)-1]
+			if !cmp.Less(n.key, hi) || !yield(n.key) {
+				return
+			}
+			for n = n.right; n != nil; n = n.left {
+				stack = append(stack, n)
+			}
+		}
+	}
+}
@@ -0,0 +1,81 @@
+package tree
+
+import (
+	"slices"
+	"testing"
+	"testing/quick"
+)
+
+// orderStatistics inserts and then deletes arbitrary keys and checks every order statistic query of s
+// against a sorted slice holding the same keys.
+func orderStatistics(s OrderedSet[int16], inserts, deletes []int16, lo, hi int16) bool {
+	var want []int16
+	for _, k := range inserts {
+		if s.Insert(k) {
+			want = append(want, k)
+		}
+	}
+	for _, k := range deletes {
+		if s.Delete(k) {
+			want = slices.DeleteFunc(want, func(w int16) bool { return w == k })
+		}
+	}
+	slices.Sort(want)
+
+	for i, k := range want {
+		if got, ok := s.Select(i); !ok || got != k || s.Rank(k) != i {
+			return false
+		}
+	}
+	if _, ok := s.Select(len(want)); ok {
+		return false
+	}
+	if _, ok := s.Select(-1); ok {
+		return false
+	}
+	from, _ := slices.BinarySearch(want, lo)
+	to, _ := slices.BinarySearch(want, hi)
+	var inRange []int16
+	if lo < hi {
+		inRange = want[from:to]
+	}
+	return s.Rank(lo) == from && s.CountRange(lo, hi) == len(inRange) &&
+		slices.Equal(slices.Collect(s.Range(lo, hi)), inRange)
+}
+
+func TestOrderStatistics(t *testing.T) {
+	for _, v := range []struct {
+		name string
+		new  func() OrderedSet[int16]
+	}{
+		{"bst", func() OrderedSet[int16] { return New[int16]() }},
+		{"avl", func() OrderedSet[int16] { return NewAVL[int16]() }},
+		{"red-black", func() OrderedSet[int16] { return NewRedBlack[int16]() }},
+		{"concurrent", func() OrderedSet[int16] { return NewConcurrent[int16]() }},
+	} {
+		t.Run(v.name, func(t *testing.T) {
+			check := func(inserts, deletes []int16, lo, hi int16) bool {
+				return orderStatistics(v.new(), inserts, deletes, lo, hi)
+			}
+			if err := quick.Check(check, &quick.Config{MaxCount: 500}); err != nil {
+				t.Fatal(err)
+			}
+		})
+	}
+}
+
+func TestRangeStopsOnBreak(t *testing.T) {
+	s := NewRedBlack[int]()
+	for k := range 100 {
+		s.Insert(k)
+	}
+	var got []int
+	for k := range s.Range(10, 90) {
+		if got = append(got, k); len(got) == 3 {
+			break
+		}
+	}
+	if !slices.Equal(got, []int{10, 11, 12}) {
+		t.Fatalf("got %v, want [10 11 12]", got)
+	}
+}
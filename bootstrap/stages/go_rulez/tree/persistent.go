@@ -0,0 +1,184 @@
+package tree
+
+import (
+	"cmp"
+	"iter"
+)
+
+/*
+	Persistent (immutable) ordered set.
+
+	Persistent uses the copy-on-write operations of cow.go directly: Insert and Delete leave the version they
+	are called on untouched and return a new version, which shares everything but O(log n) nodes with the old one.
+	So keeping every version around, say for an undo history, costs O(log n) memory per change.
+
+	Because unchanged subtrees are literally the same nodes in both versions, Changes can tell two versions
+	apart by merge-walking them like Same does, while skipping every subtree the versions share.
+*/
+
+// Persistent is an immutable AVL balanced ordered set. The zero value is an empty set ready to use.
+type Persistent[K cmp.Ordered] struct {
+	base[K]
+}
+
+// NewPersistent returns a persistent set holding the given keys.
+func NewPersistent[K cmp.Ordered](keys ...K) *Persistent[K] {
+	p := &Persistent[K]{}
+	for _, k := range keys {
+		var added bool
+		if p.root, added = cowInsert(p.root, k); added {
+			p.len++
+		}
+	}
+	return p
+}
+
+// Insert returns a version of p that also holds key, p itself if key is already there.
+func (p *Persistent[K]) Insert(key K) *Persistent[K] {
+	root, added := cowInsert(p.root, key)
+	if !added {
+		return p
+	}
+	return &Persistent[K]{base[K]{root: root, len: p.len + 1}}
+}
+
+// Delete returns a version of p without key, p itself if key is not there.
+func (p *Persistent[K]) Delete(key K) *Persistent[K] {
+	root, removed := cowRemove(p.root, key)
+	if !removed {
+		return p
+	}
+	return &Persistent[K]{base[K]{root: root, len: p.len - 1}}
+}
+
+// Verify checks the BST and AVL invariants of p.
+func (p *Persistent[K]) Verify() error {
+	if err := p.verify(); err != nil {
+		return err
+	}
+	return verifyAVL(p.root)
+}
+
+// Changes yields the keys that differ between two versions in ascending order: Left for the keys only in from
+// (deleted on the way to to), Right for the ones only in to (inserted). Subtrees shared by both versions are
+// skipped without being visited, so comparing a version with its close relatives costs about
+// O(changes * log n) instead of O(n). Unrelated sets can be compared too, they just share nothing.
+func Changes[K cmp.Ordered](from, to *Persistent[K]) iter.Seq2[K, Side] {
+	return func(yield func(K, Side) bool) {
+		l, r := newFrontier(from.root), newFrontier(to.root)
+		for {
+			lt, lok := l.peek()
+			rt, rok := r.peek()
+			switch {
+			case !lok && !rok:
+				return
+			case lok && rok && lt.whole && rt.whole && lt.n == rt.n:
+				// the very same subtree is next on both sides, nothing in it can differ
+				l.pop()
+				r.pop()
+			case lok && lt.whole && (!rok || !rt.whole || height(lt.n) >= height(rt.n)):
+				l.expand()
+			case rok && rt.whole:
+				r.expand()
+			case !rok || lok && cmp.Less(lt.n.key, rt.n.key):
+				if !yield(lt.n.key, Left) {
+					return
+				}
+				l.pop()
+			case !lok || cmp.Less(rt.n.key, lt.n.key):
+				if !yield(rt.n.key, Right) {
+					return
+				}
+				r.pop()
+			default: // the same key on both sides
+				l.pop()
+				r.pop()
+			}
+		}
+	}
+}
+
+// frontier is what is left of an in-order walk, as a stack of whole subtrees still to walk and single keys.
+// Keeping subtrees whole for as long as possible is what lets Changes skip the shared ones.
+type frontier[K cmp.Ordered] struct {
+	stack []frontierItem[K]
+}
+
+type frontierItem[K cmp.Ordered] struct {
+	n     *Node[K]
+	whole bool // the whole subtree rooted at n, otherwise just the key of n
+}
+
+func newFrontier[K cmp.Ordered](root *Node[K]) *frontier[K] {
+	f := &frontier[K]{stack: make([]frontierItem[K], 0, 2*height(root)+1)}
+	if root != nil {
+		f.stack = append(f.stack, frontierItem[K]{root, true})
+	}
+	return f
+}
+
+func (f *frontier[K]) peek() (frontierItem[K], bool) {
+	if len(f.stack) == 0 {
+		return frontierItem[K]{}, false
+	}
+	return f.stack[len(f.stack)-1], true
+}
+
+func (f *frontier[K]) pop() { f.stack = f.stack[:len(f.stack)-1] }
+
+// expand replaces the whole subtree on top with its left subtree, its root key and its right subtree.
+func (f *frontier[K]) expand() {
+	top, _ := f.peek()
+	f.pop()
+	if top.n.right != nil {
+		f.stack = append(f.stack, frontierItem[K]{top.n.right, true})
+	}
+	f.stack = append(f.stack, frontierItem[K]{top.n, false})
+	if top.n.left != nil {
+		f.stack = append(f.stack, frontierItem[K]{top.n.left, true})
+	}
+}
+
+// History keeps the versions of a Persistent set for undo and redo, every version stays readable.
+type History[K cmp.Ordered] struct {
+	versions []*Persistent[K]
+	current  int
+}
+
+// NewHistory starts a history at the given version.
+func NewHistory[K cmp.Ordered](initial *Persistent[K]) *History[K] {
+	return &History[K]{versions: []*Persistent[K]{initial}}
+}
+
+// Current returns the version we are at.
+func (h *History[K]) Current() *Persistent[K] { return h.versions[h.current] }
+
+// Commit makes p the current version. Like in any editor, versions that were undone can not be redone anymore.
+func (h *History[K]) Commit(p *Persistent[K]) {
+	h.versions = append(h.versions[:h.current+1], p)
+	h.current++
+}
+
+// Undo steps back to the previous version and reports whether there was one.
+func (h *History[K]) Undo() bool {
+	if h.current == 0 {
+		return false
+	}
+	h.current--
+	return true
+}
+
+// Redo steps forward to the version undone last and reports whether there was one.
+func (h *History[K]) Redo() bool {
+	if h.current == len(h.versions)-1 {
+		return false
+	}
+	h.current++
+	return true
+}
+
+// Len returns the number of versions kept, including the undone ones that can still be redone.
+func (h *History[K]) Len() int { return len(h.versions) }
+
+// Version returns the i-th version, the initial one being 0.
+func (h *History[K]) Version(i int) *Persistent[K] { return h.versions[i] }
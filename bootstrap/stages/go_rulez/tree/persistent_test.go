@@ -0,0 +1,118 @@
+package tree
+
+import (
+	"fmt"
+	"math/rand"
+	"slices"
+	"testing"
+)
+
+// randomVersions applies n random inserts and deletes to an empty Persistent set and returns every version
+// along the way, together with the keys each of them held when it was made.
+func randomVersions(t *testing.T, n int) ([]*Persistent[int], [][]int) {
+	t.Helper()
+	v := NewPersistent[int]()
+	versions, keys := []*Persistent[int]{v}, [][]int{nil}
+	for range n {
+		k := rand.Intn(300)
+		next := v.Delete(k)
+		if rand.Intn(2) == 0 {
+			next = v.Insert(k)
+		}
+		if err := next.Verify(); err != nil {
+			t.Fatal(err)
+		}
+		v = next
+		versions, keys = append(versions, v), append(keys, slices.Collect(v.All()))
+	}
+	return versions, keys
+}
+
+func TestPersistentVersionsStayUnchanged(t *testing.T) {
+	versions, keys := randomVersions(t, 2000)
+	for i, v := range versions {
+		if got := slices.Collect(v.All()); !slices.Equal(got, keys[i]) || v.Len() != len(keys[i]) {
+			t.Fatalf("version %d holds %v after later changes, want %v", i, got, keys[i])
+		}
+		if err := v.Verify(); err != nil {
+			t.Fatalf("version %d: %v", i, err)
+		}
+	}
+
+	p := NewPersistent(1, 2, 3)
+	if p.Insert(2) != p || p.Delete(5) != p {
+		t.Fatal("a change that changes nothing made a new version")
+	}
+}
+
+func TestChanges(t *testing.T) {
+	versions, _ := randomVersions(t, 2000)
+	naive := func(from, to *Persistent[int]) []string {
+		inFrom, inTo := make(map[int]bool), make(map[int]bool)
+		for k := range from.All() {
+			inFrom[k] = true
+		}
+		for k := range to.All() {
+			inTo[k] = true
+		}
+		var diff []string
+		for k := range 300 {
+			if inFrom[k] && !inTo[k] {
+				diff = append(diff, fmt.Sprint("-", k))
+			} else if inTo[k] && !inFrom[k] {
+				diff = append(diff, fmt.Sprint("+", k))
+			}
+		}
+		return diff
+	}
+	changes := func(from, to *Persistent[int]) []string {
+		var diff []string
+		for k, side := range Changes(from, to) {
+			diff = append(diff, fmt.Sprint(map[Side]string{Left: "-", Right: "+"}[side], k))
+		}
+		return diff
+	}
+
+	for range 300 {
+		i, j := rand.Intn(len(versions)), rand.Intn(len(versions))
+		if got, want := changes(versions[i], versions[j]), naive(versions[i], versions[j]); !slices.Equal(got, want) {
+			t.Fatalf("Changes from version %d to %d = %v, want %v", i, j, got, want)
+		}
+	}
+	if got, want := changes(NewPersistent(1, 2, 3), NewPersistent(2, 3, 4)), []string{"-1", "+4"}; !slices.Equal(got, want) {
+		t.Fatalf("Changes of unrelated sets = %v, want %v", got, want)
+	}
+	for range Changes(NewPersistent(1, 2, 3), NewPersistent[int]()) {
+		break // stopping early must not walk on
+	}
+}
+
+func TestHistory(t *testing.T) {
+	h := NewHistory(NewPersistent[int]())
+	for k := 1; k <= 3; k++ {
+		h.Commit(h.Current().Insert(k))
+	}
+	for i := range h.Len() {
+		if got := h.Version(i).Len(); got != i {
+			t.Fatalf("version %d holds %d keys, want %d", i, got, i)
+		}
+	}
+
+	if !h.Undo() || !h.Undo() || h.Current().Len() != 1 {
+		t.Fatalf("after two undos the current version holds %v", slices.Collect(h.Current().All()))
+	}
+	if !h.Redo() || h.Current().Len() != 2 {
+		t.Fatalf("after a redo the current version holds %v", slices.Collect(h.Current().All()))
+	}
+
+	/* a commit after an undo drops the versions that could have been redone */
+	h.Commit(h.Current().Delete(1))
+	if h.Redo() || h.Len() != 4 || !slices.Equal(slices.Collect(h.Current().All()), []int{2}) {
+		t.Fatalf("after committing over an undo: %d versions, current %v", h.Len(), slices.Collect(h.Current().All()))
+	}
+	for h.Undo() {
+	}
+	if h.Current().Len() != 0 {
+		t.Fatal("undoing everything did not lead back to the initial version")
+	}
+}
@@ -0,0 +1,213 @@
+package tree
+
+import (
+	"cmp"
+	"fmt"
+)
+
+/*
+	RedBlack is a left-leaning red-black tree (Sedgewick, 2008), the simplest red-black variant to get right:
+	it is a 2-3 tree in disguise, where a 3-node is a black node with a red left child.
+
+	Invariants:
+		* the root is black
+		* red links lean left, a node never has a red right child
+		* no node has two red links in a row
+		* every path from the root to a nil link passes through the same number of black nodes
+
+	Together they keep the height below 2*log2(n).
+*/
+
+// RedBlack is a self-balancing binary search tree, see the comment above for its invariants.
+// The zero value is an empty tree ready to use.
+type RedBlack[K cmp.Ordered] struct {
+	base[K]
+}
+
+// NewRedBlack returns a red-black tree holding the given keys.
+func NewRedBlack[K cmp.Ordered](keys ...K) *RedBlack[K] {
+	t := &RedBlack[K]{}
+	for _, k := range keys {
+		t.Insert(k)
+	}
+	return t
+}
+
+// Insert adds key to t and reports whether it was not already there.
+func (t *RedBlack[K]) Insert(key K) bool {
+	var added bool
+	t.root, added = rbInsert(t.root, key)
+	t.root.red = false
+	if added {
+		t.len++
+	}
+	return added
+}
+
+// Delete removes key from t and reports whether it was there.
+func (t *RedBlack[K]) Delete(key K) bool {
+	if !t.Contains(key) { // rbRemove relies on key being in the tree
+		return false
+	}
+	if !isRed(t.root.left) && !isRed(t.root.right) {
+		t.root.red = true
+	}
+	t.root = rbRemove(t.root, key)
+	if t.root != nil {
+		t.root.red = false
+	}
+	t.len--
+	return true
+}
+
+// Verify checks the BST invariants of t and the red-black ones listed above.
+func (t *RedBlack[K]) Verify() error {
+	if err := t.verify(); err != nil {
+		return err
+	}
+	if isRed(t.root) {
+		return fmt.Errorf("tree: red-black root %v is red", t.root.key)
+	}
+	_, err := verifyRedBlack(t.root)
+	return err
+}
+
+func isRed[K cmp.Ordered](n *Node[K]) bool { return n != nil && n.red }
+
+func rbRotateLeft[K cmp.Ordered](n *Node[K]) *Node[K] {
+	r := rotateLeft(n)
+	r.red, n.red = n.red, true
+	return r
+}
+
+func rbRotateRight[K cmp.Ordered](n *Node[K]) *Node[K] {
+	l := rotateRight(n)
+	l.red, n.red = n.red, true
+	return l
+}
+
+// flipColors splits (or on the way down, merges) a temporary 4-node.
+func flipColors[K cmp.Ordered](n *Node[K]) {
+	n.red = !n.red
+	n.left.red = !n.left.red
+	n.right.red = !n.right.red
+}
+
+// rbFixUp restores the left-leaning invariants on the way back up and refreshes the cached fields of n.
+func rbFixUp[K cmp.Ordered](n *Node[K]) *Node[K] {
+	if isRed(n.right) && !isRed(n.left) {
+		n = rbRotateLeft(n)
+	}
+	if isRed(n.left) && isRed(n.left.left) {
+		n = rbRotateRight(n)
+	}
+	if isRed(n.left) && isRed(n.right) {
+		flipColors(n)
+	}
+	n.fix()
+	return n
+}
+
+func rbInsert[K cmp.Ordered](n *Node[K], key K) (*Node[K], bool) {
+	if n == nil {
+		return &Node[K]{key: key, height: 1, size: 1, red: true}, true
+	}
+	var added bool
+	switch c := cmp.Compare(key, n.key); {
+	case c < 0:
+		n.left, added = rbInsert(n.left, key)
+	case c > 0:
+		n.right, added = rbInsert(n.right, key)
+	default:
+		return n, false
+	}
+	return rbFixUp(n), added
+}
+
+// moveRedLeft makes n.left or one of its children red, assuming n is red and both n.left and n.left.left are black.
+func moveRedLeft[K cmp.Ordered](n *Node[K]) *Node[K] {
+	flipColors(n)
+	if isRed(n.right.left) {
+		n.right = rbRotateRight(n.right)
+		n = rbRotateLeft(n)
+		flipColors(n)
+	}
+	return n
+}
+
+// moveRedRight makes n.right or one of its children red, assuming n is red and both n.right and n.right.left are black.
+func moveRedRight[K cmp.Ordered](n *Node[K]) *Node[K] {
+	flipColors(n)
+	if isRed(n.left.left) {
+		n = rbRotateRight(n)
+		flipColors(n)
+	}
+	return n
+}
+
+func rbRemoveMin[K cmp.Ordered](n *Node[K]) *Node[K] {
+	if n.left == nil {
+		return nil
+	}
+	if !isRed(n.left) && !isRed(n.left.left) {
+		n = moveRedLeft(n)
+	}
+	n.left = rbRemoveMin(n.left)
+	return rbFixUp(n)
+}
+
+// rbRemove removes key, which must be in the subtree rooted at n. On the way down it keeps the current node
+// (or its left child) red, so the node that is finally removed is never a lonely black one.
+func rbRemove[K cmp.Ordered](n *Node[K], key K) *Node[K] {
+	if cmp.Less(key, n.key) {
+		if !isRed(n.left) && !isRed(n.left.left) {
+			n = moveRedLeft(n)
+		}
+		n.left = rbRemove(n.left, key)
+	} else {
+		if isRed(n.left) {
+			n = rbRotateRight(n)
+		}
+		if cmp.Compare(key, n.key) == 0 && n.right == nil {
+			return nil
+		}
+		if !isRed(n.right) && !isRed(n.right.left) {
+			n = moveRedRight(n)
+		}
+		if cmp.Compare(key, n.key) == 0 {
+			n.key = n.right.min().key
+			n.right = rbRemoveMin(n.right)
+		} else {
+			n.right = rbRemove(n.right, key)
+		}
+	}
+	return rbFixUp(n)
+}
+
+// verifyRedBlack returns the black height of the subtree rooted at n.
+func verifyRedBlack[K cmp.Ordered](n *Node[K]) (int, error) {
+	if n == nil {
+		return 1, nil
+	}
+	if isRed(n.right) {
+		return 0, fmt.Errorf("tree: red-black node %v has a red right child", n.key)
+	}
+	if isRed(n) && isRed(n.left) {
+		return 0, fmt.Errorf("tree: red-black node %v and its left child are both red", n.key)
+	}
+	lb, err := verifyRedBlack(n.left)
+	if err != nil {
+		return 0, err
+	}
+	rb, err := verifyRedBlack(n.right)
+	if err != nil {
+		return 0, err
+	}
+	if lb != rb {
+		return 0, fmt.Errorf("tree: red-black node %v has black heights %d and %d", n.key, lb, rb)
+	}
+	if !isRed(n) {
+		lb++
+	}
+	return lb, nil
+}
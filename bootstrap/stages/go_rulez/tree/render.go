@@ -0,0 +1,81 @@
+package tree
+
+import (
+	"cmp"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// String draws the set sideways with ASCII, see ASCII.
+func (t *base[K]) String() string { return ASCII(t.root) }
+
+// ASCII draws the subtree rooted at n sideways, root on the left and the right subtree on top,
+// so that reading the keys from the bottom up gives the in-order walk:
+//
+//	    /-- 6
+//	    |   \-- 5
+//	4
+//	    \-- 2
+func ASCII[K cmp.Ordered](n *Node[K]) string {
+	if n == nil {
+		return "(empty)\n"
+	}
+	var b strings.Builder
+	drawASCII(&b, n, "", "")
+	return b.String()
+}
+
+// drawASCII draws n after prefix and edge, a right child hangs above its parent (/--) and a left one below (\--).
+func drawASCII[K cmp.Ordered](b *strings.Builder, n *Node[K], prefix, edge string) {
+	// children of n are indented by one more level, and the bar keeps going where it has to reach back to the parent
+	above, below := prefix+"    ", prefix+"    "
+	switch edge {
+	case "/-- ":
+		below = prefix + "|   "
+	case "\\-- ":
+		above = prefix + "|   "
+	}
+	if n.right != nil {
+		drawASCII(b, n.right, above, "/-- ")
+	}
+	fmt.Fprintf(b, "%s%s%v\n", prefix, edge, n.key)
+	if n.left != nil {
+		drawASCII(b, n.left, below, "\\-- ")
+	}
+}
+
+// DOT renders the subtree rooted at n as a Graphviz digraph named name, pipe it to `dot -Tpng` to look at it.
+// Missing children are drawn as small points so left and right children stay on their side,
+// links to red nodes of a RedBlack tree are drawn red.
+func DOT[K cmp.Ordered](n *Node[K], name string) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "digraph %s {\n\tnode [shape=circle];\n", strconv.Quote(name))
+	id := 0
+	var draw func(n *Node[K]) int
+	draw = func(n *Node[K]) int {
+		me := id
+		id++
+		if n == nil {
+			fmt.Fprintf(&b, "\tn%d [shape=point];\n", me)
+			return me
+		}
+		fmt.Fprintf(&b, "\tn%d [label=%s];\n", me, strconv.Quote(fmt.Sprint(n.key)))
+		if n.left == nil && n.right == nil {
+			return me
+		}
+		for _, child := range []*Node[K]{n.left, n.right} {
+			attrs := ""
+			if child != nil && child.red {
+				attrs = " [color=red]"
+			}
+			fmt.Fprintf(&b, "\tn%d -> n%d%s;\n", me, draw(child), attrs)
+		}
+		return me
+	}
+	if n != nil {
+		draw(n)
+	}
+	b.WriteString("}\n")
+	return b.String()
+}
@@ -0,0 +1,74 @@
+package tree
+
+import "testing"
+
+func TestASCII(t *testing.T) {
+	tests := []struct {
+		name string
+		set  OrderedSet[int]
+		want string
+	}{
+		{"empty", New[int](), "(empty)\n"},
+		{"single", New(1), "1\n"},
+		{"bst", New(4, 2, 6, 5, 1, 3), "" +
+			"    /-- 6\n" +
+			"    |   \\-- 5\n" +
+			"4\n" +
+			"    |   /-- 3\n" +
+			"    \\-- 2\n" +
+			"        \\-- 1\n"},
+		{"red-black", NewRedBlack(1, 2, 3, 4, 5), "" +
+			"    /-- 5\n" +
+			"4\n" +
+			"    |   /-- 3\n" +
+			"    \\-- 2\n" +
+			"        \\-- 1\n"},
+	}
+	for _, tt := range tests {
+		if got := tt.set.(interface{ String() string }).String(); got != tt.want {
+			t.Errorf("%s: got\n%s\nwant\n%s", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestDOT(t *testing.T) {
+	tests := []struct {
+		name string
+		got  string
+		want string
+	}{
+		{"empty", DOT(New[int]().Root(), "empty"), "digraph \"empty\" {\n\tnode [shape=circle];\n}\n"},
+		{"missing child", DOT(New(2, 1).Root(), "t"), `digraph "t" {
+	node [shape=circle];
+	n0 [label="2"];
+	n1 [label="1"];
+	n0 -> n1;
+	n2 [shape=point];
+	n0 -> n2;
+}
+`},
+		{"red links", DOT(NewRedBlack(1, 2, 3, 4, 5).Root(), "rb"), `digraph "rb" {
+	node [shape=circle];
+	n0 [label="4"];
+	n1 [label="2"];
+	n2 [label="1"];
+	n1 -> n2;
+	n3 [label="3"];
+	n1 -> n3;
+	n0 -> n1 [color=red];
+	n4 [label="5"];
+	n0 -> n4;
+}
+`},
+		{"quotes", DOT(New(`a "b"`).Root(), `x"y`), `digraph "x\"y" {
+	node [shape=circle];
+	n0 [label="a \"b\""];
+}
+`},
+	}
+	for _, tt := range tests {
+		if tt.got != tt.want {
+			t.Errorf("%s: got\n%s\nwant\n%s", tt.name, tt.got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,184 @@
+package tree
+
+import (
+	"cmp"
+	"fmt"
+	"iter"
+)
+
+// OrderedSet is the common interface of all tree variants of this package: Tree, AVL, RedBlack and Concurrent.
+type OrderedSet[K cmp.Ordered] interface {
+	// Insert adds key to the set and reports whether it was not already there.
+	Insert(key K) bool
+	// Delete removes key from the set and reports whether it was there.
+	Delete(key K) bool
+	Contains(key K) bool
+	Min() (K, bool)
+	Max() (K, bool)
+	Floor(key K) (K, bool)
+	Ceiling(key K) (K, bool)
+	Len() int
+	Height() int
+	// All iterates over the keys in ascending order.
+	All() iter.Seq[K]
+	Select(i int) (K, bool)
+	Rank(key K) int
+	CountRange(lo, hi K) int
+	Range(lo, hi K) iter.Seq[K]
+	// Root exposes the shape of the underlying tree for walkers.
+	Root() *Node[K]
+	// Verify checks the invariants of the variant and returns the first violation found.
+	Verify() error
+}
+
+// base holds what every variant has in common: the root, the number of keys and
+// the read only queries, which do not care how the tree was balanced.
+type base[K cmp.Ordered] struct {
+	root *Node[K]
+	len  int
+}
+
+// Root returns the root node of the set, nil if the set is empty.
+func (t *base[K]) Root() *Node[K] { return t.root }
+
+// Len returns the number of keys in the set.
+func (t *base[K]) Len() int { return t.len }
+
+// Height returns the number of levels of the underlying tree, 0 for an empty set.
+func (t *base[K]) Height() int { return height(t.root) }
+
+// Contains reports whether key is in the set.
+func (t *base[K]) Contains(key K) bool {
+	for n := t.root; n != nil; {
+		switch c := cmp.Compare(key, n.key); {
+		case c < 0:
+			n = n.left
+		case c > 0:
+			n = n.right
+		default:
+			return true
+		}
+	}
+	return false
+}
+
+// Min returns the smallest key in the set, ok is false if the set is empty.
+func (t *base[K]) Min() (key K, ok bool) {
+	if t.root == nil {
+		return key, false
+	}
+	return t.root.min().key, true
+}
+
+// Max returns the largest key in the set, ok is false if the set is empty.
+func (t *base[K]) Max() (key K, ok bool) {
+	if t.root == nil {
+		return key, false
+	}
+	return t.root.max().key, true
+}
+
+// Floor returns the largest key in the set that is less than or equal to key, ok is false if there is none.
+func (t *base[K]) Floor(key K) (floor K, ok bool) {
+	for n := t.root; n != nil; {
+		switch c := cmp.Compare(key, n.key); {
+		case c < 0:
+			n = n.left
+		case c > 0:
+			floor, ok = n.key, true // a candidate, but there may be a closer one on the right
+			n = n.right
+		default:
+			return n.key, true
+		}
+	}
+	return floor, ok
+}
+
+// Ceiling returns the smallest key in the set that is greater than or equal to key, ok is false if there is none.
+func (t *base[K]) Ceiling(key K) (ceil K, ok bool) {
+	for n := t.root; n != nil; {
+		switch c := cmp.Compare(key, n.key); {
+		case c < 0:
+			ceil, ok = n.key, true // a candidate, but there may be a closer one on the left
+			n = n.left
+		case c > 0:
+			n = n.right
+		default:
+			return n.key, true
+		}
+	}
+	return ceil, ok
+}
+
+// verify checks the invariants shared by all variants: keys are strictly ordered in-order,
+// cached heights and sizes are right and len matches the number of nodes.
+func (t *base[K]) verify() error {
+	n, err := verify(t.root, nil, nil)
+	if err != nil {
+		return err
+	}
+	if n != t.len {
+		return fmt.Errorf("tree: Len is %d but there are %d nodes", t.len, n)
+	}
+	return nil
+}
+
+// Checked wraps s so that its invariants are verified after every mutation,
+// it panics with the violation as soon as one shows up. Meant for tests and debugging, not for production.
+func Checked[K cmp.Ordered](s OrderedSet[K]) OrderedSet[K] {
+	return checked[K]{s}
+}
+
+type checked[K cmp.Ordered] struct {
+	OrderedSet[K]
+}
+
+func (c checked[K]) Insert(key K) bool {
+	added := c.OrderedSet.Insert(key)
+	c.mustVerify("Insert", key)
+	return added
+}
+
+func (c checked[K]) Delete(key K) bool {
+	removed := c.OrderedSet.Delete(key)
+	c.mustVerify("Delete", key)
+	return removed
+}
+
+func (c checked[K]) mustVerify(op string, key K) {
+	if err := c.Verify(); err != nil {
+		panic(fmt.Sprintf("tree: invariants broken after %s(%v): %v", op, key, err))
+	}
+}
+
+// verify walks the subtree rooted at n, every key must lie strictly between lo and hi (nil means unbounded).
+func verify[K cmp.Ordered](n *Node[K], lo, hi *K) (int, error) {
+	if n == nil {
+		return 0, nil
+	}
+	if lo != nil && cmp.Compare(n.key, *lo) <= 0 || hi != nil && cmp.Compare(n.key, *hi) >= 0 {
+		return 0, fmt.Errorf("tree: key %v is out of order", n.key)
+	}
+	ln, err := verify(n.left, lo, &n.key)
+	if err != nil {
+		return 0, err
+	}
+	rn, err := verify(n.right, &n.key, hi)
+	if err != nil {
+		return 0, err
+	}
+	if want := 1 + max(height(n.left), height(n.right)); n.height != want {
+		return 0, fmt.Errorf("tree: node %v caches height %d, want %d", n.key, n.height, want)
+	}
+	if want := 1 + ln + rn; n.size != want {
+		return 0, fmt.Errorf("tree: node %v caches size %d, want %d", n.key, n.size, want)
+	}
+	return 1 + ln + rn, nil
+}
+
+var (
+	_ OrderedSet[int] = (*Tree[int])(nil)
+	_ OrderedSet[int] = (*AVL[int])(nil)
+	_ OrderedSet[int] = (*RedBlack[int])(nil)
+	_ OrderedSet[int] = (*Concurrent[int])(nil)
+)
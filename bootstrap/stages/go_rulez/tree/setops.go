@@ -0,0 +1,176 @@
+package tree
+
+import (
+	"cmp"
+	"fmt"
+	"iter"
+	"strings"
+)
+
+/*
+	Set operations by merge-walking two in-order streams.
+
+	Same in walk.go walks both trees in their own goroutines and compares them
+	value by value. MergeWalk does the same, only with iter.Pull: each walk runs as a coroutine and hands us
+	one key at a time, so there is no channel in between and stopping early releases both walks.
+	Only the two walk stacks are kept in memory, O(height) per tree, no matter how large the trees are.
+
+	Everything here takes plain ascending iter.Seq[K] streams (such as OrderedSet.All), so the
+	two sides do not even have to be the same variant.
+*/
+
+// Side tells which of the two merged streams a key was found in.
+type Side uint8
+
+const (
+	Left Side = 1 << iota
+	Right
+	Both = Left | Right
+)
+
+func (s Side) String() string {
+	switch s {
+	case Left:
+		return "left"
+	case Right:
+		return "right"
+	case Both:
+		return "both"
+	}
+	return fmt.Sprintf("Side(%d)", uint8(s))
+}
+
+// MergeWalk merges two strictly ascending streams lazily and yields every distinct key once,
+// along with the side(s) it was found in.
+func MergeWalk[K cmp.Ordered](left, right iter.Seq[K]) iter.Seq2[K, Side] {
+	return func(yield func(K, Side) bool) {
+		nextL, stopL := iter.Pull(left)
+		defer stopL()
+		nextR, stopR := iter.Pull(right)
+		defer stopR()
+
+		l, okL := nextL()
+		r, okR := nextR()
+		for okL || okR {
+			var c int
+			switch {
+			case !okL:
+				c = 1
+			case !okR:
+				c = -1
+			default:
+				c = cmp.Compare(l, r)
+			}
+			switch {
+			case c < 0:
+				if !yield(l, Left) {
+					return
+				}
+				l, okL = nextL()
+			case c > 0:
+				if !yield(r, Right) {
+					return
+				}
+				r, okR = nextR()
+			default:
+				if !yield(l, Both) {
+					return
+				}
+				l, okL = nextL()
+				r, okR = nextR()
+			}
+		}
+	}
+}
+
+// filter keeps the merged keys whose side is one of want.
+func filter[K cmp.Ordered](left, right iter.Seq[K], want ...Side) iter.Seq[K] {
+	return func(yield func(K) bool) {
+		for k, side := range MergeWalk(left, right) {
+			for _, w := range want {
+				if side == w {
+					if !yield(k) {
+						return
+					}
+					break
+				}
+			}
+		}
+	}
+}
+
+// Intersection yields the keys found in both streams, in ascending order.
+func Intersection[K cmp.Ordered](left, right iter.Seq[K]) iter.Seq[K] {
+	return filter(left, right, Both)
+}
+
+// Union yields the keys found in either stream, in ascending order.
+func Union[K cmp.Ordered](left, right iter.Seq[K]) iter.Seq[K] {
+	return filter(left, right, Left, Right, Both)
+}
+
+// SymmetricDifference yields the keys found in exactly one of the streams, in ascending order.
+func SymmetricDifference[K cmp.Ordered](left, right iter.Seq[K]) iter.Seq[K] {
+	return filter(left, right, Left, Right)
+}
+
+// Difference yields the keys found in left but not in right, in ascending order.
+// Swap the arguments to get the ones only found in right.
+func Difference[K cmp.Ordered](left, right iter.Seq[K]) iter.Seq[K] {
+	return filter(left, right, Left)
+}
+
+// Diff summarizes how two sets differ. Counts are exact, but only the first few
+// keys of each side are kept as examples so that a Diff of huge sets stays small.
+type Diff[K cmp.Ordered] struct {
+	Common    int // keys found in both sets
+	OnlyLeft  int // keys found in the left set only
+	OnlyRight int // keys found in the right set only
+
+	LeftExamples  []K // the smallest keys found in the left set only, at most the limit given to Compare
+	RightExamples []K // the smallest keys found in the right set only, at most the limit given to Compare
+}
+
+// Equal reports whether the two compared sets hold the same keys.
+func (d Diff[K]) Equal() bool { return d.OnlyLeft == 0 && d.OnlyRight == 0 }
+
+func (d Diff[K]) String() string {
+	if d.Equal() {
+		return fmt.Sprintf("equal sets of %d keys", d.Common)
+	}
+	var b strings.Builder
+	fmt.Fprintf(&b, "%d common keys", d.Common)
+	if d.OnlyLeft > 0 {
+		fmt.Fprintf(&b, ", %d only in left %v", d.OnlyLeft, d.LeftExamples)
+		if d.OnlyLeft > len(d.LeftExamples) {
+			b.WriteString("...")
+		}
+	}
+	if d.OnlyRight > 0 {
+		fmt.Fprintf(&b, ", %d only in right %v", d.OnlyRight, d.RightExamples)
+		if d.OnlyRight > len(d.RightExamples) {
+			b.WriteString("...")
+		}
+	}
+	return b.String()
+}
+
+// Compare merge-walks both streams and reports how they differ, keeping at most limit example keys per side.
+func Compare[K cmp.Ordered](left, right iter.Seq[K], limit int) Diff[K] {
+	var d Diff[K]
+	for k, side := range MergeWalk(left, right) {
+		switch side {
+		case Both:
+			d.Common++
+		case Left:
+			if d.OnlyLeft++; len(d.LeftExamples) < limit {
+				d.LeftExamples = append(d.LeftExamples, k)
+			}
+		case Right:
+			if d.OnlyRight++; len(d.RightExamples) < limit {
+				d.RightExamples = append(d.RightExamples, k)
+			}
+		}
+	}
+	return d
+}
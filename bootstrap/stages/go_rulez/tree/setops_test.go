@@ -0,0 +1,119 @@
+package tree
+
+import (
+	"fmt"
+	"iter"
+	"slices"
+	"testing"
+	"testing/quick"
+)
+
+// setOps builds an AVL and a red-black tree of arbitrary keys and checks every set operation of the
+// merge walk against the same operation on plain maps.
+func setOps(left, right []int8, limit uint8) bool {
+	l, r := NewAVL(left...), NewRedBlack(right...)
+	inL, inR := make(map[int8]bool), make(map[int8]bool)
+	for _, k := range left {
+		inL[k] = true
+	}
+	for _, k := range right {
+		inR[k] = true
+	}
+
+	var both, either, exactlyOne, onlyL, onlyR []int8
+	for k := -128; k < 128; k++ {
+		k := int8(k)
+		switch {
+		case inL[k] && inR[k]:
+			both = append(both, k)
+		case inL[k]:
+			onlyL = append(onlyL, k)
+		case inR[k]:
+			onlyR = append(onlyR, k)
+		default:
+			continue
+		}
+		either = append(either, k)
+		if inL[k] != inR[k] {
+			exactlyOne = append(exactlyOne, k)
+		}
+	}
+	for _, c := range []struct {
+		got  iter.Seq[int8]
+		want []int8
+	}{
+		{Intersection(l.All(), r.All()), both},
+		{Union(l.All(), r.All()), either},
+		{SymmetricDifference(l.All(), r.All()), exactlyOne},
+		{Difference(l.All(), r.All()), onlyL},
+		{Difference(r.All(), l.All()), onlyR},
+	} {
+		if !slices.Equal(slices.Collect(c.got), c.want) {
+			return false
+		}
+	}
+
+	n := int(limit % 5)
+	d := Compare(l.All(), r.All(), n)
+	return d.Common == len(both) && d.OnlyLeft == len(onlyL) && d.OnlyRight == len(onlyR) &&
+		slices.Equal(d.LeftExamples, onlyL[:min(n, len(onlyL))]) && slices.Equal(d.RightExamples, onlyR[:min(n, len(onlyR))]) &&
+		d.Equal() == (len(exactlyOne) == 0)
+}
+
+func TestSetOps(t *testing.T) {
+	if err := quick.Check(setOps, &quick.Config{MaxCount: 1000}); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestMergeWalk(t *testing.T) {
+	var got []string
+	for k, side := range MergeWalk(New(1, 3, 5).All(), New(2, 3, 6).All()) {
+		got = append(got, fmt.Sprintf("%d:%v", k, side))
+	}
+	want := []string{"1:left", "2:right", "3:both", "5:left", "6:right"}
+	if !slices.Equal(got, want) {
+		t.Fatalf("MergeWalk = %v, want %v", got, want)
+	}
+}
+
+// tracked yields the keys of t and records once its walk has returned.
+func tracked(t *Tree[int], returned *bool) iter.Seq[int] {
+	return func(yield func(int) bool) {
+		defer func() { *returned = true }()
+		for k := range t.All() {
+			if !yield(k) {
+				return
+			}
+		}
+	}
+}
+
+func TestMergeWalkReleasesWalksOnBreak(t *testing.T) {
+	var leftDone, rightDone bool
+	for k := range Union(tracked(New(1, 2, 3), &leftDone), tracked(New(2, 4), &rightDone)) {
+		if k == 2 {
+			break
+		}
+	}
+	if !leftDone || !rightDone {
+		t.Fatalf("walks returned: left %v, right %v, want both after the loop stopped", leftDone, rightDone)
+	}
+}
+
+func TestDiffString(t *testing.T) {
+	tests := []struct {
+		left, right *Tree[int]
+		want        string
+	}{
+		{New(1, 2, 3), New(3, 2, 1), "equal sets of 3 keys"},
+		{New[int](), New[int](), "equal sets of 0 keys"},
+		{New(1, 2, 3), New(2), "1 common keys, 2 only in left [1 3]"},
+		{New(1, 2, 3, 4, 5), New(5, 6), "1 common keys, 4 only in left [1 2]..., 1 only in right [6]"},
+	}
+	for _, tt := range tests {
+		if got := Compare(tt.left.All(), tt.right.All(), 2).String(); got != tt.want {
+			t.Errorf("Compare(%v, %v) = %q, want %q", slices.Collect(tt.left.All()), slices.Collect(tt.right.All()), got, tt.want)
+		}
+	}
+}
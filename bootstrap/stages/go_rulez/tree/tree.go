@@ -0,0 +1,92 @@
+// Package tree is our own replacement for golang.org/x/tour/tree.
+//
+// The tour only gives us tree.New(k), a randomly shaped tree of k, 2k, ..., 10k.
+// Tree is a generic binary search tree over any cmp.Ordered key, so we can build whatever shapes we need
+// and still Walk them (see walk.go).
+//
+// Random or sorted inserts can degrade a plain Tree to a linked list, so AVL and RedBlack keep
+// themselves balanced. All three implement OrderedSet.
+package tree
+
+import "cmp"
+
+// Tree is an unbalanced binary search tree holding unique keys. The zero value is an empty tree ready to use.
+type Tree[K cmp.Ordered] struct {
+	base[K]
+}
+
+// New returns a tree holding the given keys, inserted in the given order.
+func New[K cmp.Ordered](keys ...K) *Tree[K] {
+	t := &Tree[K]{}
+	for _, k := range keys {
+		t.Insert(k)
+	}
+	return t
+}
+
+// Insert adds key to t and reports whether it was not already there.
+func (t *Tree[K]) Insert(key K) bool {
+	var added bool
+	t.root, added = insert(t.root, key)
+	if added {
+		t.len++
+	}
+	return added
+}
+
+// Delete removes key from t and reports whether it was there.
+func (t *Tree[K]) Delete(key K) bool {
+	var removed bool
+	t.root, removed = remove(t.root, key)
+	if removed {
+		t.len--
+	}
+	return removed
+}
+
+// Verify checks the invariants of t: keys are strictly ordered in-order, cached heights and sizes are right
+// and Len matches the number of nodes. It returns a descriptive error for the first violation found.
+func (t *Tree[K]) Verify() error { return t.verify() }
+
+func insert[K cmp.Ordered](n *Node[K], key K) (*Node[K], bool) {
+	if n == nil {
+		return &Node[K]{key: key, height: 1, size: 1}, true
+	}
+	var added bool
+	switch c := cmp.Compare(key, n.key); {
+	case c < 0:
+		n.left, added = insert(n.left, key)
+	case c > 0:
+		n.right, added = insert(n.right, key)
+	default:
+		return n, false
+	}
+	n.fix()
+	return n, added
+}
+
+func remove[K cmp.Ordered](n *Node[K], key K) (*Node[K], bool) {
+	if n == nil {
+		return nil, false
+	}
+	var removed bool
+	switch c := cmp.Compare(key, n.key); {
+	case c < 0:
+		n.left, removed = remove(n.left, key)
+	case c > 0:
+		n.right, removed = remove(n.right, key)
+	default:
+		if n.left == nil {
+			return n.right, true
+		} else if n.right == nil {
+			return n.left, true
+		}
+		// Two children: take over the in-order successor's key and remove the successor instead.
+		succ := n.right.min()
+		n.key = succ.key
+		n.right, _ = remove(n.right, succ.key)
+		removed = true
+	}
+	n.fix()
+	return n, removed
+}
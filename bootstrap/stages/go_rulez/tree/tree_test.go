@@ -0,0 +1,88 @@
+package tree
+
+import (
+	"math/rand"
+	"slices"
+	"testing"
+	"testing/quick"
+
+	"github.com/ysyesilyurt/lets-go/bootstrap/stages/go_rulez/leakcheck"
+)
+
+// bstInvariants inserts and then deletes arbitrary keys, checking the tree against a plain sorted slice.
+func bstInvariants(inserts, deletes []int8, probe int8) bool {
+	t := New(inserts...)
+	for _, k := range deletes {
+		t.Delete(k)
+	}
+	var want []int8
+	for _, k := range inserts {
+		if !slices.Contains(deletes, k) && !slices.Contains(want, k) {
+			want = append(want, k)
+		}
+	}
+	slices.Sort(want)
+
+	if t.Verify() != nil || t.Len() != len(want) || t.Contains(probe) != slices.Contains(want, probe) {
+		return false
+	}
+	// Floor and Ceiling must agree with a binary search on the slice
+	i, found := slices.BinarySearch(want, probe)
+	floor, okf := t.Floor(probe)
+	ceil, okc := t.Ceiling(probe)
+	switch {
+	case found:
+		return okf && okc && floor == probe && ceil == probe
+	case i == 0 && okf, i > 0 && (!okf || floor != want[i-1]):
+		return false
+	case i == len(want) && okc, i < len(want) && (!okc || ceil != want[i]):
+		return false
+	}
+	return true
+}
+
+func TestBSTInvariants(t *testing.T) {
+	if err := quick.Check(bstInvariants, &quick.Config{MaxCount: 1000}); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestWalk(t *testing.T) {
+	leakcheck.Check(t, leakcheck.Config{})
+	for n := range 20 {
+		ch := make(chan int)
+		go Walk(New(rand.Perm(n)...), ch)
+		var got []int
+		for k := range ch {
+			got = append(got, k)
+		}
+		if len(got) != n || !slices.IsSorted(got) || n > 0 && got[n-1] != n-1 {
+			t.Fatalf("Walk of %d keys gave %v", n, got)
+		}
+	}
+}
+
+func TestSame(t *testing.T) {
+	leakcheck.Check(t, leakcheck.Config{})
+	tests := []struct {
+		name   string
+		t1, t2 *Tree[int]
+		want   bool
+	}{
+		{"both empty", New[int](), New[int](), true},
+		{"one empty", New(1), New[int](), false},
+		{"same keys, other shape", New(1, 2, 3, 4, 5), New(3, 1, 5, 2, 4), true},
+		{"differ at the start", New(1, 2, 3), New(0, 2, 3), false},
+		{"differ at the end", New(1, 2, 3), New(1, 2, 4), false},
+		{"prefix", New(1, 2), New(1, 2, 3), false},
+		{"tour trees", New(rand.Perm(10)...), New(rand.Perm(10)...), true},
+	}
+	for _, tt := range tests {
+		if got := Same(tt.t1, tt.t2); got != tt.want {
+			t.Errorf("%s: Same = %v, want %v", tt.name, got, tt.want)
+		}
+		if got := Same(tt.t2, tt.t1); got != tt.want {
+			t.Errorf("%s, swapped: Same = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,66 @@
+package tree
+
+import "cmp"
+
+/*
+	Walk and Same.
+
+	The "Equivalent Binary Trees" exercise of the Go tour on our own trees (concurrent_binary_tree_checker.go runs them):
+	Walk sends the keys of a tree down a channel, Same walks two trees in their own goroutines and compares them
+	key by key.
+
+	Same returns at the first difference. A walker that is not told about it stays blocked on its next send
+	for good, nobody will ever receive it. So Same hands its walkers a quit channel and closes it on return,
+	a walker blocked on a send gives up as soon as it is closed.
+*/
+
+// Walk sends the keys of t to ch in ascending order and closes ch, like Walk of the tour exercise.
+// It only returns once every key was received, a walk that may be abandoned halfway needs a quit channel as in Same.
+func Walk[K cmp.Ordered](t *Tree[K], ch chan<- K) {
+	walk(t.root, ch, nil)
+	close(ch)
+}
+
+// walk sends the keys under n to ch in ascending order until quit is closed, and reports whether it got through.
+func walk[K cmp.Ordered](n *Node[K], ch chan<- K, quit <-chan struct{}) bool {
+	if n == nil {
+		return true
+	}
+	if !walk(n.left, ch, quit) {
+		return false
+	}
+	select {
+	case ch <- n.key:
+	case <-quit:
+		return false
+	}
+	return walk(n.right, ch, quit)
+}
+
+// Same reports whether t1 and t2 hold the same keys, whatever their shapes. Both are walked concurrently,
+// and both walkers return along with Same, also when it returns at the first difference.
+func Same[K cmp.Ordered](t1, t2 *Tree[K]) bool {
+	quit := make(chan struct{})
+	defer close(quit)
+	ch1, ch2 := make(chan K), make(chan K)
+	for _, w := range []struct {
+		t  *Tree[K]
+		ch chan K
+	}{{t1, ch1}, {t2, ch2}} {
+		go func() {
+			walk(w.t.root, w.ch, quit)
+			close(w.ch)
+		}()
+	}
+	for {
+		/* Both walks are in order, so just compare them key by key until one of them runs out */
+		k1, ok1 := <-ch1
+		k2, ok2 := <-ch2
+		if ok1 != ok2 || k1 != k2 {
+			return false
+		}
+		if !ok1 {
+			return true
+		}
+	}
+}
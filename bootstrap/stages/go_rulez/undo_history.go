@@ -0,0 +1,42 @@
+package main
+
+/* Persistent trees
+A tree.Persistent is never changed: Insert and Delete hand back a new version that shares all untouched nodes
+with the old one. That makes an undo history almost free, and tree.Changes compares two versions by
+merge-walking them (the idea behind Same) while skipping every subtree they share.
+*/
+
+import (
+	"fmt"
+	"slices"
+
+	"github.com/ysyesilyurt/lets-go/bootstrap/stages/go_rulez/tree"
+)
+
+func main() {
+	/* Say an editor keeps the set of bookmarked line numbers, every edit is a new version */
+	history := tree.NewHistory(tree.NewPersistent[int]())
+	for line := 1; line <= 100000; line += 10 {
+		history.Commit(history.Current().Insert(line))
+	}
+	before := history.Current()
+	history.Commit(before.Delete(501).Insert(502))
+	history.Commit(history.Current().Insert(777))
+	after := history.Current()
+
+	fmt.Println("versions:", history.Len(), "bookmarks now:", after.Len(), "before:", before.Len())
+	for line, side := range tree.Changes(before, after) {
+		if side == tree.Left {
+			fmt.Println("  removed bookmark", line)
+		} else {
+			fmt.Println("  added bookmark", line)
+		}
+	}
+
+	history.Undo()
+	history.Undo()
+	fmt.Println("after two undos we are back at the same version:", history.Current() == before)
+	history.Redo()
+	fmt.Println("redo brings back", slices.Collect(history.Current().Range(500, 510)))
+	fmt.Println("the first version is still there and empty:", history.Version(0).Len() == 0)
+}
@@ -0,0 +1,85 @@
+package main
+
+/* Worker pools
+A goroutine per task is cheap, but a million tasks each opening a connection or a file are not.
+pool.Pool runs them on a fixed number of workers instead, with a bounded queue in front:
+	* Submit blocks while the queue is full, TrySubmit rejects the task with pool.ErrQueueFull
+	* pool.Go returns a Future for tasks with a result
+	* the first task to fail cancels the others, like golang.org/x/sync/errgroup
+	* Wait and Close wait for the tasks, no time.Sleep needed
+*/
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync/atomic"
+	"time"
+
+	"github.com/ysyesilyurt/lets-go/bootstrap/stages/go_rulez/pool"
+)
+
+// fib is as slow as it gets, which makes it good work for a pool.
+func fib(n int) int {
+	if n < 2 {
+		return n
+	}
+	return fib(n-1) + fib(n-2)
+}
+
+func main() {
+	ctx := context.Background()
+
+	/* Futures: submit everything first, collect the results in order afterwards */
+	p := pool.New(ctx, pool.Config{Workers: 4, QueueSize: 32})
+	var futures []*pool.Future[int]
+	for n := 20; n < 32; n++ {
+		f, err := pool.Go(ctx, p, func(context.Context) (int, error) { return fib(n), nil })
+		if err != nil {
+			fmt.Println("could not submit:", err)
+			return
+		}
+		futures = append(futures, f)
+	}
+	for i, f := range futures {
+		v, _ := f.Get(ctx)
+		fmt.Printf("fib(%d) = %d\n", 20+i, v)
+	}
+	p.Close()
+
+	/* A full queue: TrySubmit turns work away instead of blocking, Submit waits for room */
+	p = pool.New(ctx, pool.Config{Workers: 1, QueueSize: 2})
+	slow := func(context.Context) error { time.Sleep(20 * time.Millisecond); return nil }
+	started := make(chan struct{})
+	p.Submit(ctx, func(ctx context.Context) error { close(started); return slow(ctx) })
+	<-started // the only worker is busy now, so the queue fills up
+	for i := 0; i < 4; i++ {
+		fmt.Printf("TrySubmit %d: %v\n", i, p.TrySubmit(slow))
+	}
+	start := time.Now()
+	p.Submit(ctx, slow)
+	fmt.Printf("Submit waited %v for room in the queue\n", time.Since(start).Round(10*time.Millisecond))
+	p.Close()
+
+	/* First error cancels the pool: the running tasks see ctx done, the queued ones never start */
+	p = pool.New(ctx, pool.Config{Workers: 4, QueueSize: 100})
+	var ran, canceled atomic.Int32
+	for i := 0; i < 100; i++ {
+		p.Submit(ctx, func(ctx context.Context) error {
+			ran.Add(1)
+			if i == 10 {
+				return errors.New("task 10 failed")
+			}
+			select {
+			case <-time.After(10 * time.Millisecond):
+				return nil
+			case <-ctx.Done():
+				canceled.Add(1)
+				return ctx.Err()
+			}
+		})
+	}
+	err := p.Close()
+	fmt.Printf("pool failed with %q: %d of 100 tasks ran, %d of them were canceled\n", err, ran.Load(), canceled.Load())
+	fmt.Println("submitting to a closed pool:", p.Submit(ctx, slow))
+}